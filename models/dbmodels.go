@@ -1,7 +1,8 @@
 package models
 
 import (
-	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -9,40 +10,118 @@ import (
 )
 
 type DurableLinkDB struct {
-	ID                  int64      `gorm:"primaryKey;autoIncrement"`
-	Host                string     `gorm:"type:varchar(255);not null;index:idx_host_path,unique,composite:host_path"`
-	Path                string     `gorm:"type:varchar(255);not null;index:idx_host_path,unique,composite:host_path"`
-	Link                string     `gorm:"type:text;not null"`
-	IsUnguessablePath   bool       `gorm:"default:false;not null;index:idx_find_existing"`
-	ProjectID           *string    `gorm:"type:uuid;index:idx_project_id"`
-	AndroidPackageName  *string    `gorm:"type:varchar(255)"`
-	AndroidFallbackLink *string    `gorm:"type:text"`
-	AndroidMinVersion   *string    `gorm:"type:varchar(50)"`
-	IOSFallbackLink     *string    `gorm:"type:text"`
-	IOSIpadFallbackLink *string    `gorm:"type:text"`
-	IOSAppStoreID       *int64     `gorm:"type:bigint"`
-	SocialTitle         *string    `gorm:"type:varchar(500)"`
-	SocialDescription   *string    `gorm:"type:text"`
-	SocialImageLink     *string    `gorm:"type:text"`
-	UtmSource           *string    `gorm:"type:varchar(255)"`
-	UtmMedium           *string    `gorm:"type:varchar(255)"`
-	UtmCampaign         *string    `gorm:"type:varchar(255)"`
-	UtmTerm             *string    `gorm:"type:varchar(255)"`
-	UtmContent          *string    `gorm:"type:varchar(255)"`
-	ItunesPt            *string    `gorm:"type:varchar(255)"`
-	ItunesAt            *string    `gorm:"type:varchar(255)"`
-	ItunesCt            *string    `gorm:"type:varchar(255)"`
-	ItunesMt            *string    `gorm:"type:varchar(50)"`
-	OtherFallbackURL    *string    `gorm:"type:text"`
-	ParamsHash          string     `gorm:"type:varchar(64);index:idx_find_existing"`
-	CreatedAt           time.Time  `gorm:"autoCreateTime"`
-	UpdatedAt           time.Time  `gorm:"autoUpdateTime"`
+	ID                   int64           `gorm:"primaryKey;autoIncrement"`
+	Host                 string          `gorm:"type:varchar(255);not null;index:idx_host_path,unique,composite:host_path"`
+	Path                 string          `gorm:"type:varchar(255);not null;index:idx_host_path,unique,composite:host_path"`
+	Link                 string          `gorm:"type:text;not null"`
+	Name                 *string         `gorm:"type:varchar(255)"`
+	Locale               *string         `gorm:"type:varchar(35)"`
+	CampaignID           *string         `gorm:"type:varchar(255);index:idx_campaign_id"`
+	IsUnguessablePath    bool            `gorm:"default:false;not null;index:idx_find_existing"`
+	ProjectID            *string         `gorm:"type:uuid;index:idx_project_id;index:idx_idempotency_key,unique,composite:idempotency_key"`
+	IdempotencyKey       *string         `gorm:"type:varchar(255);index:idx_idempotency_key,unique,composite:idempotency_key"`        // Client supplied, retries of a create with the same key return the same link
+	PathScope            string          `gorm:"type:varchar(36);not null;default:'';index:idx_host_path,unique,composite:host_path"` // Empty for globally unique paths, the project ID for per-project ones
+	AndroidPackageName   *string         `gorm:"type:varchar(255)"`
+	AndroidFallbackLink  *string         `gorm:"type:text"`
+	AndroidFallbackLinks StringList      `gorm:"type:json"`
+	AndroidMinVersion    *string         `gorm:"type:varchar(50)"`
+	IOSFallbackLink      *string         `gorm:"type:text"`
+	IOSFallbackLinks     StringList      `gorm:"type:json"`
+	IOSIpadFallbackLink  *string         `gorm:"type:text"`
+	IOSAppStoreID        *int64          `gorm:"type:bigint"`
+	SocialTitle          *string         `gorm:"type:varchar(500)"`
+	SocialDescription    *string         `gorm:"type:text"`
+	SocialImageLink      *string         `gorm:"type:text"`
+	UtmSource            *string         `gorm:"type:varchar(255)"`
+	UtmMedium            *string         `gorm:"type:varchar(255)"`
+	UtmCampaign          *string         `gorm:"type:varchar(255)"`
+	UtmTerm              *string         `gorm:"type:varchar(255)"`
+	UtmContent           *string         `gorm:"type:varchar(255)"`
+	ItunesPt             *string         `gorm:"type:varchar(255)"`
+	ItunesAt             *string         `gorm:"type:varchar(255)"`
+	ItunesCt             *string         `gorm:"type:varchar(255)"`
+	ItunesMt             *string         `gorm:"type:varchar(50)"`
+	OtherFallbackURL     *string         `gorm:"type:text"`
+	Rules                ResolutionRules `gorm:"type:json"`
+	RedirectType         *string         `gorm:"type:varchar(20)"` // nil means RedirectTypeTemporary
+	InterstitialDelayMs  *int            // nil leaves the delay to the HTML layer
+	ParamsHash           string          `gorm:"type:varchar(64);index:idx_find_existing"`
+	DedupIgnoreUTM       bool            `gorm:"default:false;not null"` // ParamsHash leaves out the UTM params, so links differing only in UTM share a path
+	RawRequest           *string         `gorm:"type:text"`              // Original create request JSON, kept for auditing only
+	ClickCount           int64           `gorm:"default:0;not null"`
+	MaxClicks            *int64          // Resolving stops once ClickCount reaches it, nil means unlimited
+	PasswordHash         *string         `gorm:"type:varchar(60)"` // bcrypt hash, the plaintext password is never stored
+	CreatedAt            time.Time       `gorm:"autoCreateTime"`
+	UpdatedAt            time.Time       `gorm:"autoUpdateTime"`
+
+	// ParamsHashAlgorithm is the algorithm ParamsHash is computed with
+	ParamsHashAlgorithm ParamsHashAlgorithm `gorm:"-"`
 }
 
 func (DurableLinkDB) TableName() string {
 	return "apppanel_durable_links"
 }
 
+// DailyClicksDB counts the clicks of a link on one UTC day.
+type DailyClicksDB struct {
+	LinkID int64     `gorm:"primaryKey;autoIncrement:false"`
+	Day    time.Time `gorm:"primaryKey"` // UTC midnight starting the day
+	Clicks int64     `gorm:"default:0;not null"`
+}
+
+func (DailyClicksDB) TableName() string {
+	return "apppanel_daily_clicks"
+}
+
+// ReleasedPathDB is a path freed by deleting its link, kept so new links can re-use it.
+type ReleasedPathDB struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement"`
+	Host       string    `gorm:"type:varchar(255);not null;index:idx_released_host_path,unique;index:idx_released_host_length"`
+	Path       string    `gorm:"type:varchar(255);not null;index:idx_released_host_path,unique"`
+	Length     int       `gorm:"not null;index:idx_released_host_length"` // len(Path), so paths are only re-used for the same length
+	ReleasedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (ReleasedPathDB) TableName() string {
+	return "apppanel_released_paths"
+}
+
+// DeletedPathDB records a path whose link was deleted, so resolving it reports the link gone
+// instead of never created. A new link at the path takes precedence.
+type DeletedPathDB struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	Host      string    `gorm:"type:varchar(255);not null;index:idx_deleted_host_path"`
+	Path      string    `gorm:"type:varchar(255);not null;index:idx_deleted_host_path"`
+	ProjectID *string   `gorm:"type:uuid"`
+	DeletedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (DeletedPathDB) TableName() string {
+	return "apppanel_deleted_paths"
+}
+
+// TenantSettingDB is one key-value setting of a tenant, overriding a TenantConfig default.
+type TenantSettingDB struct {
+	TenantID  string    `gorm:"type:varchar(255);primaryKey"`
+	Key       string    `gorm:"type:varchar(255);primaryKey"`
+	Value     string    `gorm:"type:text;not null"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (TenantSettingDB) TableName() string {
+	return "apppanel_tenant_settings"
+}
+
+// LinkTagDB associates a link with one tag.
+type LinkTagDB struct {
+	LinkID int64  `gorm:"primaryKey;autoIncrement:false"`
+	Tag    string `gorm:"type:varchar(64);primaryKey;index:idx_link_tags_tag"`
+}
+
+func (LinkTagDB) TableName() string {
+	return "apppanel_link_tags"
+}
+
 // BeforeCreate is a GORM hook that runs before creating a record
 func (db *DurableLinkDB) BeforeCreate(tx *gorm.DB) error {
 	db.ParamsHash = db.ComputeParamsHash()
@@ -57,21 +136,29 @@ func (db *DurableLinkDB) BeforeUpdate(tx *gorm.DB) error {
 
 func (db *DurableLinkDB) ToDurableLink() DurableLink {
 	return DurableLink{
-		Host: db.Host,
-		Link: db.Link,
+		Host:       db.Host,
+		Link:       db.Link,
+		Name:       db.Name,
+		Locale:     db.Locale,
+		CampaignID: db.CampaignID,
 		AndroidParameters: AndroidParameters{
 			AndroidPackageName:           db.AndroidPackageName,
 			AndroidFallbackLink:          db.AndroidFallbackLink,
+			AndroidFallbackLinks:         db.AndroidFallbackLinks,
 			AndroidMinPackageVersionCode: db.AndroidMinVersion,
 		},
 		IosParameters: IOSParameters{
 			IOSFallbackLink:     db.IOSFallbackLink,
+			IOSFallbackLinks:    db.IOSFallbackLinks,
 			IOSIpadFallbackLink: db.IOSIpadFallbackLink,
 			IOSAppStoreId:       db.IOSAppStoreID,
 		},
 		OtherPlatformParameters: OtherPlatformParameters{
 			FallbackURL: db.OtherFallbackURL,
 		},
+		Rules:               db.Rules,
+		RedirectType:        db.RedirectType,
+		InterstitialDelayMs: db.InterstitialDelayMs,
 		SocialMetaTagInfo: SocialMetaTagInfo{
 			SocialTitle:       db.SocialTitle,
 			SocialDescription: db.SocialDescription,
@@ -97,35 +184,44 @@ func (db *DurableLinkDB) ToDurableLink() DurableLink {
 
 func FromDurableLink(dl DurableLink, host, path string, isUnguessable bool, projectID *string) *DurableLinkDB {
 	return &DurableLinkDB{
-		Host:                host,
-		Path:                path,
-		Link:                dl.Link,
-		IsUnguessablePath:   isUnguessable,
-		ProjectID:           projectID,
-		AndroidPackageName:  dl.AndroidParameters.AndroidPackageName,
-		AndroidFallbackLink: dl.AndroidParameters.AndroidFallbackLink,
-		AndroidMinVersion:   dl.AndroidParameters.AndroidMinPackageVersionCode,
-		IOSFallbackLink:     dl.IosParameters.IOSFallbackLink,
-		IOSIpadFallbackLink: dl.IosParameters.IOSIpadFallbackLink,
-		IOSAppStoreID:       dl.IosParameters.IOSAppStoreId,
-		SocialTitle:         dl.SocialMetaTagInfo.SocialTitle,
-		SocialDescription:   dl.SocialMetaTagInfo.SocialDescription,
-		SocialImageLink:     dl.SocialMetaTagInfo.SocialImageLink,
-		UtmSource:           dl.AnalyticsInfo.MarketingParameters.UtmSource,
-		UtmMedium:           dl.AnalyticsInfo.MarketingParameters.UtmMedium,
-		UtmCampaign:         dl.AnalyticsInfo.MarketingParameters.UtmCampaign,
-		UtmTerm:             dl.AnalyticsInfo.MarketingParameters.UtmTerm,
-		UtmContent:          dl.AnalyticsInfo.MarketingParameters.UtmContent,
-		ItunesPt:            dl.AnalyticsInfo.ItunesConnectAnalytics.Pt,
-		ItunesAt:            dl.AnalyticsInfo.ItunesConnectAnalytics.At,
-		ItunesCt:            dl.AnalyticsInfo.ItunesConnectAnalytics.Ct,
-		ItunesMt:            dl.AnalyticsInfo.ItunesConnectAnalytics.Mt,
-		OtherFallbackURL:    dl.OtherPlatformParameters.FallbackURL,
+		Host:                 host,
+		Path:                 path,
+		Link:                 dl.Link,
+		Name:                 dl.Name,
+		Locale:               dl.Locale,
+		CampaignID:           dl.CampaignID,
+		IsUnguessablePath:    isUnguessable,
+		ProjectID:            projectID,
+		AndroidPackageName:   dl.AndroidParameters.AndroidPackageName,
+		AndroidFallbackLink:  dl.AndroidParameters.AndroidFallbackLink,
+		AndroidFallbackLinks: dl.AndroidParameters.AndroidFallbackLinks,
+		AndroidMinVersion:    dl.AndroidParameters.AndroidMinPackageVersionCode,
+		IOSFallbackLink:      dl.IosParameters.IOSFallbackLink,
+		IOSFallbackLinks:     dl.IosParameters.IOSFallbackLinks,
+		IOSIpadFallbackLink:  dl.IosParameters.IOSIpadFallbackLink,
+		IOSAppStoreID:        dl.IosParameters.IOSAppStoreId,
+		SocialTitle:          dl.SocialMetaTagInfo.SocialTitle,
+		SocialDescription:    dl.SocialMetaTagInfo.SocialDescription,
+		SocialImageLink:      dl.SocialMetaTagInfo.SocialImageLink,
+		UtmSource:            dl.AnalyticsInfo.MarketingParameters.UtmSource,
+		UtmMedium:            dl.AnalyticsInfo.MarketingParameters.UtmMedium,
+		UtmCampaign:          dl.AnalyticsInfo.MarketingParameters.UtmCampaign,
+		UtmTerm:              dl.AnalyticsInfo.MarketingParameters.UtmTerm,
+		UtmContent:           dl.AnalyticsInfo.MarketingParameters.UtmContent,
+		ItunesPt:             dl.AnalyticsInfo.ItunesConnectAnalytics.Pt,
+		ItunesAt:             dl.AnalyticsInfo.ItunesConnectAnalytics.At,
+		ItunesCt:             dl.AnalyticsInfo.ItunesConnectAnalytics.Ct,
+		ItunesMt:             dl.AnalyticsInfo.ItunesConnectAnalytics.Mt,
+		OtherFallbackURL:     dl.OtherPlatformParameters.FallbackURL,
+		Rules:                dl.Rules,
+		RedirectType:         dl.RedirectType,
+		InterstitialDelayMs:  dl.InterstitialDelayMs,
 		// ParamsHash will be auto-computed by BeforeCreate/BeforeUpdate hooks
 	}
 }
 
-// ComputeParamsHash computes a SHA256 hash of all optional parameters for efficient duplicate detection
+// ComputeParamsHash hashes all optional parameters with ParamsHashAlgorithm for efficient duplicate detection.
+// Descriptive fields such as Name, Locale and CampaignID are deliberately left out.
 func (db *DurableLinkDB) ComputeParamsHash() string {
 	// Build a deterministic string representation of all optional parameters
 	var parts []string
@@ -139,16 +235,36 @@ func (db *DurableLinkDB) ComputeParamsHash() string {
 	parts = append(parts, stringPtrOrEmpty(db.SocialTitle))
 	parts = append(parts, stringPtrOrEmpty(db.SocialDescription))
 	parts = append(parts, stringPtrOrEmpty(db.SocialImageLink))
-	parts = append(parts, stringPtrOrEmpty(db.UtmSource))
-	parts = append(parts, stringPtrOrEmpty(db.UtmMedium))
-	parts = append(parts, stringPtrOrEmpty(db.UtmCampaign))
-	parts = append(parts, stringPtrOrEmpty(db.UtmTerm))
-	parts = append(parts, stringPtrOrEmpty(db.UtmContent))
+	if db.DedupIgnoreUTM {
+		// Hash as if no UTM params were set, matching links created without them
+		parts = append(parts, stringPtrOrEmpty(nil), stringPtrOrEmpty(nil), stringPtrOrEmpty(nil), stringPtrOrEmpty(nil), stringPtrOrEmpty(nil))
+	} else {
+		parts = append(parts, stringPtrOrEmpty(db.UtmSource))
+		parts = append(parts, stringPtrOrEmpty(db.UtmMedium))
+		parts = append(parts, stringPtrOrEmpty(db.UtmCampaign))
+		parts = append(parts, stringPtrOrEmpty(db.UtmTerm))
+		parts = append(parts, stringPtrOrEmpty(db.UtmContent))
+	}
 	parts = append(parts, stringPtrOrEmpty(db.ItunesPt))
 	parts = append(parts, stringPtrOrEmpty(db.ItunesAt))
 	parts = append(parts, stringPtrOrEmpty(db.ItunesCt))
 	parts = append(parts, stringPtrOrEmpty(db.ItunesMt))
 	parts = append(parts, stringPtrOrEmpty(db.OtherFallbackURL))
+	// Fallback lists are only hashed when present, so hashes of links without them are unchanged
+	if len(db.AndroidFallbackLinks) > 0 || len(db.IOSFallbackLinks) > 0 {
+		parts = append(parts, db.AndroidFallbackLinks.hashKey())
+		parts = append(parts, db.IOSFallbackLinks.hashKey())
+	}
+	// Likewise rules, links with different rules resolve differently and must not be shared
+	if len(db.Rules) > 0 {
+		parts = append(parts, db.Rules.hashKey())
+	}
+	if db.RedirectType != nil {
+		parts = append(parts, "redirect:"+*db.RedirectType)
+	}
+	if db.InterstitialDelayMs != nil {
+		parts = append(parts, fmt.Sprintf("interstitial:%d", *db.InterstitialDelayMs))
+	}
 	combined := ""
 	for i, part := range parts {
 		if i > 0 {
@@ -156,8 +272,7 @@ func (db *DurableLinkDB) ComputeParamsHash() string {
 		}
 		combined += part
 	}
-	hash := sha256.Sum256([]byte(combined))
-	return fmt.Sprintf("%x", hash)
+	return db.ParamsHashAlgorithm.hash([]byte(combined))
 }
 
 func stringPtrOrEmpty(s *string) string {
@@ -173,3 +288,41 @@ func int64PtrToString(i *int64) string {
 	}
 	return fmt.Sprintf("%d", *i)
 }
+
+// StringList is an ordered list of strings persisted as a JSON array column.
+type StringList []string
+
+// Value implements driver.Valuer. Empty lists are stored as NULL.
+func (l StringList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+}
+
+func (l StringList) hashKey() string {
+	if len(l) == 0 {
+		return "\x01"
+	}
+	b, _ := json.Marshal([]string(l))
+	return string(b)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -122,3 +123,169 @@ func stringPtr(s string) *string {
 func int64Ptr(i int64) *int64 {
 	return &i
 }
+
+func TestComputeParamsHash_IgnoresName(t *testing.T) {
+	unnamed := &DurableLinkDB{Link: "https://example.com/target"}
+	named := &DurableLinkDB{Link: "https://example.com/target", Name: stringPtr("Launch")}
+
+	assert.Equal(t, unnamed.ComputeParamsHash(), named.ComputeParamsHash())
+	assert.Equal(t, stringPtr("Launch"), named.ToDurableLink().Name)
+}
+
+func TestFallbackLinks_RoundTrip(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&DurableLinkDB{}))
+
+	dl := DurableLink{
+		Link: "https://example.com/target",
+		AndroidParameters: AndroidParameters{
+			AndroidFallbackLink:  stringPtr("https://play.google.com/store/apps/details?id=com.example"),
+			AndroidFallbackLinks: []string{"https://mirror.example.com/app.apk", "https://example.com/web"},
+		},
+		IosParameters: IOSParameters{
+			IOSFallbackLinks: []string{"https://example.com/ios-b", "https://example.com/ios-a"},
+		},
+	}
+	require.NoError(t, db.Create(FromDurableLink(dl, "example.com", "multi", false, nil)).Error)
+
+	var stored DurableLinkDB
+	require.NoError(t, db.Where("path = ?", "multi").First(&stored).Error)
+	result := stored.ToDurableLink()
+
+	assert.Equal(t, dl.AndroidParameters.AndroidFallbackLinks, result.AndroidParameters.AndroidFallbackLinks)
+	assert.Equal(t, dl.IosParameters.IOSFallbackLinks, result.IosParameters.IOSFallbackLinks)
+	assert.Equal(t, []string{
+		"https://play.google.com/store/apps/details?id=com.example",
+		"https://mirror.example.com/app.apk",
+		"https://example.com/web",
+	}, result.AndroidParameters.FallbackLinks())
+	assert.Equal(t, []string{"https://example.com/ios-b", "https://example.com/ios-a"}, result.IosParameters.FallbackLinks())
+
+	var plain DurableLinkDB
+	require.NoError(t, db.Create(&DurableLinkDB{Host: "example.com", Path: "plain", Link: "https://example.com"}).Error)
+	require.NoError(t, db.Where("path = ?", "plain").First(&plain).Error)
+	assert.Nil(t, plain.AndroidFallbackLinks)
+}
+
+func TestComputeParamsHash_FallbackLinks(t *testing.T) {
+	plain := &DurableLinkDB{Link: "https://example.com/target"}
+	ordered := &DurableLinkDB{Link: "https://example.com/target", AndroidFallbackLinks: StringList{"https://a.example.com", "https://b.example.com"}}
+	reversed := &DurableLinkDB{Link: "https://example.com/target", AndroidFallbackLinks: StringList{"https://b.example.com", "https://a.example.com"}}
+	ios := &DurableLinkDB{Link: "https://example.com/target", IOSFallbackLinks: StringList{"https://a.example.com", "https://b.example.com"}}
+
+	assert.NotEqual(t, plain.ComputeParamsHash(), ordered.ComputeParamsHash())
+	assert.NotEqual(t, ordered.ComputeParamsHash(), reversed.ComputeParamsHash(), "order must affect the hash")
+	assert.NotEqual(t, ordered.ComputeParamsHash(), ios.ComputeParamsHash())
+
+	// Links without fallback lists keep their previous hash
+	empty := &DurableLinkDB{Link: "https://example.com/target", AndroidFallbackLinks: StringList{}}
+	assert.Equal(t, plain.ComputeParamsHash(), empty.ComputeParamsHash())
+}
+
+func TestComputeParamsHash_DedupIgnoreUTM(t *testing.T) {
+	plain := &DurableLinkDB{Link: "https://example.com/target"}
+	spring := &DurableLinkDB{Link: "https://example.com/target", UtmSource: stringPtr("newsletter"), UtmCampaign: stringPtr("spring")}
+	summer := &DurableLinkDB{Link: "https://example.com/target", UtmSource: stringPtr("newsletter"), UtmCampaign: stringPtr("summer")}
+
+	assert.NotEqual(t, spring.ComputeParamsHash(), summer.ComputeParamsHash(), "UTM params are hashed by default")
+
+	spring.DedupIgnoreUTM = true
+	summer.DedupIgnoreUTM = true
+	assert.Equal(t, spring.ComputeParamsHash(), summer.ComputeParamsHash())
+	assert.Equal(t, plain.ComputeParamsHash(), spring.ComputeParamsHash())
+
+	other := &DurableLinkDB{Link: "https://example.com/target", SocialTitle: stringPtr("Spring"), DedupIgnoreUTM: true}
+	assert.NotEqual(t, spring.ComputeParamsHash(), other.ComputeParamsHash(), "non-UTM params still count")
+}
+
+func TestLocale_RoundTripAndHash(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&DurableLinkDB{}))
+
+	dl := DurableLink{Link: "https://example.com/target", Locale: stringPtr("pt-BR")}
+	require.NoError(t, db.Create(FromDurableLink(dl, "example.com", "localized", false, nil)).Error)
+
+	var stored DurableLinkDB
+	require.NoError(t, db.Where("path = ?", "localized").First(&stored).Error)
+	assert.Equal(t, stringPtr("pt-BR"), stored.ToDurableLink().Locale)
+
+	plain := &DurableLinkDB{Link: "https://example.com/target"}
+	assert.Equal(t, plain.ComputeParamsHash(), stored.ComputeParamsHash(), "locale is not part of the dedup hash")
+}
+
+func TestRedirectType_RoundTripAndHash(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&DurableLinkDB{}))
+
+	dl := DurableLink{Link: "https://example.com/target", RedirectType: stringPtr(RedirectTypePermanent)}
+	require.NoError(t, db.Create(FromDurableLink(dl, "example.com", "moved", false, nil)).Error)
+
+	var stored DurableLinkDB
+	require.NoError(t, db.Where("path = ?", "moved").First(&stored).Error)
+	assert.Equal(t, stringPtr(RedirectTypePermanent), stored.ToDurableLink().RedirectType)
+
+	plain := &DurableLinkDB{Link: "https://example.com/target"}
+	assert.NotEqual(t, plain.ComputeParamsHash(), stored.ComputeParamsHash(), "permanent links must not be shared with temporary ones")
+}
+
+func TestInterstitialDelay_RoundTripAndHash(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&DurableLinkDB{}))
+
+	delay := 2000
+	dl := DurableLink{Link: "https://example.com/target", InterstitialDelayMs: &delay}
+	require.NoError(t, db.Create(FromDurableLink(dl, "example.com", "delayed", false, nil)).Error)
+
+	var stored DurableLinkDB
+	require.NoError(t, db.Where("path = ?", "delayed").First(&stored).Error)
+	assert.Equal(t, &delay, stored.ToDurableLink().InterstitialDelayMs)
+
+	plain := &DurableLinkDB{Link: "https://example.com/target"}
+	assert.NotEqual(t, plain.ComputeParamsHash(), stored.ComputeParamsHash(), "delayed links must not be shared with immediate ones")
+}
+
+func TestComputeParamsHash_Algorithms(t *testing.T) {
+	algorithms := []struct {
+		name      string
+		algorithm ParamsHashAlgorithm
+		prefix    string
+		length    int
+	}{
+		{name: "sha256", algorithm: ParamsHashSHA256, prefix: "", length: 64},
+		{name: "truncated sha256", algorithm: ParamsHashSHA256Truncated, prefix: "s128:", length: 37},
+		{name: "fnv64", algorithm: ParamsHashFNV64, prefix: "f64:", length: 20},
+	}
+
+	seen := map[string]string{}
+	for _, tt := range algorithms {
+		t.Run(tt.name, func(t *testing.T) {
+			link := &DurableLinkDB{Link: "https://example.com/target", SocialTitle: stringPtr("Title"), ParamsHashAlgorithm: tt.algorithm}
+			other := &DurableLinkDB{Link: "https://example.com/target", SocialTitle: stringPtr("Other"), ParamsHashAlgorithm: tt.algorithm}
+
+			hash := link.ComputeParamsHash()
+			assert.Equal(t, hash, link.ComputeParamsHash(), "hash must be stable")
+			assert.NotEqual(t, hash, other.ComputeParamsHash())
+			assert.Len(t, hash, tt.length)
+			assert.True(t, strings.HasPrefix(hash, tt.prefix))
+			assert.NotContains(t, seen, hash, "algorithms must not share hashes")
+			seen[hash] = tt.name
+		})
+	}
+
+	// The default keeps hashes of existing rows valid
+	link := &DurableLinkDB{Link: "https://example.com/target"}
+	assert.Equal(t, "ccdea66ad757e68be5e6eed26c992b98e520ff257a58affebb57a94ef485fcbe", link.ComputeParamsHash())
+}
+
+func TestCampaignID_RoundTripAndHash(t *testing.T) {
+	dl := DurableLink{Link: "https://example.com/target", CampaignID: stringPtr("spring")}
+	stored := FromDurableLink(dl, "example.com", "campaign", false, nil)
+	assert.Equal(t, stringPtr("spring"), stored.ToDurableLink().CampaignID)
+
+	plain := &DurableLinkDB{Link: "https://example.com/target"}
+	assert.Equal(t, plain.ComputeParamsHash(), stored.ComputeParamsHash(), "campaign is not part of the dedup hash")
+}
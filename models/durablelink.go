@@ -3,23 +3,56 @@ package models
 type DurableLink struct {
 	Host                    string                  `json:"host" validate:"required"`
 	Link                    string                  `json:"link" validate:"required,url"`
+	Name                    *string                 `json:"name,omitempty"`       // Internal display name, not part of the link itself
+	Locale                  *string                 `json:"locale,omitempty"`     // BCP 47 language tag used to localize previews, e.g. "pt-BR"
+	CampaignID              *string                 `json:"campaignId,omitempty"` // Groups links for aggregate stats, not part of the link itself
 	AndroidParameters       AndroidParameters       `json:"androidParameters,omitempty"`
 	IosParameters           IOSParameters           `json:"iosParameters,omitempty"`
 	OtherPlatformParameters OtherPlatformParameters `json:"otherPlatformParameters,omitempty"`
 	AnalyticsInfo           AnalyticsInfo           `json:"analyticsInfo,omitempty"`
 	SocialMetaTagInfo       SocialMetaTagInfo       `json:"socialMetaTagInfo,omitempty"`
+	Rules                   []ResolutionRule        `json:"rules,omitempty"`               // Evaluated in order on resolve, the first match replaces Link
+	RedirectType            *string                 `json:"redirectType,omitempty"`        // RedirectTypePermanent or RedirectTypeTemporary, defaults to temporary
+	InterstitialDelayMs     *int                    `json:"interstitialDelayMs,omitempty"` // How long an interstitial preview shows before redirecting, 0-10000
 }
 
+const (
+	RedirectTypePermanent = "permanent"
+	RedirectTypeTemporary = "temporary"
+)
+
 type AndroidParameters struct {
-	AndroidPackageName           *string `json:"androidPackageName,omitempty"`
-	AndroidFallbackLink          *string `json:"androidFallbackLink,omitempty"`
-	AndroidMinPackageVersionCode *string `json:"androidMinPackageVersionCode,omitempty"`
+	AndroidPackageName           *string  `json:"androidPackageName,omitempty"`
+	AndroidFallbackLink          *string  `json:"androidFallbackLink,omitempty"`
+	AndroidFallbackLinks         []string `json:"androidFallbackLinks,omitempty"` // Additional fallbacks, tried in order after AndroidFallbackLink
+	AndroidMinPackageVersionCode *string  `json:"androidMinPackageVersionCode,omitempty"`
+}
+
+// FallbackLinks returns all Android fallbacks in priority order, with the single
+// AndroidFallbackLink first for backward compatibility.
+func (p AndroidParameters) FallbackLinks() []string {
+	return prependFallback(p.AndroidFallbackLink, p.AndroidFallbackLinks)
 }
 
 type IOSParameters struct {
-	IOSFallbackLink     *string `json:"iosFallbackLink,omitempty"`
-	IOSIpadFallbackLink *string `json:"iosIpadFallbackLink,omitempty"`
-	IOSAppStoreId       *int64  `json:"iosAppStoreId,omitempty"`
+	IOSFallbackLink     *string  `json:"iosFallbackLink,omitempty"`
+	IOSFallbackLinks    []string `json:"iosFallbackLinks,omitempty"` // Additional fallbacks, tried in order after IOSFallbackLink
+	IOSIpadFallbackLink *string  `json:"iosIpadFallbackLink,omitempty"`
+	IOSAppStoreId       *int64   `json:"iosAppStoreId,omitempty"`
+}
+
+// FallbackLinks returns all iOS fallbacks in priority order, with the single
+// IOSFallbackLink first for backward compatibility.
+func (p IOSParameters) FallbackLinks() []string {
+	return prependFallback(p.IOSFallbackLink, p.IOSFallbackLinks)
+}
+
+func prependFallback(first *string, rest []string) []string {
+	links := make([]string, 0, len(rest)+1)
+	if first != nil && *first != "" {
+		links = append(links, *first)
+	}
+	return append(links, rest...)
 }
 
 type OtherPlatformParameters struct {
@@ -12,9 +12,18 @@ type ErrorDetails struct {
 
 // ValidationError represents a single field validation error
 type ValidationError struct {
-	Field   string `json:"field"`
-	Tag     string `json:"tag"`
-	Message string `json:"message"`
+	Field    string    `json:"field"`
+	Tag      string    `json:"tag"`
+	Message  string    `json:"message"`
+	Position *Position `json:"position,omitempty"` // Where in the request body a syntax error was found
+}
+
+// Position locates a byte in a request body. Offset is 0-based, Line and Column are 1-based
+// and Column counts bytes.
+type Position struct {
+	Offset int64 `json:"offset"`
+	Line   int   `json:"line"`
+	Column int   `json:"column"`
 }
 
 // ValidationErrors represents multiple validation errors
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// longLinkStringParams maps the query keys of Firebase style long links to the string field
+// of a DurableLink they set.
+var longLinkStringParams = map[string]func(dl *DurableLink) **string{
+	"apn":          func(dl *DurableLink) **string { return &dl.AndroidParameters.AndroidPackageName },
+	"afl":          func(dl *DurableLink) **string { return &dl.AndroidParameters.AndroidFallbackLink },
+	"amv":          func(dl *DurableLink) **string { return &dl.AndroidParameters.AndroidMinPackageVersionCode },
+	"ifl":          func(dl *DurableLink) **string { return &dl.IosParameters.IOSFallbackLink },
+	"ipfl":         func(dl *DurableLink) **string { return &dl.IosParameters.IOSIpadFallbackLink },
+	"ofl":          func(dl *DurableLink) **string { return &dl.OtherPlatformParameters.FallbackURL },
+	"st":           func(dl *DurableLink) **string { return &dl.SocialMetaTagInfo.SocialTitle },
+	"sd":           func(dl *DurableLink) **string { return &dl.SocialMetaTagInfo.SocialDescription },
+	"si":           func(dl *DurableLink) **string { return &dl.SocialMetaTagInfo.SocialImageLink },
+	"utm_source":   func(dl *DurableLink) **string { return &dl.AnalyticsInfo.MarketingParameters.UtmSource },
+	"utm_medium":   func(dl *DurableLink) **string { return &dl.AnalyticsInfo.MarketingParameters.UtmMedium },
+	"utm_campaign": func(dl *DurableLink) **string { return &dl.AnalyticsInfo.MarketingParameters.UtmCampaign },
+	"utm_term":     func(dl *DurableLink) **string { return &dl.AnalyticsInfo.MarketingParameters.UtmTerm },
+	"utm_content":  func(dl *DurableLink) **string { return &dl.AnalyticsInfo.MarketingParameters.UtmContent },
+	"at":           func(dl *DurableLink) **string { return &dl.AnalyticsInfo.ItunesConnectAnalytics.At },
+	"ct":           func(dl *DurableLink) **string { return &dl.AnalyticsInfo.ItunesConnectAnalytics.Ct },
+	"mt":           func(dl *DurableLink) **string { return &dl.AnalyticsInfo.ItunesConnectAnalytics.Mt },
+	"pt":           func(dl *DurableLink) **string { return &dl.AnalyticsInfo.ItunesConnectAnalytics.Pt },
+}
+
+// DurableLinkFromParams builds a DurableLink from the query params of a Firebase style long
+// link, e.g. "link", "apn" and "isi". Empty values are treated as unset. Unknown keys and an
+// "isi" that is not a number are skipped with a warning. Host is left empty, since it is not
+// a query param.
+func DurableLinkFromParams(params map[string]string) (DurableLink, []Warning) {
+	var dl DurableLink
+	warnings := []Warning{}
+
+	// Sorted, so warnings come out in a stable order
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		value := params[key]
+		if value == "" {
+			continue
+		}
+
+		if field, ok := longLinkStringParams[key]; ok {
+			*field(&dl) = &value
+			continue
+		}
+
+		switch key {
+		case "link":
+			dl.Link = value
+		case "isi":
+			id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				warnings = append(warnings, Warning{
+					WarningCode:    "MALFORMED_PARAM",
+					WarningMessage: fmt.Sprintf("Param 'isi' is not a valid App Store ID: %s", value),
+					Field:          key,
+				})
+				continue
+			}
+			dl.IosParameters.IOSAppStoreId = &id
+		default:
+			warnings = append(warnings, Warning{
+				WarningCode:    "UNRECOGNIZED_PARAM",
+				WarningMessage: fmt.Sprintf("Param '%s' is not recognized and was ignored.", key),
+				Field:          key,
+			})
+		}
+	}
+
+	return dl, warnings
+}
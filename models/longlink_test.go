@@ -0,0 +1,109 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDurableLinkFromParams(t *testing.T) {
+	t.Run("all known keys", func(t *testing.T) {
+		dl, warnings := DurableLinkFromParams(map[string]string{
+			"link":         "https://example.com/target",
+			"apn":          "com.example.app",
+			"afl":          "https://example.com/android",
+			"amv":          "42",
+			"ifl":          "https://example.com/ios",
+			"ipfl":         "https://example.com/ipad",
+			"isi":          "123456789",
+			"ofl":          "https://example.com/other",
+			"st":           "Title",
+			"sd":           "Description",
+			"si":           "https://example.com/image.png",
+			"utm_source":   "source",
+			"utm_medium":   "medium",
+			"utm_campaign": "campaign",
+			"utm_term":     "term",
+			"utm_content":  "content",
+			"at":           "affiliate",
+			"ct":           "campaign-token",
+			"mt":           "8",
+			"pt":           "provider",
+		})
+
+		assert.Empty(t, warnings)
+		assert.Equal(t, DurableLink{
+			Link: "https://example.com/target",
+			AndroidParameters: AndroidParameters{
+				AndroidPackageName:           stringPtr("com.example.app"),
+				AndroidFallbackLink:          stringPtr("https://example.com/android"),
+				AndroidMinPackageVersionCode: stringPtr("42"),
+			},
+			IosParameters: IOSParameters{
+				IOSFallbackLink:     stringPtr("https://example.com/ios"),
+				IOSIpadFallbackLink: stringPtr("https://example.com/ipad"),
+				IOSAppStoreId:       int64Ptr(123456789),
+			},
+			OtherPlatformParameters: OtherPlatformParameters{
+				FallbackURL: stringPtr("https://example.com/other"),
+			},
+			AnalyticsInfo: AnalyticsInfo{
+				MarketingParameters: MarketingParameters{
+					UtmSource:   stringPtr("source"),
+					UtmMedium:   stringPtr("medium"),
+					UtmCampaign: stringPtr("campaign"),
+					UtmTerm:     stringPtr("term"),
+					UtmContent:  stringPtr("content"),
+				},
+				ItunesConnectAnalytics: ITunesConnectAnalytics{
+					At: stringPtr("affiliate"),
+					Ct: stringPtr("campaign-token"),
+					Mt: stringPtr("8"),
+					Pt: stringPtr("provider"),
+				},
+			},
+			SocialMetaTagInfo: SocialMetaTagInfo{
+				SocialTitle:       stringPtr("Title"),
+				SocialDescription: stringPtr("Description"),
+				SocialImageLink:   stringPtr("https://example.com/image.png"),
+			},
+		}, dl)
+	})
+
+	t.Run("unknown key warns", func(t *testing.T) {
+		dl, warnings := DurableLinkFromParams(map[string]string{
+			"link": "https://example.com/target",
+			"efr":  "1",
+		})
+
+		assert.Equal(t, "https://example.com/target", dl.Link)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "UNRECOGNIZED_PARAM", warnings[0].WarningCode)
+		assert.Equal(t, "efr", warnings[0].Field)
+	})
+
+	t.Run("invalid isi warns", func(t *testing.T) {
+		dl, warnings := DurableLinkFromParams(map[string]string{
+			"link": "https://example.com/target",
+			"isi":  "not-a-number",
+		})
+
+		assert.Nil(t, dl.IosParameters.IOSAppStoreId)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "MALFORMED_PARAM", warnings[0].WarningCode)
+		assert.Equal(t, "isi", warnings[0].Field)
+	})
+
+	t.Run("empty values are unset", func(t *testing.T) {
+		dl, warnings := DurableLinkFromParams(map[string]string{
+			"link": "https://example.com/target",
+			"apn":  "",
+			"isi":  "",
+		})
+
+		assert.Empty(t, warnings)
+		assert.Nil(t, dl.AndroidParameters.AndroidPackageName)
+		assert.Nil(t, dl.IosParameters.IOSAppStoreId)
+	})
+}
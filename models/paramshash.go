@@ -0,0 +1,38 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+)
+
+// ParamsHashAlgorithm selects how DurableLinkDB.ParamsHash is computed. Every algorithm but
+// the default prefixes its hashes with a version tag, so hashes of different algorithms never
+// match and the params_hash column (64 characters) fits all of them.
+type ParamsHashAlgorithm int
+
+const (
+	// ParamsHashSHA256 is the hex encoded SHA-256, 64 characters without a prefix.
+	ParamsHashSHA256 ParamsHashAlgorithm = iota
+	// ParamsHashSHA256Truncated keeps the first 128 bits of the SHA-256, 37 characters.
+	ParamsHashSHA256Truncated
+	// ParamsHashFNV64 is the 64 bit FNV-1a hash, 20 characters. It is not collision resistant,
+	// which only costs a missed de-duplication since lookups also compare the link.
+	ParamsHashFNV64
+)
+
+// hash returns the params hash of combined under the algorithm.
+func (a ParamsHashAlgorithm) hash(combined []byte) string {
+	switch a {
+	case ParamsHashSHA256Truncated:
+		sum := sha256.Sum256(combined)
+		return "s128:" + hex.EncodeToString(sum[:16])
+	case ParamsHashFNV64:
+		h := fnv.New64a()
+		h.Write(combined)
+		return "f64:" + hex.EncodeToString(h.Sum(nil))
+	default:
+		sum := sha256.Sum256(combined)
+		return hex.EncodeToString(sum[:])
+	}
+}
@@ -0,0 +1,96 @@
+package models
+
+// Platform identifies the client platform a link is being resolved for.
+type Platform int
+
+const (
+	PlatformOther Platform = iota
+	PlatformIOS
+	PlatformIPad
+	PlatformAndroid
+)
+
+// LinkSource identifies the field of a link a resolved URL was taken from.
+type LinkSource int
+
+const (
+	LinkSourceCanonical LinkSource = iota
+	LinkSourceIOSFallback
+	LinkSourceIPadFallback
+	LinkSourceAndroidFallback
+	LinkSourceOtherFallback
+)
+
+var linkSourceNames = map[LinkSource]string{
+	LinkSourceCanonical:       "canonical",
+	LinkSourceIOSFallback:     "iosFallback",
+	LinkSourceIPadFallback:    "ipadFallback",
+	LinkSourceAndroidFallback: "androidFallback",
+	LinkSourceOtherFallback:   "otherFallback",
+}
+
+func (s LinkSource) String() string {
+	if name, ok := linkSourceNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler, so sources appear by name in JSON.
+func (s LinkSource) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Candidate is a URL a client may try, along with the field it came from.
+type Candidate struct {
+	URL    string
+	Source LinkSource
+}
+
+// Candidates returns the non-empty URLs a client on platform should try, in order of
+// preference, ending with the canonical link. Duplicates are removed, keeping the first source.
+func (dl DurableLink) Candidates(platform Platform) []Candidate {
+	var ordered []Candidate
+	add := func(source LinkSource, urls ...string) {
+		for _, u := range urls {
+			ordered = append(ordered, Candidate{URL: u, Source: source})
+		}
+	}
+
+	switch platform {
+	case PlatformIOS:
+		add(LinkSourceIOSFallback, dl.IosParameters.FallbackLinks()...)
+	case PlatformIPad:
+		if dl.IosParameters.IOSIpadFallbackLink != nil {
+			add(LinkSourceIPadFallback, *dl.IosParameters.IOSIpadFallbackLink)
+		}
+		add(LinkSourceIOSFallback, dl.IosParameters.FallbackLinks()...)
+	case PlatformAndroid:
+		add(LinkSourceAndroidFallback, dl.AndroidParameters.FallbackLinks()...)
+	}
+	if dl.OtherPlatformParameters.FallbackURL != nil {
+		add(LinkSourceOtherFallback, *dl.OtherPlatformParameters.FallbackURL)
+	}
+	add(LinkSourceCanonical, dl.Link)
+
+	candidates := []Candidate{}
+	seen := make(map[string]bool)
+	for _, c := range ordered {
+		if c.URL == "" || seen[c.URL] {
+			continue
+		}
+		seen[c.URL] = true
+		candidates = append(candidates, c)
+	}
+
+	return candidates
+}
+
+// CandidateURLs returns the URLs of Candidates(platform).
+func (dl DurableLink) CandidateURLs(platform Platform) []string {
+	urls := []string{}
+	for _, c := range dl.Candidates(platform) {
+		urls = append(urls, c.URL)
+	}
+	return urls
+}
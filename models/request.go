@@ -5,6 +5,12 @@ type ExchangeShortLinkRequest struct {
 }
 
 type CreateDurableLinkRequest struct {
-	DurableLinkInfo DurableLink `json:"durableLinkInfo"`
-	Suffix          Suffix      `json:"suffix"`
+	DurableLinkInfo        DurableLink `json:"durableLinkInfo"`
+	Suffix                 Suffix      `json:"suffix"`
+	CustomPath             string      `json:"customPath,omitempty"`                          // Vanity path to use instead of a generated one, suffix is then ignored
+	URLScheme              *string     `json:"urlScheme,omitempty"`                           // Overrides the tenant URL scheme of the returned short link
+	Password               string      `json:"password,omitempty"`                            // Protects the link, resolving then requires this password
+	SkipTargetVerification bool        `json:"skipTargetVerification,omitempty"`              // Bypasses the tenant's target reachability check
+	OutputHost             *string     `json:"outputHost,omitempty"`                          // Short domain of the returned link, one of the tenant's aliases of durableLinkInfo.host
+	MaxClicks              *int64      `json:"maxClicks,omitempty" validate:"omitempty,gt=0"` // Stops the link resolving after this many clicks
 }
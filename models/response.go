@@ -1,12 +1,33 @@
 package models
 
 type ShortLinkResponse struct {
-	ShortLink string    `json:"shortLink"`
-	Warnings  []Warning `json:"warnings"`
+	ShortLink   string            `json:"shortLink"`
+	PreviewLink string            `json:"previewLink,omitempty"` // The short link opening its debug preview instead of redirecting
+	Details     *ShortLinkDetails `json:"details,omitempty"`
+	Reused      bool              `json:"reused"` // An existing link was returned instead of creating a new one
+	Warnings    []Warning         `json:"warnings"`
+}
+
+// ShortLinkDetails breaks a short link into its components so SDKs don't have to parse ShortLink.
+type ShortLinkDetails struct {
+	Scheme string `json:"scheme"`
+	Host   string `json:"host"`
+	Path   string `json:"path"` // Short path, without the tenant path prefix
+	URL    string `json:"url"`
 }
 
 type LongLinkResponse struct {
-	LongLink string `json:"longLink"`
+	LongLink            string `json:"longLink"`
+	RedirectType        string `json:"redirectType"`                  // RedirectTypePermanent or RedirectTypeTemporary, for picking the HTTP status
+	ETag                string `json:"etag"`                          // Quoted entity tag, changes whenever the link or the resolved target does
+	InterstitialDelayMs *int   `json:"interstitialDelayMs,omitempty"` // How long an interstitial preview shows before redirecting, nil when unset
+	IsUnguessable       bool   `json:"isUnguessable"`                 // The link has an unguessable path, so it should not be listed publicly
+}
+
+// PlatformLinkResponse is the URL served to a client platform and the link field it came from.
+type PlatformLinkResponse struct {
+	LongLink string     `json:"longLink"`
+	Source   LinkSource `json:"source"`
 }
 
 type LinkResponse struct {
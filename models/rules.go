@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResolutionRule sends resolves matching all of its conditions to Link instead of the base
+// link. A rule without conditions always matches.
+type ResolutionRule struct {
+	Countries  []string    `json:"countries,omitempty"` // ISO 3166-1 alpha-2 codes, the request country must be one of them
+	TimeWindow *TimeWindow `json:"timeWindow,omitempty"`
+	Link       string      `json:"link"`
+}
+
+// TimeWindow is a daily window of local time, From inclusive and To exclusive, both "15:04".
+// A window whose To is before its From wraps past midnight.
+type TimeWindow struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	TimeZone string `json:"timeZone,omitempty"` // IANA name such as "Europe/Berlin", defaults to UTC
+}
+
+// RequestContext describes the request a link is resolved for.
+type RequestContext struct {
+	Country string    // ISO 3166-1 alpha-2 code, empty when unknown
+	Time    time.Time // When the request was made
+}
+
+// TargetFor returns the link of the first rule matching req, or the base link when none does.
+func (dl DurableLink) TargetFor(req RequestContext) string {
+	for _, rule := range dl.Rules {
+		if rule.Matches(req) {
+			return rule.Link
+		}
+	}
+	return dl.Link
+}
+
+// Matches reports whether req meets every condition of the rule.
+func (r ResolutionRule) Matches(req RequestContext) bool {
+	if len(r.Countries) > 0 {
+		found := false
+		for _, country := range r.Countries {
+			if strings.EqualFold(country, req.Country) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.TimeWindow != nil && !r.TimeWindow.Contains(req.Time) {
+		return false
+	}
+	return true
+}
+
+// Contains reports whether t falls in the window. Malformed windows contain nothing.
+func (w TimeWindow) Contains(t time.Time) bool {
+	from, to, loc, err := w.parse()
+	if err != nil {
+		return false
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	return minute >= from || minute < to
+}
+
+// Validate reports why the window can't be evaluated, if it can't.
+func (w TimeWindow) Validate() error {
+	_, _, _, err := w.parse()
+	return err
+}
+
+// parse returns the window bounds in minutes after midnight and its location.
+func (w TimeWindow) parse() (int, int, *time.Location, error) {
+	from, err := time.Parse("15:04", w.From)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid from time %q", w.From)
+	}
+	to, err := time.Parse("15:04", w.To)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid to time %q", w.To)
+	}
+	loc := time.UTC
+	if w.TimeZone != "" {
+		if loc, err = time.LoadLocation(w.TimeZone); err != nil {
+			return 0, 0, nil, fmt.Errorf("invalid time zone %q", w.TimeZone)
+		}
+	}
+	return from.Hour()*60 + from.Minute(), to.Hour()*60 + to.Minute(), loc, nil
+}
+
+// ResolutionRules is an ordered list of rules persisted as a JSON array column.
+type ResolutionRules []ResolutionRule
+
+// Value implements driver.Valuer. Empty lists are stored as NULL.
+func (r ResolutionRules) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal([]ResolutionRule(r))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (r *ResolutionRules) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]ResolutionRule)(r))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]ResolutionRule)(r))
+	default:
+		return fmt.Errorf("cannot scan %T into ResolutionRules", value)
+	}
+}
+
+func (r ResolutionRules) hashKey() string {
+	b, _ := json.Marshal([]ResolutionRule(r))
+	return string(b)
+}
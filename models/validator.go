@@ -1,6 +1,7 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -30,8 +31,18 @@ func ParseAndValidateCreateRequest(body io.Reader) (*CreateDurableLinkRequest, e
 	var req CreateDurableLinkRequest
 	var allErrors []ValidationError
 
+	// Keep the raw body so syntax error offsets can be turned into a line and column
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, ValidationErrors{Errors: []ValidationError{{
+			Field:   "",
+			Tag:     "json",
+			Message: "Invalid request body: " + err.Error(),
+		}}}
+	}
+
 	// Decode the request
-	if err := json.NewDecoder(body).Decode(&req); err != nil {
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&req); err != nil {
 		// Check if it's a type mismatch error (e.g., string instead of int)
 		if jsonErr, ok := err.(*json.UnmarshalTypeError); ok {
 			allErrors = append(allErrors, ValidationError{
@@ -40,6 +51,15 @@ func ParseAndValidateCreateRequest(body io.Reader) (*CreateDurableLinkRequest, e
 				Message: fmt.Sprintf("Invalid type for field '%s': expected %s but got %s", jsonErr.Field, jsonErr.Type.String(), jsonErr.Value),
 			})
 			// Continue to validate other fields even after type error
+		} else if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			pos := positionAt(data, syntaxErr.Offset)
+			allErrors = append(allErrors, ValidationError{
+				Field:    "",
+				Tag:      "json",
+				Message:  fmt.Sprintf("Invalid request body: %s at line %d, column %d", err.Error(), pos.Line, pos.Column),
+				Position: &pos,
+			})
+			return nil, ValidationErrors{Errors: allErrors}
 		} else {
 			// For other JSON errors, return a single generic error
 			allErrors = append(allErrors, ValidationError{
@@ -64,6 +84,18 @@ func ParseAndValidateCreateRequest(body io.Reader) (*CreateDurableLinkRequest, e
 	return &req, nil
 }
 
+// positionAt locates the byte a json.SyntaxError complains about. The error's offset counts
+// the bytes read, including the offending one.
+func positionAt(data []byte, syntaxOffset int64) Position {
+	offset := min(max(syntaxOffset-1, 0), int64(len(data)))
+	before := data[:offset]
+	return Position{
+		Offset: offset,
+		Line:   bytes.Count(before, []byte("\n")) + 1,
+		Column: len(before) - bytes.LastIndexByte(before, '\n'),
+	}
+}
+
 func parseValidationErrors(err error) []ValidationError {
 	var validationErrors []ValidationError
 
@@ -97,6 +97,26 @@ func TestParseAndValidateCreateRequest_InvalidJSON(t *testing.T) {
 	assert.Equal(t, "json", validationErrs.Errors[0].Tag)
 }
 
+func TestParseAndValidateCreateRequest_SyntaxErrorPosition(t *testing.T) {
+	// The stray comma after the link is at line 4, column 40 (offset 88)
+	jsonBody := "{\n\t\"durableLinkInfo\": {\n\t\t\"host\": \"example.com\",\n\t\t\"link\": \"https://example.com/target\",,\n\t}\n}"
+
+	req, err := ParseAndValidateCreateRequest(bytes.NewBufferString(jsonBody))
+	require.Error(t, err)
+	require.Nil(t, req)
+
+	var validationErrs ValidationErrors
+	require.ErrorAs(t, err, &validationErrs)
+	require.Len(t, validationErrs.Errors, 1)
+
+	ve := validationErrs.Errors[0]
+	assert.Equal(t, "json", ve.Tag)
+	require.NotNil(t, ve.Position)
+	assert.Equal(t, Position{Offset: 88, Line: 4, Column: 40}, *ve.Position)
+	assert.Equal(t, byte(','), jsonBody[ve.Position.Offset])
+	assert.Contains(t, ve.Message, "at line 4, column 40")
+}
+
 func TestParseAndValidateCreateRequest_TypeMismatch(t *testing.T) {
 	jsonBody := `{
 		"durableLinkInfo": {
@@ -127,3 +147,16 @@ func TestParseAndValidateCreateRequest_TypeMismatch(t *testing.T) {
 	}
 	assert.True(t, found, "Expected type mismatch error")
 }
+
+func TestParseAndValidateCreateRequest_PreservesFragment(t *testing.T) {
+	jsonBody := `{
+		"durableLinkInfo": {
+			"host": "example.com",
+			"link": "https://app.com/p#foo"
+		}
+	}`
+
+	req, err := ParseAndValidateCreateRequest(bytes.NewBufferString(jsonBody))
+	require.NoError(t, err)
+	assert.Equal(t, "https://app.com/p#foo", req.DurableLinkInfo.Link)
+}
@@ -3,4 +3,5 @@ package models
 type Warning struct {
 	WarningCode    string `json:"warningCode"`
 	WarningMessage string `json:"warningMessage"`
+	Field          string `json:"field,omitempty"` // JSON path of the offending request param, e.g. "durableLinkInfo.iosParameters.iosAppStoreId"
 }
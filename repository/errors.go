@@ -1,7 +1,22 @@
 package repository
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrLinkNotFound = errors.New("link not found")
+	ErrLinkNotFound          = errors.New("link not found")
+	ErrLinkGone              = errors.New("link is no longer available") // The link existed but can't be resolved anymore
+	ErrInvalidDateRange      = errors.New("invalid date range: from must not be after to")
+	ErrMissingProjectID      = errors.New("project id is required")
+	ErrClickLimitReached     = fmt.Errorf("%w: it has reached its maximum number of clicks", ErrLinkGone)
+	ErrLinkDeleted           = fmt.Errorf("%w: it was deleted", ErrLinkGone)
+	ErrNoReleasedPath        = errors.New("no released path available")
+	ErrInvalidBatchSize      = errors.New("batch size must be positive")
+	ErrLinkNotInserted       = errors.New("insert did not store exactly one link")
+	ErrMissingIdempotencyKey = errors.New("idempotency key is required")
+	ErrInvalidTag            = errors.New("tag must be 1-64 lowercase letters, digits, '-' or '_'")
+	ErrInvalidTableName      = errors.New("table name must be an identifier, optionally qualified by a schema")
+	ErrPathTaken             = errors.New("path is already taken")
 )
@@ -2,41 +2,201 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/apppanel/durablelinks-core/models"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type LinkRepository interface {
 	GetLinkByHostAndPath(ctx context.Context, host, path string, projectID *uuid.UUID) (*models.DurableLink, error)
-	FindExistingShortLink(ctx context.Context, host string, link *models.DurableLink, projectID *uuid.UUID) (string, error)
+	GetLinkDBByHostAndPath(ctx context.Context, host, path string, projectID *uuid.UUID) (*models.DurableLinkDB, error)
+	GetLatestLinkByTarget(ctx context.Context, host, targetLink string, projectID *uuid.UUID) (*models.DurableLinkDB, error)
+	FindExistingShortLink(ctx context.Context, host string, link *models.DurableLink, projectID *uuid.UUID, createdAfter time.Time) (string, error)
 	CreateShortLink(ctx context.Context, link *models.DurableLinkDB, projectID *uuid.UUID) error
+	CreateLinkIfPathFree(ctx context.Context, link *models.DurableLinkDB, projectID *uuid.UUID) error
+	UpsertByIdempotencyKey(ctx context.Context, link *models.DurableLinkDB, projectID uuid.UUID) (string, error)
+	ResolveAndIncrementClicks(ctx context.Context, host, path string, projectID *uuid.UUID) (*models.DurableLinkDB, error)
+	IsPathAvailable(ctx context.Context, host, path string) (bool, error)
+	IsPathAvailableInProject(ctx context.Context, host, path string, projectID *uuid.UUID) (bool, error)
+	IsPathDeleted(ctx context.Context, host, path string, projectID *uuid.UUID) (bool, error)
+	UpdateLinkTarget(ctx context.Context, host, path, newLink string, projectID *uuid.UUID) error
+	GetRawRequest(ctx context.Context, host, path string, projectID *uuid.UUID) (string, error)
+	ListDistinctHosts(ctx context.Context, projectID *uuid.UUID) ([]string, error)
+	CountLinks(ctx context.Context, projectID *uuid.UUID) (int64, error)
+	ListLinks(ctx context.Context, projectID *uuid.UUID, limit, offset int) ([]models.DurableLinkDB, error)
+	ListLinksAfter(ctx context.Context, projectID *uuid.UUID, afterID int64, limit int) ([]models.DurableLinkDB, int64, error)
+	IterateLinks(ctx context.Context, projectID *uuid.UUID, fn func(*models.DurableLinkDB) error) error
+	ListLinksByDateRange(ctx context.Context, projectID *uuid.UUID, from, to time.Time, limit, offset int) ([]models.DurableLinkDB, error)
+	ListLinksByName(ctx context.Context, projectID *uuid.UUID, name string, limit, offset int) ([]models.DurableLinkDB, error)
+	ListRecentLinks(ctx context.Context, projectID *uuid.UUID, limit int) ([]models.DurableLinkDB, error)
+	ListDuplicateGroups(ctx context.Context, projectID *uuid.UUID) ([]DuplicateGroup, error)
+	DeleteLinksByProject(ctx context.Context, projectID uuid.UUID) (int64, error)
+	DeleteLink(ctx context.Context, host, path string, projectID *uuid.UUID, releasePath bool) error
+	AddTagToLinks(ctx context.Context, projectID uuid.UUID, paths []string, tag string) error
+	RemoveTagFromLinks(ctx context.Context, projectID uuid.UUID, paths []string, tag string) error
+	GetLinkTags(ctx context.Context, host, path string, projectID *uuid.UUID) ([]string, error)
+	ListLinksByTag(ctx context.Context, projectID uuid.UUID, tag string, limit, offset int) ([]models.DurableLinkDB, error)
+	PopReleasedPath(ctx context.Context, host string, length int, pathCase ReleasedPathCase) (string, error)
+	GetClickTimeSeries(ctx context.Context, host, path string, projectID *uuid.UUID, from, to time.Time, granularity Granularity) ([]TimeBucket, error)
+	RecomputeAllParamHashes(ctx context.Context, batchSize int) (int64, error)
+	GetCampaignStats(ctx context.Context, projectID *uuid.UUID, campaignID string) (*CampaignStats, error)
+	GetTenantSettings(ctx context.Context, tenantID string) (map[string]string, error)
+	SetTenantSetting(ctx context.Context, tenantID, key, value string) error
+}
+
+// maxPathPops bounds how often PopReleasedPath retries after losing a race for a path.
+const maxPathPops = 5
+
+// ReleasedPathCase restricts the letters of the paths PopReleasedPath hands out. Paths of
+// another case are left in the pool.
+type ReleasedPathCase int
+
+const (
+	// AnyPathCase accepts paths of any letter case.
+	AnyPathCase ReleasedPathCase = iota
+	// LowerPathCase only accepts paths without upper case letters.
+	LowerPathCase
+	// UpperPathCase only accepts paths without lower case letters.
+	UpperPathCase
+)
+
+// DuplicateGroup is a set of links sharing host, target and params, stored under different paths.
+type DuplicateGroup struct {
+	Host       string
+	Link       string
+	ParamsHash string
+	Paths      []string
+}
+
+// CampaignStats aggregates the links of one campaign.
+type CampaignStats struct {
+	CampaignID string
+	Links      int64
+	Clicks     int64
 }
 
 type linkRepository struct {
-	db *gorm.DB
+	db            *gorm.DB
+	replica       *gorm.DB
+	retry         RetryPolicy
+	hashAlgorithm models.ParamsHashAlgorithm
+	tableName     string
 }
 
-func NewLinkRepository(db *gorm.DB) LinkRepository {
-	return &linkRepository{
-		db: db,
+// Option customizes a linkRepository created by NewLinkRepository.
+type Option func(*linkRepository)
+
+// WithRetryPolicy replaces DefaultRetryPolicy, use NoRetry to disable retries.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(r *linkRepository) {
+		r.retry = p
 	}
 }
 
-func (r *linkRepository) GetLinkByHostAndPath(ctx context.Context, host, path string, projectID *uuid.UUID) (*models.DurableLink, error) {
-	var dbLink models.DurableLinkDB
+// WithParamsHashAlgorithm selects the algorithm params hashes are stored and looked up with,
+// models.ParamsHashSHA256 by default. Links stored under another algorithm are not found as
+// duplicates after switching.
+func WithParamsHashAlgorithm(a models.ParamsHashAlgorithm) Option {
+	return func(r *linkRepository) {
+		r.hashAlgorithm = a
+	}
+}
 
-	query := r.db.WithContext(ctx).Where("host = ? AND path = ?", host, path)
+// WithReadReplica routes lookups, listings and click statistics to replica, a read-only
+// connection that may lag behind the primary. Writes, click counting, path availability checks,
+// FindExistingShortLink and reads on a context from WithPrimaryReads always use the primary.
+func WithReadReplica(replica *gorm.DB) Option {
+	return func(r *linkRepository) {
+		r.replica = replica
+	}
+}
 
-	if projectID != nil {
-		projectIDStr := projectID.String()
-		query = query.Where("project_id = ?", projectIDStr)
+// tableNamePattern matches a table name, optionally qualified by its schema. Names are
+// interpolated into raw SQL, so nothing that would need quoting is accepted.
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}(\.[A-Za-z_][A-Za-z0-9_]{0,62})?$`)
+
+// WithTableName stores links in name, e.g. "tenant_a.durable_links", instead of the table of
+// models.DurableLinkDB. The table must have been created with the model's columns, e.g. by
+// db.Table(name).AutoMigrate(&models.DurableLinkDB{}). Like regexp.MustCompile it panics when
+// name is not a valid identifier, as table names come from deployment config.
+func WithTableName(name string) Option {
+	if !tableNamePattern.MatchString(name) {
+		panic(fmt.Sprintf("%v: %q", ErrInvalidTableName, name))
+	}
+	return func(r *linkRepository) {
+		r.tableName = name
 	}
+}
+
+func NewLinkRepository(db *gorm.DB, opts ...Option) LinkRepository {
+	r := &linkRepository{
+		db:        db,
+		retry:     DefaultRetryPolicy,
+		tableName: models.DurableLinkDB{}.TableName(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
 
-	err := query.First(&dbLink).Error
+// NewLinkRepositoryWithReplica is NewLinkRepository with WithReadReplica(replica). A nil replica
+// uses primary for everything.
+func NewLinkRepositoryWithReplica(primary, replica *gorm.DB, opts ...Option) LinkRepository {
+	return NewLinkRepository(primary, append(opts, WithReadReplica(replica))...)
+}
+
+// links starts a query of db on the links table.
+func (r *linkRepository) links(db *gorm.DB) *gorm.DB {
+	return db.Table(r.tableName)
+}
+
+type primaryReadsKey struct{}
+
+// WithPrimaryReads returns a copy of ctx whose reads go to the primary even when a read replica
+// is configured. Checks made before an insert use it, as a lagging replica would miss the rows
+// they are looking for.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// reader returns the connection for read queries: the replica if configured and ctx does not
+// ask for primary reads, else the primary.
+func (r *linkRepository) reader(ctx context.Context) *gorm.DB {
+	if primary, _ := ctx.Value(primaryReadsKey{}).(bool); r.replica != nil && !primary {
+		return r.replica
+	}
+	return r.db
+}
+
+func (r *linkRepository) GetLinkByHostAndPath(ctx context.Context, host, path string, projectID *uuid.UUID) (*models.DurableLink, error) {
+	dbLink, err := r.GetLinkDBByHostAndPath(ctx, host, path, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	dl := dbLink.ToDurableLink()
+	return &dl, nil
+}
+
+// GetLinkDBByHostAndPath returns the full stored row, including columns that are not part of
+// models.DurableLink such as access control and counters.
+func (r *linkRepository) GetLinkDBByHostAndPath(ctx context.Context, host, path string, projectID *uuid.UUID) (*models.DurableLinkDB, error) {
+	var dbLink models.DurableLinkDB
+
+	err := r.withRetry(ctx, func() error {
+		return r.scopeHostPath(r.links(r.reader(ctx).WithContext(ctx)), host, path, projectID).First(&dbLink).Error
+	})
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -54,42 +214,1083 @@ func (r *linkRepository) GetLinkByHostAndPath(ctx context.Context, host, path st
 		return nil, err
 	}
 
-	dl := dbLink.ToDurableLink()
-	return &dl, nil
+	return &dbLink, nil
 }
 
-func (r *linkRepository) FindExistingShortLink(ctx context.Context, host string, link *models.DurableLink, projectID *uuid.UUID) (string, error) {
+// GetLatestLinkByTarget returns the most recently created link on host pointing at targetLink.
+// Unlike FindExistingShortLink it considers every link, including unguessable and protected ones.
+// The full row is returned, as the short link is built from its path.
+func (r *linkRepository) GetLatestLinkByTarget(ctx context.Context, host, targetLink string, projectID *uuid.UUID) (*models.DurableLinkDB, error) {
+	var dbLink models.DurableLinkDB
+
+	err := r.withRetry(ctx, func() error {
+		return scopeProject(r.links(r.reader(ctx).WithContext(ctx)), projectID).
+			Where("host = ? AND link = ?", host, targetLink).
+			Order("created_at DESC").
+			Order("id DESC").
+			Take(&dbLink).Error
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLinkNotFound
+		}
+		log.Error().
+			Err(err).
+			Str("host", host).
+			Str("link", targetLink).
+			Msg("Failed to retrieve latest link for target")
+		return nil, err
+	}
+
+	return &dbLink, nil
+}
+
+// FindExistingShortLink returns the path of a shareable short link on host with the same target and
+// params as link. A non-zero createdAfter skips links created at or before it.
+func (r *linkRepository) FindExistingShortLink(ctx context.Context, host string, link *models.DurableLink, projectID *uuid.UUID, createdAfter time.Time) (string, error) {
 	var result struct {
 		Path string
 	}
 
 	dbLink := models.FromDurableLink(*link, "", "", false, nil)
+	dbLink.ParamsHashAlgorithm = r.hashAlgorithm
 	paramsHash := dbLink.ComputeParamsHash()
 
-	query := r.db.WithContext(ctx).
-		Model(&models.DurableLinkDB{}).
-		Select("path").
-		Where("host = ?", host).
-		Where("link = ?", link.Link).
-		Where("params_hash = ?", paramsHash).
-		Where("is_unguessable_path = ?", false)
+	// Only creates look for existing links, so this always reads the primary
+	err := r.withRetry(ctx, func() error {
+		query := r.links(r.db.WithContext(ctx)).
+			Model(&models.DurableLinkDB{}).
+			Select("path").
+			Where("host = ?", host).
+			Where("link = ?", link.Link).
+			Where("params_hash = ?", paramsHash).
+			Where("is_unguessable_path = ?", false).
+			Where("password_hash IS NULL").
+			Where("max_clicks IS NULL")
+		query = scopeProject(query, projectID)
+		if !createdAfter.IsZero() {
+			query = query.Where("created_at > ?", createdAfter)
+		}
+
+		return query.Limit(1).First(&result).Error
+	})
+	return result.Path, err
+}
 
+func (r *linkRepository) CreateShortLink(ctx context.Context, link *models.DurableLinkDB, projectID *uuid.UUID) error {
 	if projectID != nil {
 		projectIDStr := projectID.String()
-		query = query.Where("project_id = ?", projectIDStr)
-	} else {
-		query = query.Where("project_id IS NULL")
+		link.ProjectID = &projectIDStr
 	}
+	link.ParamsHashAlgorithm = r.hashAlgorithm
 
-	err := query.Limit(1).First(&result).Error
-	return result.Path, err
+	return r.withWriteRetry(ctx, func() error {
+		result := r.links(r.db.WithContext(ctx)).Create(link)
+		if result.Error != nil {
+			return result.Error
+		}
+		// Guards against inserts silently skipped by a conflict clause or a misbehaving driver
+		if result.RowsAffected != 1 {
+			log.Error().
+				Str("host", link.Host).
+				Str("path", link.Path).
+				Int64("rows_affected", result.RowsAffected).
+				Msg("Insert did not store the link")
+			return ErrLinkNotInserted
+		}
+		return nil
+	})
 }
 
-func (r *linkRepository) CreateShortLink(ctx context.Context, link *models.DurableLinkDB, projectID *uuid.UUID) error {
+// CreateLinkIfPathFree stores link like CreateShortLink, but as an INSERT ... ON CONFLICT DO
+// NOTHING on its host, path and path scope. Of concurrent creates of the same path, all but one
+// fail with ErrPathTaken instead of a constraint violation.
+func (r *linkRepository) CreateLinkIfPathFree(ctx context.Context, link *models.DurableLinkDB, projectID *uuid.UUID) error {
 	if projectID != nil {
 		projectIDStr := projectID.String()
 		link.ProjectID = &projectIDStr
 	}
+	link.ParamsHashAlgorithm = r.hashAlgorithm
+
+	return r.withWriteRetry(ctx, func() error {
+		result := r.links(r.db.WithContext(ctx)).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "host"}, {Name: "path"}, {Name: "path_scope"}},
+				DoNothing: true,
+			}).
+			Create(link)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			log.Debug().
+				Str("host", link.Host).
+				Str("path", link.Path).
+				Msg("Path was taken before the insert")
+			return ErrPathTaken
+		}
+		return nil
+	})
+}
+
+// UpsertByIdempotencyKey stores link unless projectID already has a link with its IdempotencyKey,
+// and returns the path of the stored link. The insert is an INSERT ... ON CONFLICT DO NOTHING on
+// the idempotency key, so concurrent retries converge on the link of whichever insert won. A
+// project is required: links without one have a NULL project_id, which the unique index can't
+// compare, so their keys would never conflict.
+func (r *linkRepository) UpsertByIdempotencyKey(ctx context.Context, link *models.DurableLinkDB, projectID uuid.UUID) (string, error) {
+	if link.IdempotencyKey == nil || *link.IdempotencyKey == "" {
+		return "", ErrMissingIdempotencyKey
+	}
+	if projectID == uuid.Nil {
+		return "", ErrMissingProjectID
+	}
+	projectIDStr := projectID.String()
+	link.ProjectID = &projectIDStr
+	link.ParamsHashAlgorithm = r.hashAlgorithm
+
+	var path string
+	err := r.withWriteRetry(ctx, func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			result := r.links(tx).
+				Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "idempotency_key"}, {Name: "project_id"}},
+					DoNothing: true,
+				}).
+				Create(link)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				path = link.Path
+				return nil
+			}
+
+			return r.links(tx).Model(&models.DurableLinkDB{}).
+				Select("path").
+				Where("idempotency_key = ?", *link.IdempotencyKey).
+				Where("project_id = ?", projectIDStr).
+				Take(&path).Error
+		})
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("host", link.Host).
+			Str("idempotency_key", *link.IdempotencyKey).
+			Msg("Failed to upsert link by idempotency key")
+		return "", err
+	}
+
+	return path, nil
+}
+
+// ResolveAndIncrementClicks increments the click count of a link and returns the updated row.
+// On databases supporting RETURNING this is a single UPDATE ... RETURNING statement, otherwise
+// the UPDATE and a SELECT run in one transaction. The UPDATE only matches links below their
+// MaxClicks, so concurrent resolves can never go past the limit; ErrClickLimitReached is
+// returned once it is reached. The click is also added to the link's daily click counts.
+func (r *linkRepository) ResolveAndIncrementClicks(ctx context.Context, host, path string, projectID *uuid.UUID) (*models.DurableLinkDB, error) {
+	resolve := r.resolveAndIncrementClicksReturning
+	if !r.supportsReturning() {
+		resolve = r.resolveAndIncrementClicksTx
+	}
+
+	dbLink, err := resolve(ctx, host, path, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.recordDailyClick(ctx, dbLink.ID)
+	return dbLink, nil
+}
+
+func (r *linkRepository) resolveAndIncrementClicksReturning(ctx context.Context, host, path string, projectID *uuid.UUID) (*models.DurableLinkDB, error) {
+	var dbLink models.DurableLinkDB
+	var rowsAffected int64
+	err := r.withWriteRetry(ctx, func() error {
+		result := scopeBelowMaxClicks(r.scopeHostPath(r.links(r.db.WithContext(ctx)).Model(&dbLink), host, path, projectID)).
+			Clauses(clause.Returning{}).
+			UpdateColumn("click_count", gorm.Expr("click_count + ?", 1))
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("host", host).
+			Str("path", path).
+			Msg("Failed to resolve and count click")
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, r.clickMissError(r.db.WithContext(ctx), host, path, projectID)
+	}
+
+	return &dbLink, nil
+}
+
+func (r *linkRepository) resolveAndIncrementClicksTx(ctx context.Context, host, path string, projectID *uuid.UUID) (*models.DurableLinkDB, error) {
+	var dbLink models.DurableLinkDB
+	err := r.withWriteRetry(ctx, func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			result := scopeBelowMaxClicks(r.scopeHostPath(r.links(tx).Model(&models.DurableLinkDB{}), host, path, projectID)).
+				UpdateColumn("click_count", gorm.Expr("click_count + ?", 1))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return r.clickMissError(tx, host, path, projectID)
+			}
+
+			return r.scopeHostPath(r.links(tx), host, path, projectID).First(&dbLink).Error
+		})
+	})
+	if err != nil {
+		if !errors.Is(err, ErrLinkNotFound) && !errors.Is(err, ErrClickLimitReached) {
+			log.Error().
+				Err(err).
+				Str("host", host).
+				Str("path", path).
+				Msg("Failed to resolve and count click")
+		}
+		return nil, err
+	}
+
+	return &dbLink, nil
+}
+
+// recordDailyClick counts a click of the link with linkID in today's bucket. The daily counts
+// only feed GetClickTimeSeries, so failures are logged instead of failing the resolve.
+func (r *linkRepository) recordDailyClick(ctx context.Context, linkID int64) {
+	daily := &models.DailyClicksDB{LinkID: linkID, Day: startOfDay(time.Now()), Clicks: 1}
+	err := r.withWriteRetry(ctx, func() error {
+		return r.db.WithContext(ctx).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "link_id"}, {Name: "day"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"clicks": gorm.Expr("clicks + ?", 1)}),
+			}).
+			Create(daily).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Int64("link_id", linkID).
+			Msg("Failed to record daily click")
+	}
+}
+
+// GetClickTimeSeries returns the clicks of the link at host and path between from and to
+// (inclusive), one bucket per granularity period in order. Periods without clicks are
+// included with a count of zero.
+func (r *linkRepository) GetClickTimeSeries(ctx context.Context, host, path string, projectID *uuid.UUID, from, to time.Time, granularity Granularity) ([]TimeBucket, error) {
+	if from.After(to) {
+		return nil, ErrInvalidDateRange
+	}
+
+	dbLink, err := r.GetLinkDBByHostAndPath(ctx, host, path, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []models.DailyClicksDB
+	err = r.withRetry(ctx, func() error {
+		return r.reader(ctx).WithContext(ctx).
+			Where("link_id = ?", dbLink.ID).
+			Where("day >= ? AND day <= ?", startOfDay(from), startOfDay(to)).
+			Order("day ASC").
+			Find(&days).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("host", host).
+			Str("path", path).
+			Msg("Failed to load click time series")
+		return nil, err
+	}
+
+	return bucketClicks(days, from, to, granularity), nil
+}
+
+// scopeBelowMaxClicks restricts query to links that may still be clicked.
+func scopeBelowMaxClicks(query *gorm.DB) *gorm.DB {
+	return query.Where("max_clicks IS NULL OR click_count < max_clicks")
+}
+
+// clickMissError explains why a click update matched no row: either the link doesn't exist
+// or it has used up its clicks.
+func (r *linkRepository) clickMissError(db *gorm.DB, host, path string, projectID *uuid.UUID) error {
+	var count int64
+	if err := r.scopeHostPath(r.links(db).Model(&models.DurableLinkDB{}), host, path, projectID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrLinkNotFound
+	}
+	return ErrClickLimitReached
+}
+
+// supportsReturning reports whether the dialect can return rows from an UPDATE.
+func (r *linkRepository) supportsReturning() bool {
+	return slices.Contains(r.db.Callback().Update().Clauses, "RETURNING")
+}
+
+// scopeHostPath restricts query to the link at host and path within projectID, or among links
+// without a project when nil.
+func (r *linkRepository) scopeHostPath(query *gorm.DB, host, path string, projectID *uuid.UUID) *gorm.DB {
+	return scopeProject(query.Where("host = ? AND path = ?", host, path), projectID)
+}
+
+// IsPathAvailable reports whether no link, in any project, uses path on host.
+func (r *linkRepository) IsPathAvailable(ctx context.Context, host, path string) (bool, error) {
+	var count int64
+	err := r.withRetry(ctx, func() error {
+		return r.links(r.db.WithContext(ctx)).
+			Model(&models.DurableLinkDB{}).
+			Where("host = ? AND path = ?", host, path).
+			Count(&count).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// IsPathDeleted reports whether a link of projectID, or a link without a project when nil, at
+// path on host has been deleted. It is only meaningful once no link is found at the path.
+func (r *linkRepository) IsPathDeleted(ctx context.Context, host, path string, projectID *uuid.UUID) (bool, error) {
+	var count int64
+	err := r.withRetry(ctx, func() error {
+		return scopeProject(r.reader(ctx).WithContext(ctx).Model(&models.DeletedPathDB{}).Where("host = ? AND path = ?", host, path), projectID).
+			Count(&count).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("host", host).
+			Str("path", path).
+			Msg("Failed to look up deleted path")
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsPathAvailableInProject reports whether no link of projectID, or no link without a project
+// when nil, uses path on host. Other projects may use the same path.
+func (r *linkRepository) IsPathAvailableInProject(ctx context.Context, host, path string, projectID *uuid.UUID) (bool, error) {
+	var count int64
+	err := r.withRetry(ctx, func() error {
+		return r.scopeHostPath(r.links(r.db.WithContext(ctx)).Model(&models.DurableLinkDB{}), host, path, projectID).
+			Count(&count).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// UpdateLinkTarget points an existing link at newLink, leaving every other column as is.
+// The params hash does not cover the link itself, so it stays valid and is not recomputed.
+func (r *linkRepository) UpdateLinkTarget(ctx context.Context, host, path, newLink string, projectID *uuid.UUID) error {
+	var rowsAffected int64
+	err := r.withWriteRetry(ctx, func() error {
+		// UpdateColumns skips the BeforeUpdate hook, which would otherwise recompute the
+		// hash from an empty model
+		result := r.scopeHostPath(r.links(r.db.WithContext(ctx)).Model(&models.DurableLinkDB{}), host, path, projectID).
+			UpdateColumns(map[string]interface{}{
+				"link":       newLink,
+				"updated_at": time.Now(),
+			})
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("host", host).
+			Str("path", path).
+			Msg("Failed to update link target")
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrLinkNotFound
+	}
+
+	return nil
+}
+
+// GetRawRequest returns the original create request stored with the link at host and path
+// within projectID, or an empty string when the link was created without raw request storage.
+func (r *linkRepository) GetRawRequest(ctx context.Context, host, path string, projectID *uuid.UUID) (string, error) {
+	var result struct {
+		RawRequest *string
+	}
+
+	err := r.withRetry(ctx, func() error {
+		return r.scopeHostPath(r.links(r.reader(ctx).WithContext(ctx)).Model(&models.DurableLinkDB{}), host, path, projectID).
+			Select("raw_request").
+			First(&result).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrLinkNotFound
+		}
+		return "", err
+	}
+
+	if result.RawRequest == nil {
+		return "", nil
+	}
+	return *result.RawRequest, nil
+}
+
+// ListDistinctHosts returns every host that has at least one link, sorted. A nil projectID
+// lists hosts across all projects.
+func (r *linkRepository) ListDistinctHosts(ctx context.Context, projectID *uuid.UUID) ([]string, error) {
+	hosts := []string{}
+	err := r.withRetry(ctx, func() error {
+		query := r.links(r.reader(ctx).WithContext(ctx)).
+			Model(&models.DurableLinkDB{}).
+			Distinct("host")
+
+		if projectID != nil {
+			projectIDStr := projectID.String()
+			query = query.Where("project_id = ?", projectIDStr)
+		}
+
+		return query.Order("host ASC").Pluck("host", &hosts).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to list distinct hosts")
+		return nil, err
+	}
 
-	return r.db.WithContext(ctx).Create(link).Error
+	return hosts, nil
 }
+
+// CountLinks returns how many links projectID has, or how many links have no project when nil.
+func (r *linkRepository) CountLinks(ctx context.Context, projectID *uuid.UUID) (int64, error) {
+	var count int64
+	err := r.withRetry(ctx, func() error {
+		return scopeProject(r.links(r.db.WithContext(ctx)).Model(&models.DurableLinkDB{}), projectID).
+			Count(&count).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to count links")
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListLinks returns a page of links ordered by id, so pages stay stable while paging through.
+func (r *linkRepository) ListLinks(ctx context.Context, projectID *uuid.UUID, limit, offset int) ([]models.DurableLinkDB, error) {
+	var links []models.DurableLinkDB
+	err := r.withRetry(ctx, func() error {
+		return scopeProject(r.links(r.reader(ctx).WithContext(ctx)), projectID).
+			Order("id ASC").
+			Limit(limit).
+			Offset(offset).
+			Find(&links).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to list links")
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// ListLinksAfter returns up to limit links with an id above afterID, ordered by id, and the cursor
+// to pass as afterID for the next page. The cursor is 0 once there are no more links. Unlike
+// offsets, cursors don't skip or repeat links when links are inserted while paging.
+func (r *linkRepository) ListLinksAfter(ctx context.Context, projectID *uuid.UUID, afterID int64, limit int) ([]models.DurableLinkDB, int64, error) {
+	if limit <= 0 {
+		return nil, 0, ErrInvalidBatchSize
+	}
+
+	var links []models.DurableLinkDB
+	// One extra link tells whether another page follows
+	err := r.withRetry(ctx, func() error {
+		return scopeProject(r.links(r.reader(ctx).WithContext(ctx)), projectID).
+			Where("id > ?", afterID).
+			Order("id ASC").
+			Limit(limit + 1).
+			Find(&links).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Int64("after_id", afterID).
+			Msg("Failed to list links after cursor")
+		return nil, 0, err
+	}
+
+	if len(links) <= limit {
+		return links, 0, nil
+	}
+	links = links[:limit]
+	return links, links[limit-1].ID, nil
+}
+
+// IterateLinks calls fn for every link of projectID in id order, reading one row at a time from a
+// single query so memory stays flat however many links there are. It stops at the first error
+// fn returns and returns that error. Only opening the query is retried, errors once fn has seen
+// links are returned as they are.
+func (r *linkRepository) IterateLinks(ctx context.Context, projectID *uuid.UUID, fn func(*models.DurableLinkDB) error) error {
+	db := r.reader(ctx).WithContext(ctx)
+	var rows *sql.Rows
+	err := r.withRetry(ctx, func() error {
+		var err error
+		rows, err = scopeProject(r.links(db).Model(&models.DurableLinkDB{}), projectID).
+			Order("id ASC").
+			Rows()
+		return err
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to iterate links")
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link models.DurableLinkDB
+		if err := db.ScanRows(rows, &link); err != nil {
+			return err
+		}
+		if err := fn(&link); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ListRecentLinks returns the limit most recently created links, newest first. Unguessable and
+// password protected links are left out, as the result is published in feeds.
+func (r *linkRepository) ListRecentLinks(ctx context.Context, projectID *uuid.UUID, limit int) ([]models.DurableLinkDB, error) {
+	var links []models.DurableLinkDB
+	err := r.withRetry(ctx, func() error {
+		return scopeProject(r.links(r.reader(ctx).WithContext(ctx)), projectID).
+			Where("is_unguessable_path = ?", false).
+			Where("password_hash IS NULL").
+			Order("created_at DESC").
+			Order("id DESC").
+			Limit(limit).
+			Find(&links).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to list recent links")
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// ListLinksByDateRange returns links created between from and to (inclusive), oldest first.
+func (r *linkRepository) ListLinksByDateRange(ctx context.Context, projectID *uuid.UUID, from, to time.Time, limit, offset int) ([]models.DurableLinkDB, error) {
+	if from.After(to) {
+		return nil, ErrInvalidDateRange
+	}
+
+	var links []models.DurableLinkDB
+	err := r.withRetry(ctx, func() error {
+		query := r.links(r.reader(ctx).WithContext(ctx)).
+			Where("created_at BETWEEN ? AND ?", from, to)
+		return scopeProject(query, projectID).
+			Order("created_at ASC").
+			Limit(limit).
+			Offset(offset).
+			Find(&links).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Time("from", from).
+			Time("to", to).
+			Msg("Failed to list links by date range")
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// ListLinksByName returns links whose name contains name, ignoring case, oldest first.
+func (r *linkRepository) ListLinksByName(ctx context.Context, projectID *uuid.UUID, name string, limit, offset int) ([]models.DurableLinkDB, error) {
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(name)) + "%"
+
+	var links []models.DurableLinkDB
+	err := r.withRetry(ctx, func() error {
+		query := r.links(r.reader(ctx).WithContext(ctx)).
+			Where("LOWER(name) LIKE ? ESCAPE '\\'", pattern)
+		return scopeProject(query, projectID).
+			Order("created_at ASC").
+			Limit(limit).
+			Offset(offset).
+			Find(&links).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("name", name).
+			Msg("Failed to list links by name")
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// ListDuplicateGroups reports links that were stored more than once with the same host, target
+// and params, typically as separate unguessable links. Groups and their paths are sorted.
+func (r *linkRepository) ListDuplicateGroups(ctx context.Context, projectID *uuid.UUID) ([]DuplicateGroup, error) {
+	var rows []struct {
+		Host       string
+		Link       string
+		ParamsHash string
+		Path       string
+	}
+	// The table name is safe to interpolate, WithTableName only accepts plain identifiers
+	t := r.tableName
+	err := r.withRetry(ctx, func() error {
+		duplicates := scopeProject(r.links(r.reader(ctx)).Model(&models.DurableLinkDB{}), projectID).
+			Select("host, link, params_hash").
+			Group("host, link, params_hash").
+			Having("COUNT(*) > 1")
+
+		return scopeProject(r.links(r.reader(ctx).WithContext(ctx)).Model(&models.DurableLinkDB{}), projectID).
+			Select(fmt.Sprintf("%[1]s.host, %[1]s.link, %[1]s.params_hash, %[1]s.path", t)).
+			Joins(fmt.Sprintf("JOIN (?) AS dup ON dup.host = %[1]s.host AND dup.link = %[1]s.link AND dup.params_hash = %[1]s.params_hash", t), duplicates).
+			Order(fmt.Sprintf("%[1]s.host ASC, %[1]s.link ASC, %[1]s.params_hash ASC, %[1]s.path ASC", t)).
+			Scan(&rows).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to list duplicate links")
+		return nil, err
+	}
+
+	groups := []DuplicateGroup{}
+	for _, row := range rows {
+		last := len(groups) - 1
+		if last < 0 || groups[last].Host != row.Host || groups[last].Link != row.Link || groups[last].ParamsHash != row.ParamsHash {
+			groups = append(groups, DuplicateGroup{Host: row.Host, Link: row.Link, ParamsHash: row.ParamsHash})
+			last++
+		}
+		groups[last].Paths = append(groups[last].Paths, row.Path)
+	}
+
+	return groups, nil
+}
+
+// GetCampaignStats counts the links of campaignID in projectID and sums their clicks. A campaign
+// without links has zero stats.
+func (r *linkRepository) GetCampaignStats(ctx context.Context, projectID *uuid.UUID, campaignID string) (*CampaignStats, error) {
+	var totals struct {
+		Links  int64
+		Clicks int64
+	}
+	err := r.withRetry(ctx, func() error {
+		return scopeProject(r.links(r.reader(ctx).WithContext(ctx)).Model(&models.DurableLinkDB{}), projectID).
+			Select("COUNT(*) AS links, COALESCE(SUM(click_count), 0) AS clicks").
+			Where("campaign_id = ?", campaignID).
+			Scan(&totals).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("campaign_id", campaignID).
+			Msg("Failed to get campaign stats")
+		return nil, err
+	}
+
+	return &CampaignStats{CampaignID: campaignID, Links: totals.Links, Clicks: totals.Clicks}, nil
+}
+
+// GetTenantSettings returns the stored settings of tenantID by key, empty when it has none.
+func (r *linkRepository) GetTenantSettings(ctx context.Context, tenantID string) (map[string]string, error) {
+	var rows []models.TenantSettingDB
+	err := r.withRetry(ctx, func() error {
+		return r.reader(ctx).WithContext(ctx).
+			Where("tenant_id = ?", tenantID).
+			Find(&rows).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("tenant_id", tenantID).
+			Msg("Failed to get tenant settings")
+		return nil, err
+	}
+
+	settings := make(map[string]string, len(rows))
+	for _, row := range rows {
+		settings[row.Key] = row.Value
+	}
+	return settings, nil
+}
+
+// SetTenantSetting stores value under key for tenantID, replacing any previous value.
+func (r *linkRepository) SetTenantSetting(ctx context.Context, tenantID, key, value string) error {
+	setting := &models.TenantSettingDB{TenantID: tenantID, Key: key, Value: value}
+	err := r.withWriteRetry(ctx, func() error {
+		return r.db.WithContext(ctx).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+			}).
+			Create(setting).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("tenant_id", tenantID).
+			Str("key", key).
+			Msg("Failed to set tenant setting")
+	}
+	return err
+}
+
+// DeleteLinksByProject removes every link of projectID in a single statement and returns how many
+// were removed. The nil UUID is rejected, so a zero value can never wipe unrelated links.
+// GORM turns this into a soft delete should the model ever get a DeletedAt column.
+func (r *linkRepository) DeleteLinksByProject(ctx context.Context, projectID uuid.UUID) (int64, error) {
+	if projectID == uuid.Nil {
+		return 0, ErrMissingProjectID
+	}
+
+	var deleted int64
+	err := r.withWriteRetry(ctx, func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			projectLinks := r.links(tx).Model(&models.DurableLinkDB{}).
+				Select("id").
+				Where("project_id = ?", projectID.String())
+			if err := tx.Where("link_id IN (?)", projectLinks).Delete(&models.LinkTagDB{}).Error; err != nil {
+				return err
+			}
+
+			result := r.links(tx).
+				Where("project_id = ?", projectID.String()).
+				Delete(&models.DurableLinkDB{})
+			deleted = result.RowsAffected
+			return result.Error
+		})
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("project_id", projectID.String()).
+			Msg("Failed to delete project links")
+		return 0, err
+	}
+
+	log.Debug().
+		Str("project_id", projectID.String()).
+		Int64("deleted", deleted).
+		Msg("Deleted project links")
+
+	return deleted, nil
+}
+
+// DeleteLink deletes the link at host and path along with its tags. With releasePath the path
+// is added to the pool of released paths in the same transaction, for PopReleasedPath to hand
+// out again.
+func (r *linkRepository) DeleteLink(ctx context.Context, host, path string, projectID *uuid.UUID, releasePath bool) error {
+	err := r.withWriteRetry(ctx, func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			link := r.scopeHostPath(r.links(tx).Model(&models.DurableLinkDB{}).Select("id"), host, path, projectID)
+			if err := tx.Where("link_id IN (?)", link).Delete(&models.LinkTagDB{}).Error; err != nil {
+				return err
+			}
+
+			result := r.scopeHostPath(r.links(tx), host, path, projectID).Delete(&models.DurableLinkDB{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrLinkNotFound
+			}
+
+			deleted := &models.DeletedPathDB{Host: host, Path: path}
+			if projectID != nil {
+				projectIDStr := projectID.String()
+				deleted.ProjectID = &projectIDStr
+			}
+			if err := tx.Create(deleted).Error; err != nil {
+				return err
+			}
+			if !releasePath {
+				return nil
+			}
+
+			released := &models.ReleasedPathDB{Host: host, Path: path, Length: len(path)}
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(released).Error
+		})
+	})
+	if err != nil && !errors.Is(err, ErrLinkNotFound) {
+		log.Error().
+			Err(err).
+			Str("host", host).
+			Str("path", path).
+			Msg("Failed to delete link")
+	}
+	return err
+}
+
+// tagPattern is the format of link tags, which are matched exactly.
+var tagPattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// AddTagToLinks tags the links of projectID at paths, on any host, with tag in one transaction.
+// Links already carrying the tag and paths without a link are skipped.
+func (r *linkRepository) AddTagToLinks(ctx context.Context, projectID uuid.UUID, paths []string, tag string) error {
+	return r.updateLinkTags(ctx, projectID, paths, tag, func(tx *gorm.DB, linkIDs []int64) error {
+		tags := make([]models.LinkTagDB, 0, len(linkIDs))
+		for _, id := range linkIDs {
+			tags = append(tags, models.LinkTagDB{LinkID: id, Tag: tag})
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error
+	})
+}
+
+// RemoveTagFromLinks removes tag from the links of projectID at paths, on any host, in one
+// transaction. Links without the tag and paths without a link are skipped.
+func (r *linkRepository) RemoveTagFromLinks(ctx context.Context, projectID uuid.UUID, paths []string, tag string) error {
+	return r.updateLinkTags(ctx, projectID, paths, tag, func(tx *gorm.DB, linkIDs []int64) error {
+		return tx.Where("tag = ? AND link_id IN ?", tag, linkIDs).Delete(&models.LinkTagDB{}).Error
+	})
+}
+
+// GetLinkTags returns the tags of the link at host and path, sorted, or ErrLinkNotFound when
+// there is no such link.
+func (r *linkRepository) GetLinkTags(ctx context.Context, host, path string, projectID *uuid.UUID) ([]string, error) {
+	link, err := r.GetLinkDBByHostAndPath(ctx, host, path, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	err = r.withRetry(ctx, func() error {
+		return r.reader(ctx).WithContext(ctx).
+			Model(&models.LinkTagDB{}).
+			Where("link_id = ?", link.ID).
+			Order("tag ASC").
+			Pluck("tag", &tags).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("host", host).
+			Str("path", path).
+			Msg("Failed to get link tags")
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// ListLinksByTag returns a page of the links of projectID carrying tag, oldest first.
+func (r *linkRepository) ListLinksByTag(ctx context.Context, projectID uuid.UUID, tag string, limit, offset int) ([]models.DurableLinkDB, error) {
+	if !tagPattern.MatchString(tag) {
+		return nil, ErrInvalidTag
+	}
+	if projectID == uuid.Nil {
+		return nil, ErrMissingProjectID
+	}
+
+	var links []models.DurableLinkDB
+	err := r.withRetry(ctx, func() error {
+		db := r.reader(ctx).WithContext(ctx)
+		tagged := db.Model(&models.LinkTagDB{}).Select("link_id").Where("tag = ?", tag)
+		return scopeProject(r.links(db), &projectID).
+			Where("id IN (?)", tagged).
+			Order("created_at ASC").
+			Limit(limit).
+			Offset(offset).
+			Find(&links).Error
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("project_id", projectID.String()).
+			Str("tag", tag).
+			Msg("Failed to list links by tag")
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// updateLinkTags validates tag and runs update for the ids of the links at paths in a transaction.
+func (r *linkRepository) updateLinkTags(ctx context.Context, projectID uuid.UUID, paths []string, tag string, update func(tx *gorm.DB, linkIDs []int64) error) error {
+	if !tagPattern.MatchString(tag) {
+		return ErrInvalidTag
+	}
+	if projectID == uuid.Nil {
+		return ErrMissingProjectID
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	err := r.withWriteRetry(ctx, func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var linkIDs []int64
+			err := scopeProject(r.links(tx).Model(&models.DurableLinkDB{}), &projectID).
+				Where("path IN ?", paths).
+				Pluck("id", &linkIDs).Error
+			if err != nil || len(linkIDs) == 0 {
+				return err
+			}
+			return update(tx, linkIDs)
+		})
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("project_id", projectID.String()).
+			Str("tag", tag).
+			Msg("Failed to update link tags")
+	}
+	return err
+}
+
+// PopReleasedPath removes the oldest released path of the given length and case on host from
+// the pool and returns it, or ErrNoReleasedPath when there is none. Concurrent callers never
+// receive the same path: the path is only handed out by the caller whose DELETE removed it.
+func (r *linkRepository) PopReleasedPath(ctx context.Context, host string, length int, pathCase ReleasedPathCase) (string, error) {
+	for range maxPathPops {
+		var released models.ReleasedPathDB
+		var deleted int64
+		err := r.withWriteRetry(ctx, func() error {
+			query := r.db.WithContext(ctx).Where("host = ? AND length = ?", host, length)
+			switch pathCase {
+			case LowerPathCase:
+				query = query.Where("path = LOWER(path)")
+			case UpperPathCase:
+				query = query.Where("path = UPPER(path)")
+			}
+			err := query.Order("id ASC").First(&released).Error
+			if err != nil {
+				return err
+			}
+
+			result := r.db.WithContext(ctx).Where("id = ?", released.ID).Delete(&models.ReleasedPathDB{})
+			deleted = result.RowsAffected
+			return result.Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrNoReleasedPath
+		}
+		if err != nil {
+			return "", err
+		}
+		if deleted == 1 {
+			return released.Path, nil
+		}
+		// Another caller popped the same path first, try the next one
+	}
+
+	return "", ErrNoReleasedPath
+}
+
+// RecomputeAllParamHashes pages through all links in primary key order, batchSize at a time, and
+// rewrites stale params hashes with the configured algorithm. It returns how many rows were
+// updated. Rows whose hash is already current are left alone, so running it again, including
+// after an interrupted run, only touches the rows still left to update.
+func (r *linkRepository) RecomputeAllParamHashes(ctx context.Context, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, ErrInvalidBatchSize
+	}
+
+	var updated int64
+	var lastID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return updated, err
+		}
+
+		var batch []models.DurableLinkDB
+		err := r.withRetry(ctx, func() error {
+			return r.links(r.db.WithContext(ctx)).
+				Where("id > ?", lastID).
+				Order("id ASC").
+				Limit(batchSize).
+				Find(&batch).Error
+		})
+		if err != nil {
+			log.Error().
+				Err(err).
+				Int64("after_id", lastID).
+				Msg("Failed to load links for params hash recomputation")
+			return updated, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var batchUpdated int64
+		err = r.withWriteRetry(ctx, func() error {
+			batchUpdated = 0
+			return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for i := range batch {
+					link := &batch[i]
+					link.ParamsHashAlgorithm = r.hashAlgorithm
+					hash := link.ComputeParamsHash()
+					if hash == link.ParamsHash {
+						continue
+					}
+					// UpdateColumn skips the BeforeUpdate hook, the hash is already computed
+					err := r.links(tx).Model(&models.DurableLinkDB{}).
+						Where("id = ?", link.ID).
+						UpdateColumn("params_hash", hash).Error
+					if err != nil {
+						return err
+					}
+					batchUpdated++
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			log.Error().
+				Err(err).
+				Int64("after_id", lastID).
+				Msg("Failed to update params hashes")
+			return updated, err
+		}
+
+		updated += batchUpdated
+		lastID = batch[len(batch)-1].ID
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	log.Debug().
+		Int64("updated", updated).
+		Msg("Recomputed params hashes")
+
+	return updated, nil
+}
+
+// scopeProject restricts query to links of projectID, or to links without a project when nil.
+// Project-scoped queries all go through it, as "project_id = ?" with a NULL arg matches nothing.
+func scopeProject(query *gorm.DB, projectID *uuid.UUID) *gorm.DB {
+	if projectID != nil {
+		return query.Where("project_id = ?", projectID.String())
+	}
+	return query.Where("project_id IS NULL")
+}
+
+// likeEscaper escapes LIKE wildcards so user input is matched literally.
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
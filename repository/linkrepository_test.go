@@ -2,9 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/apppanel/durablelinks-core/models"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -26,7 +33,7 @@ func setupTestDB(t *testing.T) (*gorm.DB, LinkRepository) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&models.DurableLinkDB{})
+	err = db.AutoMigrate(&models.DurableLinkDB{}, &models.LinkTagDB{}, &models.DeletedPathDB{})
 	require.NoError(t, err)
 
 	repo := NewLinkRepository(db)
@@ -41,6 +48,10 @@ func int64Ptr(i int64) *int64 {
 	return &i
 }
 
+func intPtr(i int) *int {
+	return &i
+}
+
 func TestGetLinkByHostAndPath_Success(t *testing.T) {
 	db, repo := setupTestDB(t)
 
@@ -116,7 +127,7 @@ func TestFindExistingShortLink_Found(t *testing.T) {
 	dbLink := models.FromDurableLink(*link, host, existingPath, false, nil)
 	db.Create(dbLink)
 
-	path, err := repo.FindExistingShortLink(context.Background(), host, link, nil)
+	path, err := repo.FindExistingShortLink(context.Background(), host, link, nil, time.Time{})
 	assert.NoError(t, err)
 	assert.Equal(t, existingPath, path)
 }
@@ -129,7 +140,7 @@ func TestFindExistingShortLink_NotFound(t *testing.T) {
 		Link: "https://example.com/target",
 	}
 
-	_, err := repo.FindExistingShortLink(context.Background(), "example.com", link, nil)
+	_, err := repo.FindExistingShortLink(context.Background(), "example.com", link, nil, time.Time{})
 	assert.Error(t, err)
 }
 
@@ -183,3 +194,1687 @@ func TestCreateShortLink_WithProjectID(t *testing.T) {
 	assert.NotNil(t, result.ProjectID)
 	assert.Equal(t, projectID.String(), *result.ProjectID)
 }
+
+func TestListLinksByDateRange(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	base := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	for i, path := range []string{"day0", "day2", "day4", "day6"} {
+		db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      path,
+			Link:      "https://example.com/target",
+			ProjectID: &projectIDStr,
+			CreatedAt: base.AddDate(0, 0, i*2),
+		})
+	}
+	// Same date range but belongs to no project
+	db.Create(&models.DurableLinkDB{
+		Host:      "example.com",
+		Path:      "global",
+		Link:      "https://example.com/target",
+		CreatedAt: base.AddDate(0, 0, 2),
+	})
+
+	links, err := repo.ListLinksByDateRange(context.Background(), &projectID, base.AddDate(0, 0, 1), base.AddDate(0, 0, 6), 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 3)
+	assert.Equal(t, "day2", links[0].Path)
+	assert.Equal(t, "day4", links[1].Path)
+	assert.Equal(t, "day6", links[2].Path)
+
+	links, err = repo.ListLinksByDateRange(context.Background(), &projectID, base, base.AddDate(0, 0, 6), 2, 1)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, "day2", links[0].Path)
+	assert.Equal(t, "day4", links[1].Path)
+
+	links, err = repo.ListLinksByDateRange(context.Background(), nil, base, base.AddDate(0, 0, 6), 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "global", links[0].Path)
+}
+
+func TestListLinksByDateRange_InvertedRange(t *testing.T) {
+	_, repo := setupTestDB(t)
+
+	now := time.Now()
+	links, err := repo.ListLinksByDateRange(context.Background(), nil, now, now.Add(-time.Hour), 10, 0)
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+	assert.Nil(t, links)
+}
+
+func TestIsPathAvailable(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectIDStr := uuid.New().String()
+	db.Create(&models.DurableLinkDB{
+		Host:      "example.com",
+		Path:      "taken",
+		Link:      "https://example.com/target",
+		ProjectID: &projectIDStr,
+	})
+
+	available, err := repo.IsPathAvailable(context.Background(), "example.com", "taken")
+	require.NoError(t, err)
+	assert.False(t, available)
+
+	available, err = repo.IsPathAvailable(context.Background(), "example.com", "free")
+	require.NoError(t, err)
+	assert.True(t, available)
+
+	available, err = repo.IsPathAvailable(context.Background(), "other.com", "taken")
+	require.NoError(t, err)
+	assert.True(t, available)
+}
+
+func TestIsPathAvailableInProject(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	otherID := uuid.New()
+	projectIDStr := projectID.String()
+	require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "taken", Link: "https://example.com", ProjectID: &projectIDStr}).Error)
+
+	available, err := repo.IsPathAvailableInProject(context.Background(), "example.com", "taken", &projectID)
+	require.NoError(t, err)
+	assert.False(t, available)
+
+	available, err = repo.IsPathAvailableInProject(context.Background(), "example.com", "taken", &otherID)
+	require.NoError(t, err)
+	assert.True(t, available)
+
+	available, err = repo.IsPathAvailableInProject(context.Background(), "example.com", "taken", nil)
+	require.NoError(t, err)
+	assert.True(t, available)
+}
+
+func TestLinkName_RoundTrip(t *testing.T) {
+	_, repo := setupTestDB(t)
+
+	link := models.DurableLink{
+		Host: "example.com",
+		Link: "https://example.com/target",
+		Name: stringPtr("Spring campaign"),
+	}
+	err := repo.CreateShortLink(context.Background(), models.FromDurableLink(link, "example.com", "spring", false, nil), nil)
+	require.NoError(t, err)
+
+	result, err := repo.GetLinkByHostAndPath(context.Background(), "example.com", "spring", nil)
+	require.NoError(t, err)
+	assert.Equal(t, stringPtr("Spring campaign"), result.Name)
+
+	// The name is descriptive only and must not affect dedup
+	path, err := repo.FindExistingShortLink(context.Background(), "example.com", &models.DurableLink{Link: "https://example.com/target"}, nil, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "spring", path)
+}
+
+func TestListLinksByName(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	names := map[string]string{
+		"a": "Spring Sale 2025",
+		"b": "spring launch",
+		"c": "Autumn sale",
+		"d": "100% off",
+	}
+	for path, name := range names {
+		db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      path,
+			Link:      "https://example.com/target",
+			Name:      stringPtr(name),
+			ProjectID: &projectIDStr,
+		})
+	}
+	db.Create(&models.DurableLinkDB{
+		Host: "example.com",
+		Path: "unnamed",
+		Link: "https://example.com/target",
+	})
+
+	links, err := repo.ListLinksByName(context.Background(), &projectID, "SPRING", 10, 0)
+	require.NoError(t, err)
+	paths := []string{}
+	for _, l := range links {
+		paths = append(paths, l.Path)
+	}
+	assert.ElementsMatch(t, []string{"a", "b"}, paths)
+
+	links, err = repo.ListLinksByName(context.Background(), &projectID, "sale", 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, links, 2)
+
+	// Wildcards in the search term are matched literally
+	links, err = repo.ListLinksByName(context.Background(), &projectID, "%", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "d", links[0].Path)
+
+	links, err = repo.ListLinksByName(context.Background(), nil, "spring", 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+func TestListLinks(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	for _, path := range []string{"first", "second", "third"} {
+		db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      path,
+			Link:      "https://example.com/target",
+			ProjectID: &projectIDStr,
+		})
+	}
+	db.Create(&models.DurableLinkDB{
+		Host: "example.com",
+		Path: "global",
+		Link: "https://example.com/target",
+	})
+
+	links, err := repo.ListLinks(context.Background(), &projectID, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, "first", links[0].Path)
+	assert.Equal(t, "second", links[1].Path)
+
+	links, err = repo.ListLinks(context.Background(), &projectID, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "third", links[0].Path)
+
+	links, err = repo.ListLinks(context.Background(), nil, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "global", links[0].Path)
+}
+
+func TestCountLinks(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	for _, path := range []string{"first", "second"} {
+		db.Create(&models.DurableLinkDB{Host: "example.com", Path: path, Link: "https://example.com/target", ProjectID: &projectIDStr})
+	}
+	db.Create(&models.DurableLinkDB{Host: "example.com", Path: "global", Link: "https://example.com/target"})
+
+	count, err := repo.CountLinks(context.Background(), &projectID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = repo.CountLinks(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	otherProject := uuid.New()
+	count, err = repo.CountLinks(context.Background(), &otherProject)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestListLinksAfter(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	for i := range 5 {
+		db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      fmt.Sprintf("path%d", i),
+			Link:      "https://example.com/target",
+			ProjectID: &projectIDStr,
+		})
+	}
+	db.Create(&models.DurableLinkDB{Host: "example.com", Path: "global", Link: "https://example.com/target"})
+
+	var paths []string
+	var cursor int64
+	pages := 0
+	for {
+		links, next, err := repo.ListLinksAfter(context.Background(), &projectID, cursor, 2)
+		require.NoError(t, err)
+		pages++
+		for _, link := range links {
+			paths = append(paths, link.Path)
+		}
+		if next == 0 {
+			break
+		}
+		assert.Greater(t, next, cursor, "cursor must advance")
+		cursor = next
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"path0", "path1", "path2", "path3", "path4"}, paths)
+
+	t.Run("exactly one full page ends without an empty page", func(t *testing.T) {
+		links, next, err := repo.ListLinksAfter(context.Background(), &projectID, 0, 5)
+		require.NoError(t, err)
+		assert.Len(t, links, 5)
+		assert.Zero(t, next)
+	})
+
+	t.Run("links inserted while paging are picked up", func(t *testing.T) {
+		links, next, err := repo.ListLinksAfter(context.Background(), &projectID, 0, 4)
+		require.NoError(t, err)
+		require.Len(t, links, 4)
+
+		db.Create(&models.DurableLinkDB{Host: "example.com", Path: "late", Link: "https://example.com/target", ProjectID: &projectIDStr})
+
+		links, next, err = repo.ListLinksAfter(context.Background(), &projectID, next, 4)
+		require.NoError(t, err)
+		require.Len(t, links, 2)
+		assert.Equal(t, "path4", links[0].Path)
+		assert.Equal(t, "late", links[1].Path)
+		assert.Zero(t, next)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		_, _, err := repo.ListLinksAfter(context.Background(), &projectID, 0, 0)
+		assert.ErrorIs(t, err, ErrInvalidBatchSize)
+	})
+}
+
+func TestIterateLinks(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	total := 25
+	for i := range total {
+		db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      fmt.Sprintf("path%d", i),
+			Link:      "https://example.com/target",
+			ProjectID: &projectIDStr,
+		})
+	}
+	db.Create(&models.DurableLinkDB{Host: "example.com", Path: "global", Link: "https://example.com/target"})
+
+	t.Run("visits every link of the project in order", func(t *testing.T) {
+		var paths []string
+		err := repo.IterateLinks(context.Background(), &projectID, func(link *models.DurableLinkDB) error {
+			paths = append(paths, link.Path)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, paths, total)
+		assert.Equal(t, "path0", paths[0])
+		assert.Equal(t, fmt.Sprintf("path%d", total-1), paths[total-1])
+	})
+
+	t.Run("stops at the first callback error", func(t *testing.T) {
+		errStop := errors.New("stop")
+		calls := 0
+		err := repo.IterateLinks(context.Background(), &projectID, func(*models.DurableLinkDB) error {
+			calls++
+			if calls == 3 {
+				return errStop
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, errStop)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("links without a project", func(t *testing.T) {
+		calls := 0
+		err := repo.IterateLinks(context.Background(), nil, func(link *models.DurableLinkDB) error {
+			calls++
+			assert.Equal(t, "global", link.Path)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestFindExistingShortLink_CreatedAfter(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dl := models.DurableLink{Link: "https://example.com/target"}
+	dbLink := models.FromDurableLink(dl, "example.com", "old", false, nil)
+	dbLink.CreatedAt = createdAt
+	require.NoError(t, db.Create(dbLink).Error)
+
+	path, err := repo.FindExistingShortLink(context.Background(), "example.com", &dl, nil, createdAt.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "old", path)
+
+	_, err = repo.FindExistingShortLink(context.Background(), "example.com", &dl, nil, createdAt)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestListRecentLinks(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, path := range []string{"oldest", "middle", "newest"} {
+		db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      path,
+			Link:      "https://example.com/target",
+			ProjectID: &projectIDStr,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	db.Create(&models.DurableLinkDB{
+		Host:      "example.com",
+		Path:      "global",
+		Link:      "https://example.com/target",
+		CreatedAt: base.Add(24 * time.Hour),
+	})
+	db.Create(&models.DurableLinkDB{
+		Host:              "example.com",
+		Path:              "unguessable",
+		Link:              "https://example.com/target",
+		IsUnguessablePath: true,
+		ProjectID:         &projectIDStr,
+		CreatedAt:         base.Add(48 * time.Hour),
+	})
+	db.Create(&models.DurableLinkDB{
+		Host:         "example.com",
+		Path:         "protected",
+		Link:         "https://example.com/target",
+		PasswordHash: stringPtr("$2a$10$hash"),
+		ProjectID:    &projectIDStr,
+		CreatedAt:    base.Add(48 * time.Hour),
+	})
+
+	links, err := repo.ListRecentLinks(context.Background(), &projectID, 2)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, "newest", links[0].Path)
+	assert.Equal(t, "middle", links[1].Path)
+
+	links, err = repo.ListRecentLinks(context.Background(), nil, 10)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "global", links[0].Path)
+}
+
+func TestScopeProject(t *testing.T) {
+	db, _ := setupTestDB(t)
+	projectID := uuid.New()
+
+	tests := []struct {
+		name       string
+		projectID  *uuid.UUID
+		expectSQL  string
+		expectVars []interface{}
+	}{
+		{
+			name:       "project compares by id",
+			projectID:  &projectID,
+			expectSQL:  "SELECT * FROM `apppanel_durable_links` WHERE project_id = ?",
+			expectVars: []interface{}{projectID.String()},
+		},
+		{
+			name:      "no project matches NULL",
+			projectID: nil,
+			expectSQL: "SELECT * FROM `apppanel_durable_links` WHERE project_id IS NULL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var links []models.DurableLinkDB
+			stmt := scopeProject(db.Session(&gorm.Session{DryRun: true}), tt.projectID).Find(&links).Statement
+
+			assert.Equal(t, tt.expectSQL, stmt.SQL.String())
+			assert.Equal(t, tt.expectVars, stmt.Vars)
+		})
+	}
+}
+
+func TestGetLatestLinkByTarget(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	links := []models.DurableLinkDB{
+		{Host: "example.com", Path: "old", Link: "https://example.com/target", ProjectID: &projectIDStr, CreatedAt: base},
+		{Host: "example.com", Path: "newest", Link: "https://example.com/target", ProjectID: &projectIDStr, IsUnguessablePath: true, CreatedAt: base.Add(2 * time.Hour)},
+		{Host: "example.com", Path: "middle", Link: "https://example.com/target", ProjectID: &projectIDStr, CreatedAt: base.Add(time.Hour)},
+		{Host: "example.com", Path: "other-target", Link: "https://example.com/other", ProjectID: &projectIDStr, CreatedAt: base.Add(3 * time.Hour)},
+		{Host: "other.com", Path: "other-host", Link: "https://example.com/target", ProjectID: &projectIDStr, CreatedAt: base.Add(3 * time.Hour)},
+		{Host: "example.com", Path: "global", Link: "https://example.com/target", CreatedAt: base.Add(3 * time.Hour)},
+	}
+	for i := range links {
+		require.NoError(t, db.Create(&links[i]).Error)
+	}
+
+	t.Run("newest link wins, including unguessable ones", func(t *testing.T) {
+		link, err := repo.GetLatestLinkByTarget(context.Background(), "example.com", "https://example.com/target", &projectID)
+		require.NoError(t, err)
+		assert.Equal(t, "newest", link.Path)
+	})
+
+	t.Run("links without a project are scoped separately", func(t *testing.T) {
+		link, err := repo.GetLatestLinkByTarget(context.Background(), "example.com", "https://example.com/target", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "global", link.Path)
+	})
+
+	t.Run("unknown target is not found", func(t *testing.T) {
+		_, err := repo.GetLatestLinkByTarget(context.Background(), "example.com", "https://example.com/missing", &projectID)
+		assert.ErrorIs(t, err, ErrLinkNotFound)
+	})
+}
+
+func TestLinkTags(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	otherProjectStr := uuid.New().String()
+	ids := map[string]int64{}
+	for _, link := range []models.DurableLinkDB{
+		{Host: "example.com", Path: "one", Link: "https://example.com/target", ProjectID: &projectIDStr},
+		{Host: "example.com", Path: "two", Link: "https://example.com/target", ProjectID: &projectIDStr},
+		{Host: "example.com", Path: "three", Link: "https://example.com/target", ProjectID: &projectIDStr},
+		{Host: "other.com", Path: "foreign", Link: "https://example.com/target", ProjectID: &otherProjectStr},
+	} {
+		require.NoError(t, db.Create(&link).Error)
+		ids[link.Path] = link.ID
+	}
+
+	taggedLinks := func(tag string) []int64 {
+		var linkIDs []int64
+		db.Model(&models.LinkTagDB{}).Where("tag = ?", tag).Order("link_id").Pluck("link_id", &linkIDs)
+		return linkIDs
+	}
+
+	t.Run("adds a tag to several links", func(t *testing.T) {
+		err := repo.AddTagToLinks(context.Background(), projectID, []string{"one", "two", "missing", "foreign"}, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids["one"], ids["two"]}, taggedLinks("spring-sale"))
+	})
+
+	t.Run("re-adding is idempotent", func(t *testing.T) {
+		err := repo.AddTagToLinks(context.Background(), projectID, []string{"one", "two", "three"}, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids["one"], ids["two"], ids["three"]}, taggedLinks("spring-sale"))
+	})
+
+	t.Run("removes a tag", func(t *testing.T) {
+		require.NoError(t, repo.AddTagToLinks(context.Background(), projectID, []string{"one"}, "summer"))
+
+		err := repo.RemoveTagFromLinks(context.Background(), projectID, []string{"one", "three"}, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids["two"]}, taggedLinks("spring-sale"))
+		assert.Equal(t, []int64{ids["one"]}, taggedLinks("summer"))
+
+		// Removing again is a no-op
+		require.NoError(t, repo.RemoveTagFromLinks(context.Background(), projectID, []string{"one"}, "spring-sale"))
+	})
+
+	t.Run("invalid tags are rejected", func(t *testing.T) {
+		for _, tag := range []string{"", "Spring", "spring sale", strings.Repeat("a", 65)} {
+			err := repo.AddTagToLinks(context.Background(), projectID, []string{"one"}, tag)
+			assert.ErrorIs(t, err, ErrInvalidTag, tag)
+			err = repo.RemoveTagFromLinks(context.Background(), projectID, []string{"one"}, tag)
+			assert.ErrorIs(t, err, ErrInvalidTag, tag)
+		}
+	})
+
+	t.Run("nil project is rejected", func(t *testing.T) {
+		err := repo.AddTagToLinks(context.Background(), uuid.Nil, []string{"one"}, "spring-sale")
+		assert.ErrorIs(t, err, ErrMissingProjectID)
+		_, err = repo.ListLinksByTag(context.Background(), uuid.Nil, "spring-sale", 10, 0)
+		assert.ErrorIs(t, err, ErrMissingProjectID)
+	})
+
+	t.Run("reads the tags of a link", func(t *testing.T) {
+		require.NoError(t, repo.AddTagToLinks(context.Background(), projectID, []string{"two"}, "autumn"))
+
+		tags, err := repo.GetLinkTags(context.Background(), "example.com", "two", &projectID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"autumn", "spring-sale"}, tags)
+
+		tags, err = repo.GetLinkTags(context.Background(), "example.com", "three", &projectID)
+		require.NoError(t, err)
+		assert.Empty(t, tags)
+
+		_, err = repo.GetLinkTags(context.Background(), "example.com", "missing", &projectID)
+		assert.ErrorIs(t, err, ErrLinkNotFound)
+	})
+
+	t.Run("lists links by tag", func(t *testing.T) {
+		require.NoError(t, repo.AddTagToLinks(context.Background(), projectID, []string{"one"}, "spring-sale"))
+		require.NoError(t, repo.AddTagToLinks(context.Background(), uuid.MustParse(otherProjectStr), []string{"foreign"}, "spring-sale"))
+
+		links, err := repo.ListLinksByTag(context.Background(), projectID, "spring-sale", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, links, 2)
+		assert.Equal(t, "one", links[0].Path)
+		assert.Equal(t, "two", links[1].Path)
+
+		links, err = repo.ListLinksByTag(context.Background(), projectID, "spring-sale", 1, 1)
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		assert.Equal(t, "two", links[0].Path)
+
+		links, err = repo.ListLinksByTag(context.Background(), projectID, "unused", 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, links)
+
+		_, err = repo.ListLinksByTag(context.Background(), projectID, "Spring", 10, 0)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+	})
+
+	t.Run("deleting a link deletes its tags", func(t *testing.T) {
+		require.NoError(t, repo.DeleteLink(context.Background(), "example.com", "two", &projectID, false))
+
+		assert.Equal(t, []int64{ids["one"], ids["foreign"]}, taggedLinks("spring-sale"))
+		assert.Empty(t, taggedLinks("autumn"))
+	})
+
+	t.Run("deleting a project's links deletes their tags", func(t *testing.T) {
+		deleted, err := repo.DeleteLinksByProject(context.Background(), projectID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+
+		// Only the tag of the other project's link is left
+		assert.Equal(t, []int64{ids["foreign"]}, taggedLinks("spring-sale"))
+		assert.Empty(t, taggedLinks("summer"))
+	})
+}
+
+func TestGetRawRequest(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	db.Create(&models.DurableLinkDB{
+		Host:       "example.com",
+		Path:       "audited",
+		Link:       "https://example.com/target",
+		RawRequest: stringPtr(`{"durableLinkInfo":{"host":"example.com"}}`),
+	})
+	db.Create(&models.DurableLinkDB{
+		Host: "example.com",
+		Path: "plain",
+		Link: "https://example.com/target",
+	})
+
+	raw, err := repo.GetRawRequest(context.Background(), "example.com", "audited", nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"durableLinkInfo":{"host":"example.com"}}`, raw)
+
+	raw, err = repo.GetRawRequest(context.Background(), "example.com", "plain", nil)
+	require.NoError(t, err)
+	assert.Empty(t, raw)
+
+	_, err = repo.GetRawRequest(context.Background(), "example.com", "missing", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+}
+
+func TestGetRawRequest_ScopedToProject(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectA := uuid.New()
+	projectB := uuid.New()
+	projectAStr := projectA.String()
+	projectBStr := projectB.String()
+	db.Create(&models.DurableLinkDB{
+		Host:       "example.com",
+		Path:       "shared",
+		Link:       "https://example.com/a",
+		ProjectID:  &projectAStr,
+		PathScope:  projectAStr,
+		RawRequest: stringPtr(`{"project":"a"}`),
+	})
+	db.Create(&models.DurableLinkDB{
+		Host:       "example.com",
+		Path:       "shared",
+		Link:       "https://example.com/b",
+		ProjectID:  &projectBStr,
+		PathScope:  projectBStr,
+		RawRequest: stringPtr(`{"project":"b"}`),
+	})
+
+	raw, err := repo.GetRawRequest(context.Background(), "example.com", "shared", &projectB)
+	require.NoError(t, err)
+	assert.Equal(t, `{"project":"b"}`, raw)
+
+	raw, err = repo.GetRawRequest(context.Background(), "example.com", "shared", &projectA)
+	require.NoError(t, err)
+	assert.Equal(t, `{"project":"a"}`, raw)
+
+	_, err = repo.GetRawRequest(context.Background(), "example.com", "shared", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+}
+
+func TestUpdateLinkTarget(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	link := models.FromDurableLink(models.DurableLink{
+		Link: "https://example.com/wrong",
+		AndroidParameters: models.AndroidParameters{
+			AndroidPackageName: stringPtr("com.example.app"),
+		},
+	}, "example.com", "abc123", false, nil)
+	require.NoError(t, db.Create(link).Error)
+
+	var before models.DurableLinkDB
+	db.Where("path = ?", "abc123").First(&before)
+
+	time.Sleep(10 * time.Millisecond)
+	err := repo.UpdateLinkTarget(context.Background(), "example.com", "abc123", "https://example.com/right", nil)
+	require.NoError(t, err)
+
+	var after models.DurableLinkDB
+	db.Where("path = ?", "abc123").First(&after)
+	assert.Equal(t, "https://example.com/right", after.Link)
+	assert.Equal(t, before.ParamsHash, after.ParamsHash)
+	assert.Equal(t, before.ParamsHash, after.ComputeParamsHash(), "hash must not depend on the link")
+	assert.Equal(t, stringPtr("com.example.app"), after.AndroidPackageName)
+	assert.True(t, after.UpdatedAt.After(before.UpdatedAt))
+
+	// Dedup still finds the link under its new target
+	path, err := repo.FindExistingShortLink(context.Background(), "example.com", &models.DurableLink{
+		Link: "https://example.com/right",
+		AndroidParameters: models.AndroidParameters{
+			AndroidPackageName: stringPtr("com.example.app"),
+		},
+	}, nil, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", path)
+}
+
+func TestUpdateLinkTarget_NotFound(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectIDStr := uuid.New().String()
+	db.Create(&models.DurableLinkDB{
+		Host:      "example.com",
+		Path:      "owned",
+		Link:      "https://example.com/target",
+		ProjectID: &projectIDStr,
+	})
+
+	err := repo.UpdateLinkTarget(context.Background(), "example.com", "missing", "https://example.com/new", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+
+	// A project-owned link can't be updated without its project
+	err = repo.UpdateLinkTarget(context.Background(), "example.com", "owned", "https://example.com/new", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+
+	otherProject := uuid.New()
+	err = repo.UpdateLinkTarget(context.Background(), "example.com", "owned", "https://example.com/new", &otherProject)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+}
+
+func TestListDistinctHosts(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	rows := []struct {
+		host      string
+		path      string
+		projectID *string
+	}{
+		{"b.example.com", "one", &projectIDStr},
+		{"a.example.com", "two", &projectIDStr},
+		{"a.example.com", "three", &projectIDStr},
+		{"c.example.com", "four", nil},
+	}
+	for _, row := range rows {
+		db.Create(&models.DurableLinkDB{
+			Host:      row.host,
+			Path:      row.path,
+			Link:      "https://example.com/target",
+			ProjectID: row.projectID,
+		})
+	}
+
+	hosts, err := repo.ListDistinctHosts(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.example.com", "b.example.com", "c.example.com"}, hosts)
+
+	hosts, err = repo.ListDistinctHosts(context.Background(), &projectID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, hosts)
+
+	otherProject := uuid.New()
+	hosts, err = repo.ListDistinctHosts(context.Background(), &otherProject)
+	require.NoError(t, err)
+	assert.Empty(t, hosts)
+}
+
+func setupMockDB(t *testing.T, sqliteVersion string, opts ...Option) (sqlmock.Sqlmock, LinkRepository) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	mock.ExpectQuery("select sqlite_version()").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(sqliteVersion))
+
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	require.NoError(t, err)
+
+	return mock, NewLinkRepository(db, opts...)
+}
+
+func TestResolveAndIncrementClicks(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	db.Create(&models.DurableLinkDB{
+		Host: "example.com",
+		Path: "abc123",
+		Link: "https://example.com/target",
+	})
+
+	for i := int64(1); i <= 3; i++ {
+		result, err := repo.ResolveAndIncrementClicks(context.Background(), "example.com", "abc123", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", result.Link)
+		assert.Equal(t, i, result.ClickCount)
+	}
+
+	_, err := repo.ResolveAndIncrementClicks(context.Background(), "example.com", "missing", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+}
+
+func TestResolveAndIncrementClicks_SingleStatement(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.46.0")
+
+	// GORM wraps writes in its default transaction, the update itself is one statement
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE `apppanel_durable_links` SET `click_count`=click_count + ? WHERE (host = ? AND path = ?) AND project_id IS NULL AND (max_clicks IS NULL OR click_count < max_clicks) RETURNING *")).
+		WithArgs(1, "example.com", "abc123").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "host", "path", "link", "click_count"}).
+			AddRow(1, "example.com", "abc123", "https://example.com/target", 5))
+	mock.ExpectCommit()
+	// The daily click count is a separate write after the resolve
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `apppanel_daily_clicks`")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := repo.ResolveAndIncrementClicks(context.Background(), "example.com", "abc123", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/target", result.Link)
+	assert.Equal(t, int64(5), result.ClickCount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveAndIncrementClicks_WithoutReturning(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.30.0")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `apppanel_durable_links` SET `click_count`=click_count + ? WHERE (host = ? AND path = ?) AND project_id IS NULL AND (max_clicks IS NULL OR click_count < max_clicks)")).
+		WithArgs(1, "example.com", "abc123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `apppanel_durable_links` WHERE (host = ? AND path = ?) AND project_id IS NULL")).
+		WithArgs("example.com", "abc123").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "host", "path", "link", "click_count"}).
+			AddRow(1, "example.com", "abc123", "https://example.com/target", 2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `apppanel_daily_clicks`")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := repo.ResolveAndIncrementClicks(context.Background(), "example.com", "abc123", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.ClickCount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveAndIncrementClicks_WithoutReturningNotFound(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.30.0")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `apppanel_durable_links` SET `click_count`=click_count + ?")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `apppanel_durable_links` WHERE (host = ? AND path = ?) AND project_id IS NULL")).
+		WithArgs("example.com", "missing").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectRollback()
+
+	_, err := repo.ResolveAndIncrementClicks(context.Background(), "example.com", "missing", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetLinkDBByHostAndPath(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	db.Create(&models.DurableLinkDB{
+		Host:         "example.com",
+		Path:         "protected",
+		Link:         "https://example.com/target",
+		PasswordHash: stringPtr("$2a$10$hash"),
+		ClickCount:   4,
+	})
+
+	result, err := repo.GetLinkDBByHostAndPath(context.Background(), "example.com", "protected", nil)
+	require.NoError(t, err)
+	assert.Equal(t, stringPtr("$2a$10$hash"), result.PasswordHash)
+	assert.Equal(t, int64(4), result.ClickCount)
+
+	// Protected links are never reused for dedup
+	_, err = repo.FindExistingShortLink(context.Background(), "example.com", &models.DurableLink{Link: "https://example.com/target"}, nil, time.Time{})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = repo.GetLinkDBByHostAndPath(context.Background(), "example.com", "missing", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+}
+
+func TestListDuplicateGroups(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	rows := []struct {
+		path      string
+		link      string
+		projectID *string
+	}{
+		{"dup-b", "https://example.com/target", &projectIDStr},
+		{"dup-a", "https://example.com/target", &projectIDStr},
+		{"unique", "https://example.com/other", &projectIDStr},
+		{"elsewhere", "https://example.com/target", nil},
+	}
+	for _, row := range rows {
+		require.NoError(t, db.Create(&models.DurableLinkDB{
+			Host:              "example.com",
+			Path:              row.path,
+			Link:              row.link,
+			IsUnguessablePath: true,
+			ProjectID:         row.projectID,
+		}).Error)
+	}
+
+	groups, err := repo.ListDuplicateGroups(context.Background(), &projectID)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "example.com", groups[0].Host)
+	assert.Equal(t, "https://example.com/target", groups[0].Link)
+	assert.NotEmpty(t, groups[0].ParamsHash)
+	assert.Equal(t, []string{"dup-a", "dup-b"}, groups[0].Paths)
+
+	// Links without a project have no duplicates of their own
+	groups, err = repo.ListDuplicateGroups(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestDeleteLinksByProject(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	otherIDStr := uuid.New().String()
+	for i, id := range []*string{&projectIDStr, &projectIDStr, &otherIDStr, nil} {
+		require.NoError(t, db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      fmt.Sprintf("path%d", i),
+			Link:      "https://example.com/target",
+			ProjectID: id,
+		}).Error)
+	}
+
+	deleted, err := repo.DeleteLinksByProject(context.Background(), projectID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	var remaining int64
+	db.Model(&models.DurableLinkDB{}).Count(&remaining)
+	assert.Equal(t, int64(2), remaining)
+
+	deleted, err = repo.DeleteLinksByProject(context.Background(), projectID)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestDeleteLinksByProject_RejectsNilProject(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	require.NoError(t, db.Create(&models.DurableLinkDB{
+		Host: "example.com",
+		Path: "abc123",
+		Link: "https://example.com/target",
+	}).Error)
+
+	deleted, err := repo.DeleteLinksByProject(context.Background(), uuid.Nil)
+	assert.ErrorIs(t, err, ErrMissingProjectID)
+	assert.Zero(t, deleted)
+
+	var remaining int64
+	db.Model(&models.DurableLinkDB{}).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+}
+
+func TestResolveAndIncrementClicks_MaxClicks(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	maxClicks := int64(2)
+	require.NoError(t, db.Create(&models.DurableLinkDB{
+		Host:      "example.com",
+		Path:      "giveaway",
+		Link:      "https://example.com/target",
+		MaxClicks: &maxClicks,
+	}).Error)
+
+	result, err := repo.ResolveAndIncrementClicks(context.Background(), "example.com", "giveaway", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.ClickCount)
+
+	// Last allowed click
+	result, err = repo.ResolveAndIncrementClicks(context.Background(), "example.com", "giveaway", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.ClickCount)
+
+	// First denied click leaves the count untouched
+	_, err = repo.ResolveAndIncrementClicks(context.Background(), "example.com", "giveaway", nil)
+	assert.ErrorIs(t, err, ErrClickLimitReached)
+
+	stored, err := repo.GetLinkDBByHostAndPath(context.Background(), "example.com", "giveaway", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stored.ClickCount)
+}
+
+func TestResolveAndIncrementClicks_MaxClicksWithoutReturning(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.30.0")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `apppanel_durable_links` SET `click_count`=click_count + ? WHERE (host = ? AND path = ?) AND project_id IS NULL AND (max_clicks IS NULL OR click_count < max_clicks)")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `apppanel_durable_links` WHERE (host = ? AND path = ?) AND project_id IS NULL")).
+		WithArgs("example.com", "giveaway").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	_, err := repo.ResolveAndIncrementClicks(context.Background(), "example.com", "giveaway", nil)
+	assert.ErrorIs(t, err, ErrClickLimitReached)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestParamsHashAlgorithm_FindsDuplicates(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.DurableLinkDB{}))
+
+	repo := NewLinkRepository(db, WithParamsHashAlgorithm(models.ParamsHashFNV64))
+	link := &models.DurableLink{Link: "https://example.com/target", SocialMetaTagInfo: models.SocialMetaTagInfo{SocialTitle: stringPtr("Title")}}
+	require.NoError(t, repo.CreateShortLink(context.Background(), models.FromDurableLink(*link, "example.com", "abc123", false, nil), nil))
+
+	var stored models.DurableLinkDB
+	require.NoError(t, db.First(&stored).Error)
+	assert.True(t, strings.HasPrefix(stored.ParamsHash, "f64:"))
+
+	path, err := repo.FindExistingShortLink(context.Background(), "example.com", link, nil, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", path)
+
+	// Hashes of another algorithm never match
+	_, err = NewLinkRepository(db).FindExistingShortLink(context.Background(), "example.com", link, nil, time.Time{})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestGetLinkByHostAndPath_ProjectScope(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	require.NoError(t, db.Create(&models.DurableLinkDB{
+		Host:      "example.com",
+		Path:      "owned",
+		Link:      "https://example.com/project",
+		ProjectID: &projectIDStr,
+	}).Error)
+	require.NoError(t, db.Create(&models.DurableLinkDB{
+		Host: "example.com",
+		Path: "global",
+		Link: "https://example.com/global",
+	}).Error)
+
+	// A project-owned row is not visible to a lookup without a project
+	_, err := repo.GetLinkByHostAndPath(context.Background(), "example.com", "owned", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+
+	link, err := repo.GetLinkByHostAndPath(context.Background(), "example.com", "owned", &projectID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/project", link.Link)
+
+	// Nor is a global row visible to a project lookup
+	_, err = repo.GetLinkByHostAndPath(context.Background(), "example.com", "global", &projectID)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+
+	link, err = repo.GetLinkByHostAndPath(context.Background(), "example.com", "global", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/global", link.Link)
+}
+
+func TestDeleteLink_ReleasesPath(t *testing.T) {
+	db, repo := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ReleasedPathDB{}))
+
+	db.Create(&models.DurableLinkDB{Host: "example.com", Path: "kept", Link: "https://example.com/kept"})
+	db.Create(&models.DurableLinkDB{Host: "example.com", Path: "freed", Link: "https://example.com/freed"})
+
+	require.NoError(t, repo.DeleteLink(context.Background(), "example.com", "kept", nil, false))
+	require.NoError(t, repo.DeleteLink(context.Background(), "example.com", "freed", nil, true))
+
+	err := repo.DeleteLink(context.Background(), "example.com", "freed", nil, true)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+
+	var remaining int64
+	db.Model(&models.DurableLinkDB{}).Count(&remaining)
+	assert.Zero(t, remaining)
+
+	_, err = repo.PopReleasedPath(context.Background(), "example.com", 4, AnyPathCase)
+	assert.ErrorIs(t, err, ErrNoReleasedPath, "paths are only re-used for the same length")
+
+	path, err := repo.PopReleasedPath(context.Background(), "example.com", 5, AnyPathCase)
+	require.NoError(t, err)
+	assert.Equal(t, "freed", path)
+
+	_, err = repo.PopReleasedPath(context.Background(), "example.com", 5, AnyPathCase)
+	assert.ErrorIs(t, err, ErrNoReleasedPath, "a popped path leaves the pool")
+}
+
+func TestDeleteLink_RecordsDeletedPath(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	db.Create(&models.DurableLinkDB{Host: "example.com", Path: "gone", Link: "https://example.com/target", ProjectID: &projectIDStr})
+
+	deleted, err := repo.IsPathDeleted(context.Background(), "example.com", "gone", &projectID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	require.NoError(t, repo.DeleteLink(context.Background(), "example.com", "gone", &projectID, false))
+
+	deleted, err = repo.IsPathDeleted(context.Background(), "example.com", "gone", &projectID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = repo.IsPathDeleted(context.Background(), "example.com", "gone", nil)
+	require.NoError(t, err)
+	assert.False(t, deleted, "other projects never had a link at the path")
+}
+
+func TestPopReleasedPath_PathCase(t *testing.T) {
+	db, repo := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ReleasedPathDB{}))
+
+	for _, path := range []string{"AbCd", "ABCD", "ab12"} {
+		require.NoError(t, db.Create(&models.ReleasedPathDB{Host: "example.com", Path: path, Length: 4}).Error)
+	}
+
+	path, err := repo.PopReleasedPath(context.Background(), "example.com", 4, LowerPathCase)
+	require.NoError(t, err)
+	assert.Equal(t, "ab12", path)
+
+	path, err = repo.PopReleasedPath(context.Background(), "example.com", 4, UpperPathCase)
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD", path)
+
+	_, err = repo.PopReleasedPath(context.Background(), "example.com", 4, LowerPathCase)
+	assert.ErrorIs(t, err, ErrNoReleasedPath)
+
+	// Paths of another case were left for tenants that can use them
+	path, err = repo.PopReleasedPath(context.Background(), "example.com", 4, AnyPathCase)
+	require.NoError(t, err)
+	assert.Equal(t, "AbCd", path)
+}
+
+func TestPopReleasedPath_LostRace(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.45.0")
+
+	selectQuery := regexp.QuoteMeta("SELECT * FROM `apppanel_released_paths` WHERE host = ? AND length = ? ORDER BY id ASC")
+	deleteQuery := regexp.QuoteMeta("DELETE FROM `apppanel_released_paths` WHERE id = ?")
+
+	// A concurrent caller deletes the first path before us
+	mock.ExpectQuery(selectQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "host", "path", "length"}).AddRow(1, "example.com", "abc123", 6))
+	mock.ExpectBegin()
+	mock.ExpectExec(deleteQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery(selectQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "host", "path", "length"}).AddRow(2, "example.com", "def456", 6))
+	mock.ExpectBegin()
+	mock.ExpectExec(deleteQuery).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	path, err := repo.PopReleasedPath(context.Background(), "example.com", 6, AnyPathCase)
+	require.NoError(t, err)
+	assert.Equal(t, "def456", path)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetClickTimeSeries(t *testing.T) {
+	db, repo := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.DailyClicksDB{}))
+
+	link := &models.DurableLinkDB{Host: "example.com", Path: "abc123", Link: "https://example.com/target"}
+	require.NoError(t, db.Create(link).Error)
+
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	// Thursday March 26 to Tuesday April 7
+	for _, daily := range []models.DailyClicksDB{
+		{LinkID: link.ID, Day: day(time.March, 25), Clicks: 100}, // before the range
+		{LinkID: link.ID, Day: day(time.March, 26), Clicks: 1},
+		{LinkID: link.ID, Day: day(time.March, 29), Clicks: 2},
+		{LinkID: link.ID, Day: day(time.March, 30), Clicks: 3},
+		{LinkID: link.ID, Day: day(time.April, 2), Clicks: 4},
+		{LinkID: link.ID, Day: day(time.April, 7), Clicks: 5},
+		{LinkID: link.ID + 1, Day: day(time.March, 30), Clicks: 100}, // another link
+	} {
+		require.NoError(t, db.Create(&daily).Error)
+	}
+	from := day(time.March, 26).Add(15 * time.Hour)
+	to := day(time.April, 7).Add(9 * time.Hour)
+
+	t.Run("daily", func(t *testing.T) {
+		buckets, err := repo.GetClickTimeSeries(context.Background(), "example.com", "abc123", nil, from, to, GranularityDay)
+		require.NoError(t, err)
+		require.Len(t, buckets, 13)
+		assert.Equal(t, TimeBucket{Start: day(time.March, 26), Clicks: 1}, buckets[0])
+		assert.Equal(t, TimeBucket{Start: day(time.March, 27), Clicks: 0}, buckets[1])
+		assert.Equal(t, TimeBucket{Start: day(time.March, 30), Clicks: 3}, buckets[4])
+		assert.Equal(t, TimeBucket{Start: day(time.April, 7), Clicks: 5}, buckets[12])
+	})
+
+	t.Run("weekly", func(t *testing.T) {
+		buckets, err := repo.GetClickTimeSeries(context.Background(), "example.com", "abc123", nil, from, to, GranularityWeek)
+		require.NoError(t, err)
+		assert.Equal(t, []TimeBucket{
+			{Start: day(time.March, 23), Clicks: 3},
+			{Start: day(time.March, 30), Clicks: 7},
+			{Start: day(time.April, 6), Clicks: 5},
+		}, buckets)
+	})
+
+	t.Run("monthly", func(t *testing.T) {
+		buckets, err := repo.GetClickTimeSeries(context.Background(), "example.com", "abc123", nil, from, to, GranularityMonth)
+		require.NoError(t, err)
+		assert.Equal(t, []TimeBucket{
+			{Start: day(time.March, 1), Clicks: 6},
+			{Start: day(time.April, 1), Clicks: 9},
+		}, buckets)
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		_, err := repo.GetClickTimeSeries(context.Background(), "example.com", "abc123", nil, to, from, GranularityDay)
+		assert.ErrorIs(t, err, ErrInvalidDateRange)
+	})
+
+	t.Run("unknown link", func(t *testing.T) {
+		_, err := repo.GetClickTimeSeries(context.Background(), "example.com", "missing", nil, from, to, GranularityDay)
+		assert.ErrorIs(t, err, ErrLinkNotFound)
+	})
+}
+
+func TestResolveAndIncrementClicks_RecordsDailyClicks(t *testing.T) {
+	db, repo := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.DailyClicksDB{}))
+
+	require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "abc123", Link: "https://example.com/target"}).Error)
+	for range 3 {
+		_, err := repo.ResolveAndIncrementClicks(context.Background(), "example.com", "abc123", nil)
+		require.NoError(t, err)
+	}
+
+	now := time.Now()
+	buckets, err := repo.GetClickTimeSeries(context.Background(), "example.com", "abc123", nil, now, now, GranularityDay)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	assert.Equal(t, int64(3), buckets[0].Clicks)
+}
+
+func TestRecomputeAllParamHashes(t *testing.T) {
+	db, repo := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := range 5 {
+		dl := models.DurableLink{Link: fmt.Sprintf("https://example.com/target/%d", i)}
+		require.NoError(t, repo.CreateShortLink(ctx, models.FromDurableLink(dl, "example.com", fmt.Sprintf("path%d", i), false, nil), nil))
+	}
+	// Simulate rows written before a hash change
+	require.NoError(t, db.Model(&models.DurableLinkDB{}).Where("path IN ?", []string{"path1", "path3", "path4"}).UpdateColumn("params_hash", "stale").Error)
+
+	updated, err := repo.RecomputeAllParamHashes(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), updated)
+
+	var links []models.DurableLinkDB
+	require.NoError(t, db.Find(&links).Error)
+	for _, link := range links {
+		assert.Equal(t, link.ComputeParamsHash(), link.ParamsHash, link.Path)
+	}
+
+	updated, err = repo.RecomputeAllParamHashes(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), updated, "a second run has nothing left to update")
+
+	// Switching algorithms rehashes every row
+	updated, err = NewLinkRepository(db, WithParamsHashAlgorithm(models.ParamsHashFNV64)).RecomputeAllParamHashes(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), updated)
+
+	_, err = repo.RecomputeAllParamHashes(ctx, 0)
+	assert.ErrorIs(t, err, ErrInvalidBatchSize)
+}
+
+func TestRecomputeAllParamHashes_KeepsDedupIgnoreUTM(t *testing.T) {
+	db, repo := setupTestDB(t)
+	ctx := context.Background()
+
+	dl := models.DurableLink{
+		Link: "https://example.com/target",
+		AnalyticsInfo: models.AnalyticsInfo{
+			MarketingParameters: models.MarketingParameters{UtmSource: stringPtr("newsletter")},
+		},
+	}
+	link := models.FromDurableLink(dl, "example.com", "abc123", false, nil)
+	link.DedupIgnoreUTM = true
+	require.NoError(t, repo.CreateShortLink(ctx, link, nil))
+
+	updated, err := repo.RecomputeAllParamHashes(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, updated, "the hash of a link created ignoring UTM params must not change")
+
+	// Creates of the tenant look links up with their UTM params stripped, which still finds it
+	withoutUTM := dl
+	withoutUTM.AnalyticsInfo.MarketingParameters = models.MarketingParameters{}
+	path, err := repo.FindExistingShortLink(ctx, "example.com", &withoutUTM, nil, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", path)
+
+	var stored models.DurableLinkDB
+	require.NoError(t, db.First(&stored).Error)
+	assert.True(t, stored.DedupIgnoreUTM)
+}
+
+func TestReadReplica(t *testing.T) {
+	primary, _ := setupTestDB(t)
+	replica, _ := setupTestDB(t)
+	repo := NewLinkRepositoryWithReplica(primary, replica)
+	ctx := context.Background()
+
+	// Only the replica has this link, so finding it proves reads go there
+	dl := models.DurableLink{Link: "https://example.com/replicated"}
+	require.NoError(t, replica.Create(models.FromDurableLink(dl, "example.com", "replicated", false, nil)).Error)
+
+	got, err := repo.GetLinkByHostAndPath(ctx, "example.com", "replicated", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/replicated", got.Link)
+
+	links, err := repo.ListLinks(ctx, nil, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+
+	hosts, err := repo.ListDistinctHosts(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com"}, hosts)
+
+	// Writes and path availability go to the primary
+	created := models.FromDurableLink(models.DurableLink{Link: "https://example.com/new"}, "example.com", "new", false, nil)
+	require.NoError(t, repo.CreateShortLink(ctx, created, nil))
+
+	var count int64
+	require.NoError(t, primary.Model(&models.DurableLinkDB{}).Where("path = ?", "new").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, replica.Model(&models.DurableLinkDB{}).Where("path = ?", "new").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	available, err := repo.IsPathAvailable(ctx, "example.com", "new")
+	require.NoError(t, err)
+	assert.False(t, available)
+
+	// Checks made before an insert must see it too, not the lagging replica
+	newLink := models.DurableLink{Link: "https://example.com/new"}
+	path, err := repo.FindExistingShortLink(ctx, "example.com", &newLink, nil, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "new", path)
+	_, err = repo.FindExistingShortLink(ctx, "example.com", &dl, nil, time.Time{})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = repo.GetLinkByHostAndPath(ctx, "example.com", "new", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+	got, err = repo.GetLinkByHostAndPath(WithPrimaryReads(ctx), "example.com", "new", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", got.Link)
+}
+
+func TestReadReplica_NilUsesPrimary(t *testing.T) {
+	primary, _ := setupTestDB(t)
+	repo := NewLinkRepositoryWithReplica(primary, nil)
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateShortLink(ctx, models.FromDurableLink(models.DurableLink{Link: "https://example.com/target"}, "example.com", "abc123", false, nil), nil))
+
+	got, err := repo.GetLinkByHostAndPath(ctx, "example.com", "abc123", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/target", got.Link)
+}
+
+func TestUpsertByIdempotencyKey(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	newLink := func(path, key string) *models.DurableLinkDB {
+		return &models.DurableLinkDB{
+			Host:              "example.com",
+			Path:              path,
+			Link:              "https://example.com/target",
+			IsUnguessablePath: true,
+			IdempotencyKey:    stringPtr(key),
+		}
+	}
+
+	path, err := repo.UpsertByIdempotencyKey(context.Background(), newLink("original", "retry-1"), projectID)
+	require.NoError(t, err)
+	assert.Equal(t, "original", path)
+
+	t.Run("retry with the same key returns the original path", func(t *testing.T) {
+		path, err := repo.UpsertByIdempotencyKey(context.Background(), newLink("retried", "retry-1"), projectID)
+		require.NoError(t, err)
+		assert.Equal(t, "original", path)
+
+		var count int64
+		db.Model(&models.DurableLinkDB{}).Where("idempotency_key = ?", "retry-1").Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("same key in another project creates a link", func(t *testing.T) {
+		path, err := repo.UpsertByIdempotencyKey(context.Background(), newLink("other-project", "retry-1"), uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, "other-project", path)
+	})
+
+	t.Run("missing key is rejected", func(t *testing.T) {
+		link := newLink("no-key", "")
+		_, err := repo.UpsertByIdempotencyKey(context.Background(), link, projectID)
+		assert.ErrorIs(t, err, ErrMissingIdempotencyKey)
+
+		link.IdempotencyKey = nil
+		_, err = repo.UpsertByIdempotencyKey(context.Background(), link, projectID)
+		assert.ErrorIs(t, err, ErrMissingIdempotencyKey)
+	})
+
+	t.Run("nil project is rejected", func(t *testing.T) {
+		_, err := repo.UpsertByIdempotencyKey(context.Background(), newLink("no-project", "retry-2"), uuid.Nil)
+		assert.ErrorIs(t, err, ErrMissingProjectID)
+	})
+}
+
+func TestCreateLinkIfPathFree(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	newLink := func(target string) *models.DurableLinkDB {
+		return &models.DurableLinkDB{
+			Host: "example.com",
+			Path: "spring-sale",
+			Link: target,
+		}
+	}
+
+	require.NoError(t, repo.CreateLinkIfPathFree(context.Background(), newLink("https://example.com/first"), nil))
+
+	err := repo.CreateLinkIfPathFree(context.Background(), newLink("https://example.com/second"), nil)
+	assert.ErrorIs(t, err, ErrPathTaken)
+
+	var stored []models.DurableLinkDB
+	require.NoError(t, db.Find(&stored).Error)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "https://example.com/first", stored[0].Link, "the first create keeps the path")
+
+	// Per-project paths live in their own scope
+	scoped := newLink("https://example.com/scoped")
+	scoped.PathScope = uuid.NewString()
+	require.NoError(t, repo.CreateLinkIfPathFree(context.Background(), scoped, nil))
+}
+
+func TestCreateShortLink_NoRowsInserted(t *testing.T) {
+	insert := regexp.QuoteMeta("INSERT INTO `apppanel_durable_links`")
+
+	t.Run("with RETURNING", func(t *testing.T) {
+		mock, repo := setupMockDB(t, "3.46.0")
+		mock.ExpectBegin()
+		mock.ExpectQuery(insert).WillReturnRows(sqlmock.NewRows([]string{"id", "click_count"}))
+		mock.ExpectCommit()
+
+		link := &models.DurableLinkDB{Host: "example.com", Path: "abc123", Link: "https://example.com/target"}
+		err := repo.CreateShortLink(context.Background(), link, nil)
+		assert.ErrorIs(t, err, ErrLinkNotInserted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("without RETURNING", func(t *testing.T) {
+		mock, repo := setupMockDB(t, "3.30.0")
+		mock.ExpectBegin()
+		mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		link := &models.DurableLinkDB{Host: "example.com", Path: "abc123", Link: "https://example.com/target"}
+		err := repo.CreateShortLink(context.Background(), link, nil)
+		assert.ErrorIs(t, err, ErrLinkNotInserted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetCampaignStats(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	rows := []struct {
+		path       string
+		campaignID *string
+		projectID  *string
+		clicks     int64
+	}{
+		{"spring-a", stringPtr("spring"), &projectIDStr, 3},
+		{"spring-b", stringPtr("spring"), &projectIDStr, 4},
+		{"spring-c", stringPtr("spring"), &projectIDStr, 0},
+		{"summer", stringPtr("summer"), &projectIDStr, 10},
+		{"no-campaign", nil, &projectIDStr, 5},
+		{"other-project", stringPtr("spring"), nil, 100},
+	}
+	for _, row := range rows {
+		require.NoError(t, db.Create(&models.DurableLinkDB{
+			Host:       "example.com",
+			Path:       row.path,
+			Link:       "https://example.com/target",
+			CampaignID: row.campaignID,
+			ProjectID:  row.projectID,
+			ClickCount: row.clicks,
+		}).Error)
+	}
+
+	stats, err := repo.GetCampaignStats(context.Background(), &projectID, "spring")
+	require.NoError(t, err)
+	assert.Equal(t, &CampaignStats{CampaignID: "spring", Links: 3, Clicks: 7}, stats)
+
+	stats, err = repo.GetCampaignStats(context.Background(), nil, "spring")
+	require.NoError(t, err)
+	assert.Equal(t, &CampaignStats{CampaignID: "spring", Links: 1, Clicks: 100}, stats)
+
+	stats, err = repo.GetCampaignStats(context.Background(), &projectID, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, &CampaignStats{CampaignID: "unknown"}, stats)
+}
+
+func TestTenantSettings(t *testing.T) {
+	db, repo := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.TenantSettingDB{}))
+	ctx := context.Background()
+
+	settings, err := repo.GetTenantSettings(ctx, "acme")
+	require.NoError(t, err)
+	assert.Empty(t, settings)
+
+	require.NoError(t, repo.SetTenantSetting(ctx, "acme", "short_path_length", "6"))
+	require.NoError(t, repo.SetTenantSetting(ctx, "acme", "strict_validation", "true"))
+	require.NoError(t, repo.SetTenantSetting(ctx, "acme", "short_path_length", "8"))
+	require.NoError(t, repo.SetTenantSetting(ctx, "other", "strict_validation", "false"))
+
+	settings, err = repo.GetTenantSettings(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"short_path_length": "8", "strict_validation": "true"}, settings)
+}
+
+// TestGetLinkDBByHostAndPath_RoundTripsAllColumns guards against drift between the model and
+// its columns: every stored field must come back as written.
+func TestGetLinkDBByHostAndPath_RoundTripsAllColumns(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	link := &models.DurableLinkDB{
+		Host:                 "example.com",
+		Path:                 "full",
+		Link:                 "https://example.com/target",
+		Name:                 stringPtr("Spring sale"),
+		Locale:               stringPtr("pt-BR"),
+		CampaignID:           stringPtr("spring"),
+		IsUnguessablePath:    true,
+		ProjectID:            &projectIDStr,
+		IdempotencyKey:       stringPtr("create-1"),
+		PathScope:            projectIDStr,
+		AndroidPackageName:   stringPtr("com.example.app"),
+		AndroidFallbackLink:  stringPtr("https://example.com/android"),
+		AndroidFallbackLinks: models.StringList{"https://example.com/android-2"},
+		AndroidMinVersion:    stringPtr("21"),
+		IOSFallbackLink:      stringPtr("https://example.com/ios"),
+		IOSFallbackLinks:     models.StringList{"https://example.com/ios-2"},
+		IOSIpadFallbackLink:  stringPtr("https://example.com/ipad"),
+		IOSAppStoreID:        int64Ptr(123456789),
+		SocialTitle:          stringPtr("Title"),
+		SocialDescription:    stringPtr("Description"),
+		SocialImageLink:      stringPtr("https://example.com/image.png"),
+		UtmSource:            stringPtr("newsletter"),
+		UtmMedium:            stringPtr("email"),
+		UtmCampaign:          stringPtr("spring"),
+		UtmTerm:              stringPtr("shoes"),
+		UtmContent:           stringPtr("banner"),
+		ItunesPt:             stringPtr("pt"),
+		ItunesAt:             stringPtr("at"),
+		ItunesCt:             stringPtr("ct"),
+		ItunesMt:             stringPtr("8"),
+		OtherFallbackURL:     stringPtr("https://example.com/other"),
+		Rules:                models.ResolutionRules{{Countries: []string{"US"}, Link: "https://example.com/us"}},
+		RedirectType:         stringPtr(models.RedirectTypePermanent),
+		InterstitialDelayMs:  intPtr(1500),
+		DedupIgnoreUTM:       true,
+		RawRequest:           stringPtr(`{"longDynamicLink":"x"}`),
+		ClickCount:           7,
+		MaxClicks:            int64Ptr(10),
+		PasswordHash:         stringPtr("$2a$10$hash"),
+		CreatedAt:            created,
+		UpdatedAt:            created,
+	}
+	require.NoError(t, db.Create(link).Error)
+
+	// A new column must be populated above, or it could silently stop being read back
+	v := reflect.ValueOf(*link)
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if field.Tag.Get("gorm") == "-" {
+			continue
+		}
+		assert.False(t, v.Field(i).IsZero(), "field %s is not covered by this test", field.Name)
+	}
+
+	got, err := repo.GetLinkDBByHostAndPath(context.Background(), "example.com", "full", &projectID)
+	require.NoError(t, err)
+
+	assert.True(t, link.CreatedAt.Equal(got.CreatedAt))
+	assert.False(t, got.UpdatedAt.IsZero())
+	got.CreatedAt, got.UpdatedAt = link.CreatedAt, link.UpdatedAt
+	assert.Equal(t, link, got)
+}
+
+func TestWithTableName(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Table("tenant_links").AutoMigrate(&models.DurableLinkDB{}))
+	require.NoError(t, db.AutoMigrate(&models.DailyClicksDB{}, &models.LinkTagDB{}, &models.DeletedPathDB{}))
+
+	repo := NewLinkRepository(db, WithTableName("tenant_links"))
+	ctx := context.Background()
+
+	for _, path := range []string{"dup-a", "dup-b"} {
+		require.NoError(t, repo.CreateShortLink(ctx, &models.DurableLinkDB{
+			Host:              "example.com",
+			Path:              path,
+			Link:              "https://example.com/target",
+			IsUnguessablePath: true,
+		}, nil))
+	}
+
+	var count int64
+	require.NoError(t, db.Table("tenant_links").Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+	assert.False(t, db.Migrator().HasTable(&models.DurableLinkDB{}), "the default table must not be created")
+
+	link, err := repo.GetLinkDBByHostAndPath(ctx, "example.com", "dup-a", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/target", link.Link)
+
+	clicked, err := repo.ResolveAndIncrementClicks(ctx, "example.com", "dup-a", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), clicked.ClickCount)
+
+	// The raw SQL of the duplicate report is built with the table name too
+	groups, err := repo.ListDuplicateGroups(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, []string{"dup-a", "dup-b"}, groups[0].Paths)
+
+	require.NoError(t, repo.DeleteLink(ctx, "example.com", "dup-b", nil, false))
+	links, err := repo.ListLinks(ctx, nil, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "dup-a", links[0].Path)
+}
+
+func TestWithTableName_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		valid bool
+	}{
+		{name: "plain", table: "durable_links", valid: true},
+		{name: "schema qualified", table: "tenant_a.durable_links", valid: true},
+		{name: "empty", table: ""},
+		{name: "leading digit", table: "1links"},
+		{name: "injection", table: "links; DROP TABLE links"},
+		{name: "quoted", table: `"links"`},
+		{name: "three parts", table: "db.schema.links"},
+		{name: "too long", table: strings.Repeat("a", 64)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.valid {
+				assert.NotPanics(t, func() { WithTableName(tt.table) })
+				return
+			}
+			assert.PanicsWithValue(t, fmt.Sprintf("%v: %q", ErrInvalidTableName, tt.table), func() { WithTableName(tt.table) })
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// RetryPolicy controls how queries failing with a transient error are retried.
+// A MaxAttempts of 1 or less disables retries. Every query of the repository is retried,
+// except that IterateLinks only retries opening its query: rows already handed to the
+// caller can't be taken back.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration // Delay before the first retry, doubled on every further attempt
+	MaxDelay    time.Duration // Upper bound for a single delay, zero means unbounded
+}
+
+// DefaultRetryPolicy is used by NewLinkRepository unless replaced with WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   25 * time.Millisecond,
+	MaxDelay:    500 * time.Millisecond,
+}
+
+// NoRetry disables retrying transient errors.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// unsentMessages are error fragments of drivers that don't expose typed errors for failing
+// before the statement reached the database.
+var unsentMessages = []string{
+	"connection refused",
+	"bad connection",
+}
+
+// conflictMessages are error fragments of lock contention and serialization failures, after
+// which the database has rolled the statement back.
+var conflictMessages = []string{
+	"sqlstate 40001", // serialization_failure
+	"sqlstate 40p01", // deadlock_detected
+	"could not serialize access",
+	"serialization failure",
+	"deadlock detected",
+	"database is locked",
+}
+
+// connectionLostMessages are error fragments of connections lost while a statement ran. The
+// statement may or may not have been applied, so only reads retry them.
+var connectionLostMessages = []string{
+	"connection reset",
+	"broken pipe",
+}
+
+// permanentMessages mark constraint violations, which retrying cannot fix.
+var permanentMessages = []string{
+	"constraint",
+	"duplicate key",
+	"sqlstate 23",
+}
+
+// isTransient reports whether a failed read is worth retrying.
+func isTransient(err error) bool {
+	return isRetryable(err, true)
+}
+
+// isRetryableWrite reports whether a failed write is safe to retry: it never reached the
+// database, or the database rolled it back on a serialization failure or deadlock. Writes that
+// lost their connection midway are not retried, since they may have been applied.
+func isRetryableWrite(err error) bool {
+	return isRetryable(err, false)
+}
+
+// isRetryable reports whether err is worth retrying, including errors of a lost connection
+// when connectionLost is set.
+func isRetryable(err error, connectionLost bool) bool {
+	if err == nil ||
+		errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, gorm.ErrRecordNotFound) ||
+		errors.Is(err, gorm.ErrDuplicatedKey) ||
+		errors.Is(err, ErrLinkNotFound) ||
+		errors.Is(err, ErrClickLimitReached) ||
+		errors.Is(err, ErrLinkNotInserted) ||
+		errors.Is(err, ErrPathTaken) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return connectionLost
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, m := range permanentMessages {
+		if strings.Contains(msg, m) {
+			return false
+		}
+	}
+	messages := append(slices.Clip(unsentMessages), conflictMessages...)
+	if connectionLost {
+		messages = append(messages, connectionLostMessages...)
+	}
+	for _, m := range messages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry runs the read fn, retrying it with exponential backoff while it fails with a
+// transient error.
+func (r *linkRepository) withRetry(ctx context.Context, fn func() error) error {
+	return r.retryWhile(ctx, isTransient, fn)
+}
+
+// withWriteRetry runs the write fn like withRetry, but only retries errors that leave the write
+// unapplied.
+func (r *linkRepository) withWriteRetry(ctx context.Context, fn func() error) error {
+	return r.retryWhile(ctx, isRetryableWrite, fn)
+}
+
+// retryWhile runs fn, retrying it with exponential backoff while retryable reports its error.
+func (r *linkRepository) retryWhile(ctx context.Context, retryable func(error) bool, fn func() error) error {
+	delay := r.retry.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if attempt >= r.retry.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		log.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("Transient database error, retrying")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if r.retry.MaxDelay > 0 && delay > r.retry.MaxDelay {
+			delay = r.retry.MaxDelay
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var fastRetry = WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+var errSerialization = errors.New("ERROR: could not serialize access due to concurrent update (SQLSTATE 40001)")
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      bool
+		wantWrite bool
+	}{
+		{name: "nil", err: nil},
+		{name: "no rows", err: sql.ErrNoRows},
+		{name: "record not found", err: gorm.ErrRecordNotFound},
+		{name: "link not found", err: ErrLinkNotFound},
+		{name: "context canceled", err: context.Canceled},
+		{name: "unique constraint", err: errors.New("UNIQUE constraint failed: apppanel_durable_links.host")},
+		{name: "duplicate key", err: errors.New("ERROR: duplicate key value violates unique constraint (SQLSTATE 23505)")},
+		{name: "bad connection", err: driver.ErrBadConn, want: true, wantWrite: true},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, want: true, wantWrite: true},
+		{name: "connection refused message", err: errors.New("dial tcp 10.0.0.5:5432: connect: connection refused"), want: true, wantWrite: true},
+		{name: "wrapped connection reset", err: fmt.Errorf("query failed: %w", syscall.ECONNRESET), want: true},
+		{name: "broken pipe", err: &net.OpError{Op: "write", Err: syscall.EPIPE}, want: true},
+		{name: "connection reset message", err: errors.New("read tcp 10.0.0.5:5432: connection reset by peer"), want: true},
+		{name: "serialization failure", err: errSerialization, want: true, wantWrite: true},
+		{name: "deadlock", err: errors.New("ERROR: deadlock detected (SQLSTATE 40P01)"), want: true, wantWrite: true},
+		{name: "database is locked", err: errors.New("database is locked"), want: true, wantWrite: true},
+		{name: "unknown error", err: errors.New("syntax error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isTransient(tt.err))
+			assert.Equal(t, tt.wantWrite, isRetryableWrite(tt.err))
+		})
+	}
+}
+
+func TestRetry_TransientErrorThenSuccess(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.46.0", fastRetry)
+
+	query := regexp.QuoteMeta("SELECT * FROM `apppanel_durable_links` WHERE (host = ? AND path = ?) AND project_id IS NULL")
+	mock.ExpectQuery(query).WillReturnError(errSerialization)
+	mock.ExpectQuery(query).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "host", "path", "link"}).
+			AddRow(1, "example.com", "abc123", "https://example.com/target"))
+
+	result, err := repo.GetLinkDBByHostAndPath(context.Background(), "example.com", "abc123", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/target", result.Link)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetry_CreateTransientErrorThenSuccess(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.46.0", fastRetry)
+
+	insert := regexp.QuoteMeta("INSERT INTO `apppanel_durable_links`")
+	mock.ExpectBegin()
+	mock.ExpectQuery(insert).WillReturnError(errSerialization)
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(insert).WillReturnRows(sqlmock.NewRows([]string{"id", "click_count"}).AddRow(7, 0))
+	mock.ExpectCommit()
+
+	link := &models.DurableLinkDB{Host: "example.com", Path: "abc123", Link: "https://example.com/target"}
+	require.NoError(t, repo.CreateShortLink(context.Background(), link, nil))
+	assert.Equal(t, int64(7), link.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetry_ListAndUpdateTransientErrorThenSuccess(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.46.0", fastRetry)
+
+	list := regexp.QuoteMeta("SELECT * FROM `apppanel_durable_links` WHERE project_id IS NULL ORDER BY id ASC")
+	mock.ExpectQuery(list).WillReturnError(errSerialization)
+	mock.ExpectQuery(list).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "host", "path", "link"}).
+			AddRow(1, "example.com", "abc123", "https://example.com/target"))
+
+	links, err := repo.ListLinks(context.Background(), nil, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+
+	update := regexp.QuoteMeta("UPDATE `apppanel_durable_links` SET")
+	mock.ExpectBegin()
+	mock.ExpectExec(update).WillReturnError(errSerialization)
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(update).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.UpdateLinkTarget(context.Background(), "example.com", "abc123", "https://example.com/new", nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetry_WriteConnectionLostIsNotRetried(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.46.0", fastRetry)
+
+	// The insert may have been applied before the connection dropped, so retrying could
+	// store the link twice
+	connReset := &net.OpError{Op: "read", Err: syscall.ECONNRESET}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO `apppanel_durable_links`")).WillReturnError(connReset)
+	mock.ExpectRollback()
+
+	link := &models.DurableLinkDB{Host: "example.com", Path: "abc123", Link: "https://example.com/target"}
+	err := repo.CreateShortLink(context.Background(), link, nil)
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.46.0", fastRetry)
+
+	query := regexp.QuoteMeta("SELECT count(*) FROM `apppanel_durable_links`")
+	for range 3 {
+		mock.ExpectQuery(query).WillReturnError(errSerialization)
+	}
+
+	_, err := repo.IsPathAvailable(context.Background(), "example.com", "abc123")
+	assert.ErrorIs(t, err, errSerialization)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetry_PermanentErrorsAreNotRetried(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.46.0", fastRetry)
+
+	constraintErr := errors.New("UNIQUE constraint failed: apppanel_durable_links.host, apppanel_durable_links.path")
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO `apppanel_durable_links`")).WillReturnError(constraintErr)
+	mock.ExpectRollback()
+
+	link := &models.DurableLinkDB{Host: "example.com", Path: "abc123", Link: "https://example.com/target"}
+	err := repo.CreateShortLink(context.Background(), link, nil)
+	assert.ErrorIs(t, err, constraintErr)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `apppanel_durable_links`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.GetLinkDBByHostAndPath(context.Background(), "example.com", "missing", nil)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetry_Disabled(t *testing.T) {
+	mock, repo := setupMockDB(t, "3.46.0", WithRetryPolicy(NoRetry))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `apppanel_durable_links`")).WillReturnError(errSerialization)
+
+	_, err := repo.GetLinkDBByHostAndPath(context.Background(), "example.com", "abc123", nil)
+	assert.ErrorIs(t, err, errSerialization)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
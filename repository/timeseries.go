@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+)
+
+// Granularity is the length of the periods a click time series is bucketed into.
+type Granularity int
+
+const (
+	GranularityDay Granularity = iota
+	// GranularityWeek buckets by ISO week, starting on Monday.
+	GranularityWeek
+	GranularityMonth
+)
+
+// TimeBucket is the number of clicks in the period starting at Start (UTC).
+type TimeBucket struct {
+	Start  time.Time
+	Clicks int64
+}
+
+// startOfDay returns UTC midnight of the day t falls on in UTC.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// periodStart returns the start of the granularity period day falls in.
+func (g Granularity) periodStart(day time.Time) time.Time {
+	day = startOfDay(day)
+	switch g {
+	case GranularityWeek:
+		// Weekday counts from Sunday, shift so Monday starts the week
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case GranularityMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// next returns the start of the period after the one starting at start.
+func (g Granularity) next(start time.Time) time.Time {
+	switch g {
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// bucketClicks sums days into one bucket per period from from to to.
+func bucketClicks(days []models.DailyClicksDB, from, to time.Time, granularity Granularity) []TimeBucket {
+	var buckets []TimeBucket
+	index := map[time.Time]int{}
+	last := granularity.periodStart(to)
+	for start := granularity.periodStart(from); !start.After(last); start = granularity.next(start) {
+		index[start] = len(buckets)
+		buckets = append(buckets, TimeBucket{Start: start})
+	}
+
+	for _, day := range days {
+		if i, ok := index[granularity.periodStart(day.Day)]; ok {
+			buckets[i].Clicks += day.Clicks
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/google/uuid"
+)
+
+// BatchCreateResult is the outcome of one item of a batch create. Exactly one of
+// Response and Err is set, Response carrying that item's own warnings.
+type BatchCreateResult struct {
+	Response *models.ShortLinkResponse
+	Err      error
+}
+
+// CreateDurableLinksBatch creates every item independently, so a failing item does not
+// affect the others. Results are returned in the order of items.
+func (s *linkService) CreateDurableLinksBatch(ctx context.Context, items []models.CreateDurableLinkRequest, projectID *uuid.UUID, tenantCfg TenantConfig) []BatchCreateResult {
+	results := make([]BatchCreateResult, len(items))
+	for i, item := range items {
+		resp, err := s.CreateDurableLink(ctx, item, projectID, tenantCfg)
+		results[i] = BatchCreateResult{Response: resp, Err: err}
+	}
+	return results
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDurableLinksBatch(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	items := []models.CreateDurableLinkRequest{
+		{
+			DurableLinkInfo: models.DurableLink{Host: "example.com", Link: "https://example.com/clean"},
+			Suffix:          models.Suffix{Option: "UNGUESSABLE"},
+		},
+		{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/warned",
+				OtherPlatformParameters: models.OtherPlatformParameters{
+					FallbackURL: stringPtr("not-a-url"),
+				},
+			},
+			Suffix: models.Suffix{Option: "UNGUESSABLE"},
+		},
+		{
+			DurableLinkInfo: models.DurableLink{Host: "example.com", Link: "https://notallowed.com/target"},
+			Suffix:          models.Suffix{Option: "UNGUESSABLE"},
+		},
+		{
+			DurableLinkInfo: models.DurableLink{Host: "example.com", Link: "https://example.com/suffix"},
+			Suffix:          models.Suffix{Option: "TINY"},
+		},
+	}
+
+	results := service.CreateDurableLinksBatch(context.Background(), items, nil, defaultTenantCfg)
+	require.Len(t, results, len(items))
+
+	require.NoError(t, results[0].Err)
+	assert.NotEmpty(t, results[0].Response.ShortLink)
+	assert.Empty(t, results[0].Response.Warnings)
+
+	require.NoError(t, results[1].Err)
+	require.Len(t, results[1].Response.Warnings, 1)
+	assert.Equal(t, "durableLinkInfo.otherPlatformParameters.fallbackUrl", results[1].Response.Warnings[0].Field)
+
+	assert.ErrorIs(t, results[2].Err, ErrDomainLinkNotAllowed)
+	assert.Nil(t, results[2].Response)
+
+	require.NoError(t, results[3].Err)
+	require.Len(t, results[3].Response.Warnings, 1)
+	assert.Equal(t, "INVALID_SUFFIX_OPTION", results[3].Response.Warnings[0].WarningCode)
+}
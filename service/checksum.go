@@ -0,0 +1,33 @@
+package service
+
+// checksumAlphabet matches the alphabet of generated paths, so a checksummed path stays alphanumeric.
+const checksumAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// pathChecksum returns the check character for path. Each byte is weighted by its position,
+// so both single character typos and most swaps of adjacent characters change the result.
+func pathChecksum(path string) byte {
+	sum := 0
+	for i := 0; i < len(path); i++ {
+		sum += (i + 1) * int(path[i])
+	}
+	return checksumAlphabet[sum%len(checksumAlphabet)]
+}
+
+// appendPathChecksum adds the check character to path when the tenant uses checksummed paths.
+// The character is folded to the tenant's path case like the rest of the path.
+func appendPathChecksum(path string, tenantCfg TenantConfig) string {
+	if !tenantCfg.PathChecksum {
+		return path
+	}
+	return path + tenantCfg.PathCase.apply(string(pathChecksum(path)))
+}
+
+// validPathChecksum reports whether the last character of path is the checksum of the rest,
+// folded to pathCase.
+func validPathChecksum(path string, pathCase PathCase) bool {
+	if len(path) < 2 {
+		return false
+	}
+	body := path[:len(path)-1]
+	return path[len(path)-1:] == pathCase.apply(string(pathChecksum(body)))
+}
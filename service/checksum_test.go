@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathChecksum(t *testing.T) {
+	cfg := TenantConfig{PathChecksum: true}
+
+	path := appendPathChecksum("abc123", cfg)
+	assert.Len(t, path, len("abc123")+1)
+	assert.True(t, strings.HasPrefix(path, "abc123"))
+	assert.Contains(t, checksumAlphabet, path[len(path)-1:])
+	assert.True(t, validPathChecksum(path, PathCaseMixed))
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "changed character", path: "abd123" + path[len(path)-1:]},
+		{name: "swapped adjacent characters", path: "ab1c23" + path[len(path)-1:]},
+		{name: "missing character", path: "abc12" + path[len(path)-1:]},
+		{name: "too short", path: "a"},
+		{name: "empty", path: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.False(t, validPathChecksum(tt.path, PathCaseMixed))
+		})
+	}
+
+	assert.Equal(t, "abc123", appendPathChecksum("abc123", TenantConfig{}))
+}
+
+func TestCreateAndResolve_PathChecksum(t *testing.T) {
+	service, db := setupTestService(t)
+
+	cfg := defaultTenantCfg
+	cfg.PathChecksum = true
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "SHORT",
+		},
+	}
+
+	result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+	require.NoError(t, err)
+
+	path := result.Details.Path
+	assert.Len(t, path, cfg.ShortPathLength+1)
+	assert.True(t, validPathChecksum(path, PathCaseMixed))
+
+	var stored models.DurableLinkDB
+	require.NoError(t, db.First(&stored).Error)
+	assert.Equal(t, path, stored.Path, "stored path includes the checksum")
+
+	resolved, err := service.ResolveShortPath(context.Background(), result.ShortLink, nil, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/target", resolved.LongLink)
+
+	custom := params
+	custom.CustomPath = "spring"
+	customResult, err := service.CreateDurableLink(context.Background(), custom, nil, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, appendPathChecksum("spring", cfg), customResult.Details.Path)
+
+	// A corrupted path is rejected before the database is queried
+	wrong := "a"
+	if strings.HasSuffix(path, wrong) {
+		wrong = "b"
+	}
+	corrupted := path[:len(path)-1] + wrong
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	_, err = service.ResolveShortPath(context.Background(), "https://example.com/"+corrupted, nil, cfg)
+	assert.ErrorIs(t, err, ErrPathChecksumMismatch)
+}
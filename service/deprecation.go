@@ -0,0 +1,73 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/apppanel/durablelinks-core/models"
+)
+
+// WithDeprecatedParams makes creates warn with DEPRECATED_PARAM when they set one of params,
+// dotted JSON paths within durableLinkInfo such as "iosParameters.iosIpadFallbackLink". The
+// params are still accepted and stored, the warning only gives clients notice to migrate.
+func WithDeprecatedParams(params ...string) Option {
+	return func(s *linkService) {
+		s.deprecatedParams = append(s.deprecatedParams, params...)
+	}
+}
+
+// deprecatedParamWarnings warns about each of the service's deprecated params that dl sets.
+func (s *linkService) deprecatedParamWarnings(dl *models.DurableLink) []models.Warning {
+	if len(s.deprecatedParams) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(dl)
+	if err != nil {
+		return nil
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil
+	}
+
+	var warnings []models.Warning
+	for _, param := range s.deprecatedParams {
+		parts := strings.Split(param, ".")
+		if !isParamSet(doc, parts) {
+			continue
+		}
+		warnings = append(warnings, models.Warning{
+			WarningCode:    "DEPRECATED_PARAM",
+			WarningMessage: fmt.Sprintf("Param '%s' is deprecated and may stop being supported", parts[len(parts)-1]),
+			Field:          linkField(parts...),
+		})
+	}
+	return warnings
+}
+
+// isParamSet reports whether the JSON path parts leads to a non-empty value in doc.
+func isParamSet(doc map[string]any, parts []string) bool {
+	value, ok := doc[parts[0]]
+	if !ok {
+		return false
+	}
+	if len(parts) > 1 {
+		nested, ok := value.(map[string]any)
+		return ok && isParamSet(nested, parts[1:])
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
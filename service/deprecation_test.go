@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/apppanel/durablelinks-core/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDurableLink_DeprecatedParams(t *testing.T) {
+	newParams := func() models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			Suffix: models.Suffix{
+				Option: "UNGUESSABLE",
+			},
+		}
+	}
+
+	_, db := setupTestService(t)
+	service := NewLinkService(repository.NewLinkRepository(db),
+		WithDeprecatedParams("iosParameters.iosIpadFallbackLink", "locale"))
+
+	t.Run("deprecated param warns and is stored", func(t *testing.T) {
+		params := newParams()
+		params.DurableLinkInfo.IosParameters.IOSIpadFallbackLink = stringPtr("https://example.com/ipad")
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		require.Len(t, result.Warnings, 1)
+		assert.Equal(t, "DEPRECATED_PARAM", result.Warnings[0].WarningCode)
+		assert.Equal(t, "Param 'iosIpadFallbackLink' is deprecated and may stop being supported", result.Warnings[0].WarningMessage)
+		assert.Equal(t, "durableLinkInfo.iosParameters.iosIpadFallbackLink", result.Warnings[0].Field)
+
+		var stored models.DurableLinkDB
+		require.NoError(t, db.Where("path = ?", result.Details.Path).First(&stored).Error)
+		require.NotNil(t, stored.IOSIpadFallbackLink)
+		assert.Equal(t, "https://example.com/ipad", *stored.IOSIpadFallbackLink)
+	})
+
+	t.Run("top-level deprecated param warns", func(t *testing.T) {
+		params := newParams()
+		params.DurableLinkInfo.Locale = stringPtr("pt-BR")
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		require.Len(t, result.Warnings, 1)
+		assert.Equal(t, "durableLinkInfo.locale", result.Warnings[0].Field)
+	})
+
+	t.Run("unset deprecated params do not warn", func(t *testing.T) {
+		result, err := service.CreateDurableLink(context.Background(), newParams(), nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Empty(t, result.Warnings)
+	})
+
+	t.Run("deprecated params do not fail strict validation", func(t *testing.T) {
+		params := newParams()
+		params.DurableLinkInfo.Locale = stringPtr("en")
+		cfg := defaultTenantCfg
+		cfg.StrictValidation = true
+
+		_, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+	})
+
+	t.Run("without deprecated params nothing warns", func(t *testing.T) {
+		plain := NewLinkService(repository.NewLinkRepository(db))
+		params := newParams()
+		params.DurableLinkInfo.Locale = stringPtr("fr")
+
+		result, err := plain.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Empty(t, result.Warnings)
+	})
+}
@@ -1,9 +1,36 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrDomainLinkNotAllowed = errors.New("domain link not in allow list")
 	ErrInvalidPathFormat    = errors.New("path must contain exactly one segment")
 	ErrInvalidRequestedLink = errors.New("invalid requested link")
+	ErrMissingTenantSecret  = errors.New("tenant secret is required for deterministic paths")
+	ErrPathCollision        = errors.New("could not find a free path after several attempts")
+	ErrCustomPathTooShort   = errors.New("custom path is shorter than the minimum length")
+	ErrInvalidCustomPath    = errors.New("custom path may only contain letters, digits, '-' and '_'")
+	ErrCustomPathTaken      = errors.New("custom path is already in use")
+	ErrInvalidURLScheme     = errors.New("url scheme must be 'http' or 'https'")
+	ErrPasswordRequired     = errors.New("link is password protected")
+	ErrInvalidPassword      = errors.New("invalid link password")
+	ErrTargetUnreachable    = errors.New("target link is not reachable")
+	ErrPathChecksumMismatch = errors.New("path checksum does not match")
+	ErrInvalidSignature     = errors.New("missing or invalid link signature")
+	ErrOutputHostNotAllowed = errors.New("output host is not an alias of the link host")
+	ErrRelativeLink         = errors.New("link must be an absolute URL with a scheme and host")
+	ErrInvalidTenantSetting = errors.New("invalid tenant setting")
+	ErrPrivateTarget        = errors.New("link target is a private, loopback or link-local address")
+	ErrQuotaExceeded        = errors.New("link quota exceeded")
+	ErrNotModified          = errors.New("link not modified")
 )
+
+// ErrEmptyDomainAllowList is returned instead of a plain ErrDomainLinkNotAllowed when the tenant
+// allows no domain at all, which is usually a configuration mistake.
+var ErrEmptyDomainAllowList = fmt.Errorf("%w: the tenant domain allow list is empty", ErrDomainLinkNotAllowed)
+
+// ErrDomainDenied is returned when the domain of a link is on the tenant's deny list.
+var ErrDomainDenied = fmt.Errorf("%w: the domain is on the tenant deny list", ErrDomainLinkNotAllowed)
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/google/uuid"
+)
+
+// linkETag derives the entity tag of a resolve from the stored link and the target it resolved
+// to. The target is covered as resolution rules may pick a different one per request.
+func linkETag(link *models.DurableLinkDB, target string) string {
+	h := sha256.New()
+	for _, part := range []string{
+		target,
+		redirectType(link),
+		link.ParamsHash,
+		link.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// ResolveIfNoneMatch resolves rawURL like ResolveShortPath but fails with ErrNotModified when
+// ifNoneMatch, the value of an If-None-Match header, lists the resolve's ETag.
+func (s *linkService) ResolveIfNoneMatch(ctx context.Context, rawURL, ifNoneMatch string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.LongLinkResponse, error) {
+	resp, err := s.ResolveShortPath(ctx, rawURL, projectID, tenantCfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if etagMatches(ifNoneMatch, resp.ETag) {
+		return nil, ErrNotModified
+	}
+	return resp, nil
+}
+
+// etagMatches reports whether the If-None-Match header value ifNoneMatch matches etag, using
+// the weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveIfNoneMatch(t *testing.T) {
+	service, db := setupTestService(t)
+
+	require.NoError(t, db.Create(&models.DurableLinkDB{
+		Host: "example.com",
+		Path: "abc123",
+		Link: "https://example.com/target",
+	}).Error)
+
+	first, err := service.ResolveShortPath(context.Background(), "https://example.com/abc123", nil, defaultTenantCfg)
+	require.NoError(t, err)
+	require.NotEmpty(t, first.ETag)
+
+	t.Run("unchanged link is not modified", func(t *testing.T) {
+		for _, header := range []string{first.ETag, "W/" + first.ETag, `"other", ` + first.ETag, "*"} {
+			resp, err := service.ResolveIfNoneMatch(context.Background(), "https://example.com/abc123", header, nil, defaultTenantCfg)
+			assert.ErrorIs(t, err, ErrNotModified, header)
+			assert.Equal(t, http.StatusNotModified, HTTPStatusForError(err))
+			assert.Nil(t, resp)
+		}
+	})
+
+	t.Run("missing or other etag resolves", func(t *testing.T) {
+		for _, header := range []string{"", `"other"`} {
+			resp, err := service.ResolveIfNoneMatch(context.Background(), "https://example.com/abc123", header, nil, defaultTenantCfg)
+			require.NoError(t, err, header)
+			assert.Equal(t, "https://example.com/target", resp.LongLink)
+			assert.Equal(t, first.ETag, resp.ETag)
+		}
+	})
+
+	t.Run("changed link gets a new etag", func(t *testing.T) {
+		db.Model(&models.DurableLinkDB{}).Where("path = ?", "abc123").UpdateColumns(map[string]interface{}{
+			"link":       "https://example.com/moved",
+			"updated_at": time.Now().Add(time.Minute),
+		})
+
+		resp, err := service.ResolveIfNoneMatch(context.Background(), "https://example.com/abc123", first.ETag, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/moved", resp.LongLink)
+		assert.NotEqual(t, first.ETag, resp.ETag)
+	})
+
+	t.Run("errors are passed through", func(t *testing.T) {
+		_, err := service.ResolveIfNoneMatch(context.Background(), "https://example.com/missing", "*", nil, defaultTenantCfg)
+		assert.Equal(t, http.StatusNotFound, HTTPStatusForError(err))
+	})
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/google/uuid"
+)
+
+// exportPageSize is how many links are read from the repository per page while exporting.
+const exportPageSize = 500
+
+var csvHeader = []string{"host", "path", "link", "name", "unguessable", "click_count", "created_at", "updated_at"}
+
+// ExportLinksCSV streams all links of a project to w as CSV, one page at a time.
+func (s *linkService) ExportLinksCSV(ctx context.Context, projectID *uuid.UUID, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for offset := 0; ; offset += exportPageSize {
+		links, err := s.repo.ListLinks(ctx, projectID, exportPageSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to list links: %w", err)
+		}
+
+		for _, link := range links {
+			if err := cw.Write(csvRecord(link)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+
+		if len(links) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+func csvRecord(link models.DurableLinkDB) []string {
+	name := ""
+	if link.Name != nil {
+		name = *link.Name
+	}
+
+	return []string{
+		link.Host,
+		link.Path,
+		link.Link,
+		name,
+		strconv.FormatBool(link.IsUnguessablePath),
+		strconv.FormatInt(link.ClickCount, 10),
+		link.CreatedAt.UTC().Format(time.RFC3339),
+		link.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"testing"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportLinksCSV(t *testing.T) {
+	service, db := setupTestService(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+
+	// More than one page to exercise pagination
+	total := exportPageSize + 3
+	for i := range total {
+		db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      fmt.Sprintf("path%d", i),
+			Link:      "https://example.com/target",
+			ProjectID: &projectIDStr,
+		})
+	}
+	db.Create(&models.DurableLinkDB{
+		Host: "example.com",
+		Path: "other-project",
+		Link: "https://example.com/target",
+		Name: stringPtr("Not exported"),
+	})
+	db.Model(&models.DurableLinkDB{}).Where("path = ?", "path0").Updates(map[string]interface{}{"name": "First, \"quoted\"", "click_count": 7})
+
+	var buf bytes.Buffer
+	err := service.ExportLinksCSV(context.Background(), &projectID, &buf)
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, total+1)
+
+	assert.Equal(t, []string{"host", "path", "link", "name", "unguessable", "click_count", "created_at", "updated_at"}, records[0])
+	assert.Equal(t, "example.com", records[1][0])
+	assert.Equal(t, "path0", records[1][1])
+	assert.Equal(t, "https://example.com/target", records[1][2])
+	assert.Equal(t, "First, \"quoted\"", records[1][3])
+	assert.Equal(t, "false", records[1][4])
+	assert.Equal(t, "7", records[1][5])
+	assert.NotEmpty(t, records[1][6])
+	assert.Equal(t, fmt.Sprintf("path%d", total-1), records[total][1])
+}
+
+func TestExportLinksCSV_Empty(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	projectID := uuid.New()
+	var buf bytes.Buffer
+	err := service.ExportLinksCSV(context.Background(), &projectID, &buf)
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/google/uuid"
+)
+
+// maxFeedEntries bounds how many links a feed lists, and is used when no limit is given.
+const maxFeedEntries = exportPageSize
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Link    atomLink `xml:"link"`
+	Updated string   `xml:"updated"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// ExportLinksFeed writes the limit most recently created links of a project to w as an Atom
+// feed, newest first. Unguessable and password protected links are never listed. A limit outside 1..maxFeedEntries lists maxFeedEntries links.
+func (s *linkService) ExportLinksFeed(ctx context.Context, projectID *uuid.UUID, limit int, w io.Writer, tenantCfg TenantConfig) error {
+	tenantCfg = resolveTenantConfig(ctx, tenantCfg)
+	if limit <= 0 || limit > maxFeedEntries {
+		limit = maxFeedEntries
+	}
+
+	links, err := s.repo.ListRecentLinks(ctx, projectID, limit)
+	if err != nil {
+		return fmt.Errorf("failed to list links: %w", err)
+	}
+
+	feed := atomFeed{
+		Xmlns:   atomNamespace,
+		ID:      "urn:durablelinks:links",
+		Title:   "Links",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Entries: make([]atomEntry, 0, len(links)),
+	}
+	if projectID != nil {
+		feed.ID = "urn:uuid:" + projectID.String()
+	}
+	if len(links) > 0 {
+		feed.Updated = links[0].CreatedAt.UTC().Format(time.RFC3339)
+	}
+	for _, link := range links {
+		feed.Entries = append(feed.Entries, feedEntry(tenantCfg, link))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// feedEntry titles link by its social title, then its name, then its short link. The short link
+// is left unsigned, a feed must not hand out signatures.
+func feedEntry(tenantCfg TenantConfig, link models.DurableLinkDB) atomEntry {
+	tenantCfg.RequireSignature = false
+	shortLink := buildShortLink(tenantCfg, link.Host, link.Path)
+
+	title := shortLink
+	if link.SocialTitle != nil && *link.SocialTitle != "" {
+		title = *link.SocialTitle
+	} else if link.Name != nil && *link.Name != "" {
+		title = *link.Name
+	}
+
+	return atomEntry{
+		ID:      shortLink,
+		Title:   title,
+		Link:    atomLink{Href: shortLink},
+		Updated: link.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
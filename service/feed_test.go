@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type parsedFeed struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Entries []struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Link  struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Updated string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+func TestExportLinksFeed(t *testing.T) {
+	service, db := setupTestService(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	db.Create(&models.DurableLinkDB{
+		Host:      "example.com",
+		Path:      "oldest",
+		Link:      "https://example.com/target",
+		ProjectID: &projectIDStr,
+		CreatedAt: base,
+	})
+	db.Create(&models.DurableLinkDB{
+		Host:      "example.com",
+		Path:      "named",
+		Link:      "https://example.com/target",
+		Name:      stringPtr("Spring <sale> & more"),
+		ProjectID: &projectIDStr,
+		CreatedAt: base.Add(time.Hour),
+	})
+	db.Create(&models.DurableLinkDB{
+		Host:        "example.com",
+		Path:        "social",
+		Link:        "https://example.com/target",
+		Name:        stringPtr("Internal name"),
+		SocialTitle: stringPtr(`"Quoted" title`),
+		ProjectID:   &projectIDStr,
+		CreatedAt:   base.Add(2 * time.Hour),
+	})
+	db.Create(&models.DurableLinkDB{
+		Host: "example.com",
+		Path: "other-project",
+		Link: "https://example.com/target",
+	})
+
+	var buf bytes.Buffer
+	err := service.ExportLinksFeed(context.Background(), &projectID, 10, &buf, defaultTenantCfg)
+	require.NoError(t, err)
+
+	var feed parsedFeed
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &feed))
+
+	assert.Equal(t, "urn:uuid:"+projectIDStr, feed.ID)
+	assert.Equal(t, "2024-01-01T14:00:00Z", feed.Updated)
+	require.Len(t, feed.Entries, 3)
+
+	assert.Equal(t, `"Quoted" title`, feed.Entries[0].Title)
+	assert.Equal(t, "https://example.com/social", feed.Entries[0].Link.Href)
+	assert.Equal(t, "https://example.com/social", feed.Entries[0].ID)
+	assert.Equal(t, "2024-01-01T14:00:00Z", feed.Entries[0].Updated)
+	assert.Equal(t, "Spring <sale> & more", feed.Entries[1].Title)
+	assert.Equal(t, "https://example.com/oldest", feed.Entries[2].Title)
+	assert.NotContains(t, buf.String(), "<sale>")
+
+	buf.Reset()
+	err = service.ExportLinksFeed(context.Background(), &projectID, 1, &buf, defaultTenantCfg)
+	require.NoError(t, err)
+	var limited parsedFeed
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &limited))
+	require.Len(t, limited.Entries, 1)
+	assert.Equal(t, "https://example.com/social", limited.Entries[0].Link.Href)
+}
+
+func TestExportLinksFeed_Empty(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	projectID := uuid.New()
+	var buf bytes.Buffer
+	err := service.ExportLinksFeed(context.Background(), &projectID, 0, &buf, defaultTenantCfg)
+	require.NoError(t, err)
+
+	var feed parsedFeed
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &feed))
+	assert.Empty(t, feed.Entries)
+	assert.NotEmpty(t, feed.Updated)
+}
+
+func TestExportLinksFeed_LeavesOutPrivateLinks(t *testing.T) {
+	service, db := setupTestService(t)
+
+	projectID := uuid.New()
+	projectIDStr := projectID.String()
+	db.Create(&models.DurableLinkDB{
+		Host:      "example.com",
+		Path:      "public",
+		Link:      "https://example.com/target",
+		ProjectID: &projectIDStr,
+	})
+	db.Create(&models.DurableLinkDB{
+		Host:              "example.com",
+		Path:              "unguessable",
+		Link:              "https://example.com/target",
+		IsUnguessablePath: true,
+		ProjectID:         &projectIDStr,
+	})
+	db.Create(&models.DurableLinkDB{
+		Host:         "example.com",
+		Path:         "protected",
+		Link:         "https://example.com/target",
+		PasswordHash: stringPtr("$2a$10$hash"),
+		ProjectID:    &projectIDStr,
+	})
+
+	cfg := defaultTenantCfg
+	cfg.Secret = "tenant-secret"
+	cfg.RequireSignature = true
+
+	var buf bytes.Buffer
+	err := service.ExportLinksFeed(context.Background(), &projectID, 10, &buf, cfg)
+	require.NoError(t, err)
+
+	var feed parsedFeed
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &feed))
+	require.Len(t, feed.Entries, 1)
+	assert.Equal(t, "https://example.com/public", feed.Entries[0].Link.Href)
+	assert.NotContains(t, buf.String(), signatureParam+"=")
+}
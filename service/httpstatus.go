@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/apppanel/durablelinks-core/repository"
+)
+
+// HTTPStatusForError maps errors returned by the service to the HTTP status code a
+// transport layer should respond with. Unknown errors map to 500.
+func HTTPStatusForError(err error) int {
+	var validationErrs models.ValidationErrors
+
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrNotModified):
+		return http.StatusNotModified
+	case errors.As(err, &validationErrs):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrDomainLinkNotAllowed),
+		errors.Is(err, ErrInvalidPathFormat),
+		errors.Is(err, ErrInvalidRequestedLink),
+		errors.Is(err, ErrCustomPathTooShort),
+		errors.Is(err, ErrInvalidCustomPath),
+		errors.Is(err, ErrInvalidURLScheme),
+		errors.Is(err, ErrTargetUnreachable),
+		errors.Is(err, ErrOutputHostNotAllowed),
+		errors.Is(err, ErrRelativeLink),
+		errors.Is(err, ErrPrivateTarget):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrPasswordRequired):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrInvalidPassword),
+		errors.Is(err, ErrInvalidSignature),
+		errors.Is(err, ErrQuotaExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, ErrCustomPathTaken):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrLinkNotFound),
+		errors.Is(err, ErrPathChecksumMismatch):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrLinkGone):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/apppanel/durablelinks-core/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPStatusForError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "nil", err: nil, expected: http.StatusOK},
+		{name: "domain not allowed", err: ErrDomainLinkNotAllowed, expected: http.StatusBadRequest},
+		{name: "invalid path format", err: ErrInvalidPathFormat, expected: http.StatusBadRequest},
+		{name: "invalid requested link", err: ErrInvalidRequestedLink, expected: http.StatusBadRequest},
+		{name: "custom path too short", err: ErrCustomPathTooShort, expected: http.StatusBadRequest},
+		{name: "invalid custom path", err: ErrInvalidCustomPath, expected: http.StatusBadRequest},
+		{name: "invalid url scheme", err: ErrInvalidURLScheme, expected: http.StatusBadRequest},
+		{name: "target unreachable", err: fmt.Errorf("%w: status 404", ErrTargetUnreachable), expected: http.StatusBadRequest},
+		{name: "output host not allowed", err: ErrOutputHostNotAllowed, expected: http.StatusBadRequest},
+		{name: "relative link", err: ErrRelativeLink, expected: http.StatusBadRequest},
+		{name: "empty domain allow list", err: ErrEmptyDomainAllowList, expected: http.StatusBadRequest},
+		{name: "private target", err: fmt.Errorf("%w: localhost", ErrPrivateTarget), expected: http.StatusBadRequest},
+		{name: "password required", err: ErrPasswordRequired, expected: http.StatusUnauthorized},
+		{name: "invalid password", err: ErrInvalidPassword, expected: http.StatusForbidden},
+		{name: "invalid signature", err: ErrInvalidSignature, expected: http.StatusForbidden},
+		{name: "quota exceeded", err: ErrQuotaExceeded, expected: http.StatusForbidden},
+		{name: "not modified", err: ErrNotModified, expected: http.StatusNotModified},
+		{name: "custom path taken", err: ErrCustomPathTaken, expected: http.StatusConflict},
+		{name: "link not found", err: repository.ErrLinkNotFound, expected: http.StatusNotFound},
+		{name: "wrapped link not found", err: fmt.Errorf("lookup: %w", repository.ErrLinkNotFound), expected: http.StatusNotFound},
+		{name: "path checksum mismatch", err: ErrPathChecksumMismatch, expected: http.StatusNotFound},
+		{name: "click limit reached", err: repository.ErrClickLimitReached, expected: http.StatusGone},
+		{name: "link gone", err: repository.ErrLinkGone, expected: http.StatusGone},
+		{name: "link deleted", err: repository.ErrLinkDeleted, expected: http.StatusGone},
+		{
+			name:     "validation errors",
+			err:      models.ValidationErrors{Errors: []models.ValidationError{{Field: "durableLinkInfo.link", Tag: "required"}}},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "wrapped validation errors",
+			err:      fmt.Errorf("create: %w", models.ValidationErrors{}),
+			expected: http.StatusUnprocessableEntity,
+		},
+		{name: "unknown error", err: errors.New("boom"), expected: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, HTTPStatusForError(tt.err))
+		})
+	}
+}
@@ -2,11 +2,21 @@ package service
 
 import (
 	"context"
-	"crypto/rand"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/big"
+	"net"
 	"net/url"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/apppanel/durablelinks-core/models"
 	"github.com/apppanel/durablelinks-core/repository"
@@ -14,76 +24,519 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// PathStrategy selects how new link paths are generated.
+type PathStrategy int
+
+const (
+	// PathStrategyRandom generates paths from a cryptographically random source.
+	PathStrategyRandom PathStrategy = iota
+	// PathStrategyHMACDeterministic derives paths from an HMAC of the target, so the
+	// same host, link and parameters always yield the same code.
+	PathStrategyHMACDeterministic
+)
+
+var pathStrategyNames = map[string]PathStrategy{
+	"random":             PathStrategyRandom,
+	"hmac_deterministic": PathStrategyHMACDeterministic,
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting "random" or "hmac_deterministic",
+// so the path strategy can be loaded from tenant settings.
+func (p *PathStrategy) UnmarshalText(text []byte) error {
+	strategy, ok := pathStrategyNames[strings.ToLower(strings.TrimSpace(string(text)))]
+	if !ok {
+		return fmt.Errorf("unknown path strategy %q", text)
+	}
+	*p = strategy
+	return nil
+}
+
+// PathCase selects the letter case of generated paths.
+type PathCase int
+
+const (
+	// PathCaseMixed generates paths from both lower and upper case letters.
+	PathCaseMixed PathCase = iota
+	// PathCaseLower generates lower case paths and resolves paths case-insensitively.
+	PathCaseLower
+	// PathCaseUpper generates upper case paths and resolves paths case-insensitively.
+	PathCaseUpper
+)
+
+var pathCaseNames = map[string]PathCase{
+	"mixed": PathCaseMixed,
+	"lower": PathCaseLower,
+	"upper": PathCaseUpper,
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting "mixed", "lower" or "upper", so
+// the path case can be loaded from tenant settings.
+func (c *PathCase) UnmarshalText(text []byte) error {
+	pathCase, ok := pathCaseNames[strings.ToLower(strings.TrimSpace(string(text)))]
+	if !ok {
+		return fmt.Errorf("unknown path case %q", text)
+	}
+	*c = pathCase
+	return nil
+}
+
+// apply folds path to c, a mixed case path is returned unchanged.
+func (c PathCase) apply(path string) string {
+	switch c {
+	case PathCaseLower:
+		return strings.ToLower(path)
+	case PathCaseUpper:
+		return strings.ToUpper(path)
+	}
+	return path
+}
+
+// releasedPathCase returns the released paths c can hand out again.
+func (c PathCase) releasedPathCase() repository.ReleasedPathCase {
+	switch c {
+	case PathCaseLower:
+		return repository.LowerPathCase
+	case PathCaseUpper:
+		return repository.UpperPathCase
+	}
+	return repository.AnyPathCase
+}
+
+// PathUniquenessScope selects which links a new path must not collide with.
+type PathUniquenessScope int
+
+const (
+	// PathUniquenessGlobal keeps every path on a host unique across projects, so a path
+	// always means the same link.
+	PathUniquenessGlobal PathUniquenessScope = iota
+	// PathUniquenessPerProject gives every project its own namespace of paths, the same
+	// path may then point at different links in different projects.
+	PathUniquenessPerProject
+)
+
+var pathUniquenessScopeNames = map[string]PathUniquenessScope{
+	"global":      PathUniquenessGlobal,
+	"per_project": PathUniquenessPerProject,
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting "global" or "per_project", so
+// the uniqueness scope can be loaded from tenant settings.
+func (p *PathUniquenessScope) UnmarshalText(text []byte) error {
+	scope, ok := pathUniquenessScopeNames[strings.ToLower(strings.TrimSpace(string(text)))]
+	if !ok {
+		return fmt.Errorf("unknown path uniqueness scope %q", text)
+	}
+	*p = scope
+	return nil
+}
+
+// defaultMinCustomPathLength applies when TenantConfig.MinCustomPathLength is unset.
+const defaultMinCustomPathLength = 3
+
+var customPathPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// androidPackagePattern is the Android application ID grammar: two or more dot separated segments,
+// each starting with a letter.
+var androidPackagePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*(\.[a-zA-Z][a-zA-Z0-9_]*)+$`)
+
+// countryPattern matches ISO 3166-1 alpha-2 country codes.
+var countryPattern = regexp.MustCompile(`^[A-Za-z]{2}$`)
+
+// localePattern loosely matches BCP 47 language tags: a language followed by optional subtags.
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`)
+
+// itunesMediaTypes are the media types Apple defines for the itunes "mt" param, 8 being apps.
+var itunesMediaTypes = map[string]bool{
+	"1": true, "2": true, "3": true, "4": true, "5": true, "6": true,
+	"7": true, "8": true, "9": true, "10": true, "11": true, "12": true,
+}
+
+// maxInterstitialDelayMs bounds InterstitialDelayMs, a longer preview is more likely a mistake than intended.
+const maxInterstitialDelayMs = 10000
+
+// maxSocialTitleLength is the size of the social_title column.
+const maxSocialTitleLength = 500
+
+// defaultPreviewQueryParam applies when TenantConfig.PreviewQueryParam is unset.
+const defaultPreviewQueryParam = "d=1"
+
+// maxPathAttempts bounds how many candidate paths are tried before giving up on a collision.
+const maxPathAttempts = 5
+
 type TenantConfig struct {
-	URLScheme             string
-	DomainAllowList       []string
-	ShortPathLength       int
-	UnguessablePathLength int
-	DefaultIOSAppStoreId  *int64
-	DefaultAndroidPackage *string
+	URLScheme                 string
+	DomainAllowList           []string
+	DomainDenyList            []string // Target domains, and their subdomains, links may never point at; wins over DomainAllowList
+	ShortPathLength           int
+	UnguessablePathLength     int
+	DefaultIOSAppStoreId      *int64
+	DefaultAndroidPackage     *string
+	DefaultUtmSource          *string // Applied when a create has neither utm_source nor utm_medium
+	DefaultUtmMedium          *string // Applied when a create has a utm_source, possibly the default, but no utm_medium
+	PathStrategy              PathStrategy
+	PathCase                  PathCase // Case of generated and custom paths, resolving falls back to folding paths to it
+	Secret                    string   // Key for HMAC based features such as PathStrategyHMACDeterministic
+	Secrets                   []Secret // Rotating keys for the same features, newest last. New signatures use the newest, all verify
+	MinCustomPathLength       int      // Minimum length of a caller supplied custom path, defaults to 3
+	NotFoundFallbackURL       *string
+	StoreRawRequest           bool   // Keep the original create request JSON with the link for auditing
+	PathPrefix                string // Sub-path the service is mounted under, e.g. "/l" for example.com/l/abc123
+	PathSuffix                string // Appended to short links and stripped on resolve, e.g. ".page" for example.com/abc123.page; must not contain '/'
+	StrictValidation          bool   // Fail creates with ValidationErrors instead of warning on and clearing malformed params
+	VerifyTargetReachable     bool   // Reject creates whose target link can't be reached or returns 4xx/5xx
+	VerifyTargetTimeout       time.Duration
+	DedupIgnoreUTM            bool              // Treat links that differ only in UTM params as duplicates
+	PathChecksum              bool              // Append a check character to every path, custom ones included, and verify it on resolve
+	RequireSignature          bool              // Sign short links with Secret and refuse to resolve links without a valid signature
+	UnwrapNestedShortLinks    bool              // Point links whose target is one of our own short links at that link's target instead
+	HostAliases               map[string]string // Extra short domains, mapped to the host their links are stored under
+	PathUniquenessScope       PathUniquenessScope
+	RecyclePaths              bool          // Hand out paths of deleted links again before generating new ones
+	StripQueryParams          []string      // Query params, e.g. "fbclid", removed from target links before they are stored
+	AllowAllDomains           bool          // Let an empty DomainAllowList allow every domain instead of none
+	RejectPrivateTargets      bool          // Reject target links on loopback, private or link-local addresses, resolving host names
+	PreviewQueryParam         string        // Query appended to short links to form preview links, defaults to "d=1"
+	PreviewSubdomain          bool          // Form preview links on the "preview." subdomain instead of with PreviewQueryParam
+	MaxLinks                  *int64        // Links a project may hold, creates beyond it fail with ErrQuotaExceeded; nil means unlimited
+	MaxReuseAge               time.Duration // Only reuse short links created within it, zero reuses links of any age
+	RequirePlatformConfig     bool          // Warn, or fail in strict mode, when a create sets no platform specific param
+	WarnOnCrossDomainFallback bool          // Warn when a fallback link points to another host than 'link'
 }
 
 type LinkService interface {
 	CreateDurableLink(ctx context.Context, params models.CreateDurableLinkRequest, projectID *uuid.UUID, tenantCfg TenantConfig) (*models.ShortLinkResponse, error)
+	ValidateCreateRequest(params models.CreateDurableLinkRequest, tenantCfg TenantConfig) ([]models.Warning, error)
+	CreateDurableLinksBatch(ctx context.Context, items []models.CreateDurableLinkRequest, projectID *uuid.UUID, tenantCfg TenantConfig) []BatchCreateResult
 	ParseLongDurableLink(longLink string) (models.CreateDurableLinkRequest, error)
-	ResolveShortPath(ctx context.Context, rawURL string, projectID *uuid.UUID, tenantCfg TenantConfig) (*models.LongLinkResponse, error)
+	ResolveShortPath(ctx context.Context, rawURL string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.LongLinkResponse, error)
+	ResolveHostPath(ctx context.Context, host, path string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.LongLinkResponse, error)
+	ResolveCandidates(ctx context.Context, rawURL string, platform models.Platform, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) ([]string, error)
+	ResolveForPlatform(ctx context.Context, rawURL string, platform models.Platform, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.PlatformLinkResponse, error)
+	ResolveProtected(ctx context.Context, rawURL, password string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.LongLinkResponse, error)
+	ResolveOrFallback(ctx context.Context, rawURL string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (string, bool, error)
+	ResolveIfNoneMatch(ctx context.Context, rawURL, ifNoneMatch string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.LongLinkResponse, error)
+	ExportLinksCSV(ctx context.Context, projectID *uuid.UUID, w io.Writer) error
+	ExportLinksFeed(ctx context.Context, projectID *uuid.UUID, limit int, w io.Writer, tenantCfg TenantConfig) error
+	DeleteLink(ctx context.Context, rawURL string, projectID *uuid.UUID, tenantCfg TenantConfig) error
+	GetTenantConfig(ctx context.Context, tenantID string) (*TenantConfig, error)
 }
 
 type linkService struct {
-	repo repository.LinkRepository
+	repo             repository.LinkRepository
+	pathGenerator    PathGenerator
+	uniquenessFilter UniquenessFilter
+	hostResolver     HostResolver
+	deprecatedParams []string
+}
+
+// Option customizes a linkService created by NewLinkService.
+type Option func(*linkService)
+
+// WithPathGenerator replaces the default crypto-random path generator.
+func WithPathGenerator(g PathGenerator) Option {
+	return func(s *linkService) {
+		s.pathGenerator = g
+	}
+}
+
+// WithUniquenessFilter lets a UniquenessFilter skip the lookup for existing short links.
+func WithUniquenessFilter(f UniquenessFilter) Option {
+	return func(s *linkService) {
+		s.uniquenessFilter = f
+	}
 }
 
-func NewLinkService(repo repository.LinkRepository) *linkService {
-	return &linkService{
-		repo: repo,
+func NewLinkService(repo repository.LinkRepository, opts ...Option) *linkService {
+	s := &linkService{
+		repo:             repo,
+		pathGenerator:    randomPathGenerator{},
+		uniquenessFilter: passThroughFilter{},
+		hostResolver:     net.DefaultResolver,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
+// getLongLinkFromHostAndPath returns the stored link verbatim, including its query and fragment,
+// or the link of its first resolution rule matching the request context on ctx.
 func (s *linkService) getLongLinkFromHostAndPath(
 	ctx context.Context,
 	host string,
 	path string,
 	projectID *uuid.UUID,
+	pathCase PathCase,
+	opts resolveOptions,
 ) (*models.LongLinkResponse, error) {
-	link, err := s.repo.GetLinkByHostAndPath(ctx, host, path, projectID)
+	link, err := s.resolveLink(ctx, host, path, "", projectID, pathCase, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	target := link.ToDurableLink().TargetFor(resolveRequestContext(ctx))
 	log.Debug().
 		Str("path", path).
-		Str("long_link", link.Link).
+		Str("long_link", target).
 		Msg("Link retrieved from service")
 
+	return newLongLinkResponse(link, target), nil
+}
+
+// newLongLinkResponse builds the resolve response for link, which resolved to target.
+func newLongLinkResponse(link *models.DurableLinkDB, target string) *models.LongLinkResponse {
 	return &models.LongLinkResponse{
-		LongLink: link.Link,
-	}, nil
+		LongLink:            target,
+		RedirectType:        redirectType(link),
+		ETag:                linkETag(link, target),
+		InterstitialDelayMs: link.InterstitialDelayMs,
+		IsUnguessable:       link.IsUnguessablePath,
+	}
+}
+
+// redirectType returns the stored redirect type of link, temporary when unset.
+func redirectType(link *models.DurableLinkDB) string {
+	if link.RedirectType == nil {
+		return models.RedirectTypeTemporary
+	}
+	return *link.RedirectType
+}
+
+// resolveLink fetches the stored link, enforces its password, if any, and counts the click.
+// The click is counted with repository.ResolveAndIncrementClicks, which fails with
+// repository.ErrClickLimitReached once the link has used up its clicks. Requests with a missing
+// or wrong password are rejected before, so they don't use up clicks. With skipAnalytics no
+// click is counted, only the click limit is checked. A path whose link was deleted fails with
+// repository.ErrLinkDeleted instead of repository.ErrLinkNotFound.
+func (s *linkService) resolveLink(ctx context.Context, host, path, password string, projectID *uuid.UUID, pathCase PathCase, opts resolveOptions) (*models.DurableLinkDB, error) {
+	link, err := s.findLink(ctx, host, path, projectID, pathCase)
+	if errors.Is(err, repository.ErrLinkNotFound) {
+		return nil, s.missingLinkError(ctx, host, path, projectID, pathCase)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if link.PasswordHash != nil {
+		if password == "" {
+			return nil, ErrPasswordRequired
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(*link.PasswordHash), []byte(password)); err != nil {
+			return nil, ErrInvalidPassword
+		}
+	}
+
+	if opts.skipAnalytics {
+		if link.MaxClicks != nil && link.ClickCount >= *link.MaxClicks {
+			return nil, repository.ErrClickLimitReached
+		}
+		return link, nil
+	}
+
+	return s.repo.ResolveAndIncrementClicks(ctx, host, link.Path, projectID)
+}
+
+// findLink looks up the link at path on host. The exact path is tried first, then path folded to
+// pathCase, so links keep resolving both with the case they were created with and when a
+// lower or upper case path is typed in another case.
+func (s *linkService) findLink(ctx context.Context, host, path string, projectID *uuid.UUID, pathCase PathCase) (*models.DurableLinkDB, error) {
+	link, err := s.repo.GetLinkDBByHostAndPath(ctx, host, path, projectID)
+	if folded := pathCase.apply(path); errors.Is(err, repository.ErrLinkNotFound) && folded != path {
+		return s.repo.GetLinkDBByHostAndPath(ctx, host, folded, projectID)
+	}
+	return link, err
+}
+
+// missingLinkError tells a path whose link was deleted, exactly or folded to pathCase like
+// findLink, from one that never had a link.
+func (s *linkService) missingLinkError(ctx context.Context, host, path string, projectID *uuid.UUID, pathCase PathCase) error {
+	candidates := []string{path}
+	if folded := pathCase.apply(path); folded != path {
+		candidates = append(candidates, folded)
+	}
+	for _, candidate := range candidates {
+		deleted, err := s.repo.IsPathDeleted(ctx, host, candidate, projectID)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			return repository.ErrLinkDeleted
+		}
+	}
+	return repository.ErrLinkNotFound
 }
 
 func (s *linkService) CreateDurableLink(ctx context.Context, params models.CreateDurableLinkRequest, projectID *uuid.UUID, tenantCfg TenantConfig) (*models.ShortLinkResponse, error) {
+	tenantCfg = resolveTenantConfig(ctx, tenantCfg)
+	// Dedupe, nested link and path lookups must see recent writes, so skip the read replica
+	ctx = repository.WithPrimaryReads(ctx)
+
+	// Take the password out of the request so it is never logged or stored in plaintext
+	password := params.Password
+	params.Password = ""
+
 	log.Debug().
 		Str("params", fmt.Sprintf("%+v", params)).
 		Msg("Dynamic link parameters")
 
+	opts := createOptions{
+		dedupIgnoreUTM: tenantCfg.DedupIgnoreUTM,
+		maxClicks:      params.MaxClicks,
+		pathScope:      pathScope(projectID, tenantCfg),
+	}
+	if tenantCfg.StoreRawRequest {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize request: %w", err)
+		}
+		rawStr := string(raw)
+		opts.rawRequest = &rawStr
+	}
+
+	host, warnings, err := s.prepareCreate(&params, tenantCfg)
+	if err != nil {
+		return nil, err
+	}
+	if params.URLScheme != nil {
+		tenantCfg.URLScheme = strings.ToLower(*params.URLScheme)
+	}
+
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		hashStr := string(hash)
+		opts.passwordHash = &hashStr
+	}
+
+	if nested := s.nestedShortLink(ctx, host, params.DurableLinkInfo.Link, projectID, tenantCfg); nested != nil {
+		// Protected links are never unwrapped, that would bypass their password
+		if tenantCfg.UnwrapNestedShortLinks && nested.PasswordHash == nil {
+			log.Debug().
+				Str("link", params.DurableLinkInfo.Link).
+				Str("unwrapped", nested.Link).
+				Msg("Unwrapping nested short link")
+			// The nested link passed the checks of its own create, possibly under other
+			// settings, so its target is checked like a requested one
+			link, linkWarnings, err := prepareTargetLink(nested.Link, tenantCfg)
+			if err != nil {
+				return nil, err
+			}
+			params.DurableLinkInfo.Link = link
+			warnings = append(warnings, linkWarnings...)
+		} else {
+			warnings = append(warnings, models.Warning{
+				WarningCode:    "DOUBLE_SHORTENED",
+				WarningMessage: "Param 'link' is itself a short link, opening the new link will redirect twice",
+				Field:          linkField("link"),
+			})
+		}
+	}
+
+	if tenantCfg.RejectPrivateTargets {
+		targets := []string{params.DurableLinkInfo.Link}
+		for _, rule := range params.DurableLinkInfo.Rules {
+			targets = append(targets, rule.Link)
+		}
+		for _, target := range targets {
+			if err := s.checkPublicTarget(ctx, target); err != nil {
+				log.Error().
+					Err(err).
+					Str("link", target).
+					Msg("Rejecting private link target")
+				return nil, err
+			}
+		}
+	}
+
+	if tenantCfg.VerifyTargetReachable && !params.SkipTargetVerification {
+		if err := verifyTargetReachable(ctx, params.DurableLinkInfo.Link, tenantCfg.VerifyTargetTimeout, tenantCfg.RejectPrivateTargets); err != nil {
+			return nil, err
+		}
+	}
+
+	var response *models.ShortLinkResponse
+	if params.CustomPath != "" {
+		customPath := appendPathChecksum(tenantCfg.PathCase.apply(params.CustomPath), tenantCfg)
+		response, err = s.createCustomPathLink(ctx, host, params.DurableLinkInfo, customPath, opts, projectID, tenantCfg)
+	} else {
+		shortPath, _ := s.validateSuffixOption(params.Suffix)
+		response, err = s.createOrGetShortLink(ctx, host, params.DurableLinkInfo, shortPath, opts, projectID, tenantCfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The link stays stored under host, only the returned URL uses the alias
+	if params.OutputHost != nil {
+		outputHost, _ := utils.CleanHost(log.Logger, *params.OutputHost)
+		reused := response.Reused
+		response = newShortLinkResponse(tenantCfg, outputHost, response.Details.Path)
+		response.Reused = reused
+	}
+
+	response.Warnings = warnings
+	return response, nil
+}
+
+// ValidateCreateRequest runs the checks of CreateDurableLink that don't need storage: host
+// cleaning, the domain allow list, tenant defaults and param validation. It returns the
+// warnings a create would report, or the error it would fail with. The repository is never
+// used, so a service without one can validate requests too.
+func (s *linkService) ValidateCreateRequest(params models.CreateDurableLinkRequest, tenantCfg TenantConfig) ([]models.Warning, error) {
+	_, warnings, err := s.prepareCreate(&params, tenantCfg)
+	if err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// prepareCreate applies the storage independent part of a create to params in place and
+// returns the cleaned host along with the warnings collected on the way.
+func (s *linkService) prepareCreate(params *models.CreateDurableLinkRequest, tenantCfg TenantConfig) (string, []models.Warning, error) {
+	if _, ok := tenantCfg.signingSecret(); tenantCfg.RequireSignature && !ok {
+		return "", nil, ErrMissingTenantSecret
+	}
+
+	if params.URLScheme != nil {
+		scheme := strings.ToLower(*params.URLScheme)
+		if scheme != "http" && scheme != "https" {
+			return "", nil, ErrInvalidURLScheme
+		}
+	}
+
 	host, err := utils.CleanHost(log.Logger, params.DurableLinkInfo.Host)
 	if err != nil {
 		log.Error().
 			Str("host", params.DurableLinkInfo.Host).
 			Msg("Invalid host")
-		return nil, fmt.Errorf("invalid host: %w", err)
+		return "", nil, fmt.Errorf("invalid host: %w", err)
 	}
 
-	if !utils.IsDomainAllowed(log.Logger, tenantCfg.DomainAllowList, params.DurableLinkInfo.Link) {
-		log.Error().
-			Str("link", params.DurableLinkInfo.Link).
-			Msg("Domain link not in allow list")
-		return nil, ErrDomainLinkNotAllowed
+	link, warnings, err := prepareTargetLink(params.DurableLinkInfo.Link, tenantCfg)
+	if err != nil {
+		return "", nil, err
 	}
+	params.DurableLinkInfo.Link = link
 
-	warnings := []models.Warning{}
+	if params.OutputHost != nil {
+		outputHost, err := utils.CleanHost(log.Logger, *params.OutputHost)
+		if err != nil {
+			return "", nil, ErrOutputHostNotAllowed
+		}
+		if stored, ok := aliasedHost(tenantCfg, outputHost); outputHost != host && (!ok || stored != host) {
+			log.Error().
+				Str("host", host).
+				Str("output_host", outputHost).
+				Msg("Output host is not an alias of the link host")
+			return "", nil, ErrOutputHostNotAllowed
+		}
+	}
 
 	// Apply defaults from tenant config if not provided
 	if params.DurableLinkInfo.IosParameters.IOSAppStoreId == nil && tenantCfg.DefaultIOSAppStoreId != nil {
@@ -91,31 +544,155 @@ func (s *linkService) CreateDurableLink(ctx context.Context, params models.Creat
 		warnings = append(warnings, models.Warning{
 			WarningCode:    "DEFAULT_APPLIED",
 			WarningMessage: fmt.Sprintf("Using default iOS App Store ID: %d", *tenantCfg.DefaultIOSAppStoreId),
+			Field:          linkField("iosParameters", "iosAppStoreId"),
 		})
 	}
 
 	if params.DurableLinkInfo.AndroidParameters.AndroidPackageName == nil && tenantCfg.DefaultAndroidPackage != nil {
-		params.DurableLinkInfo.AndroidParameters.AndroidPackageName = tenantCfg.DefaultAndroidPackage
+		defaultPackage := strings.TrimSpace(*tenantCfg.DefaultAndroidPackage)
+		if androidPackagePattern.MatchString(defaultPackage) {
+			params.DurableLinkInfo.AndroidParameters.AndroidPackageName = &defaultPackage
+			warnings = append(warnings, models.Warning{
+				WarningCode:    "DEFAULT_APPLIED",
+				WarningMessage: fmt.Sprintf("Using default Android package name: %s", defaultPackage),
+				Field:          linkField("androidParameters", "androidPackageName"),
+			})
+		} else {
+			log.Error().
+				Str("package", *tenantCfg.DefaultAndroidPackage).
+				Msg("Ignoring malformed default Android package name")
+		}
+	}
+
+	marketing := &params.DurableLinkInfo.AnalyticsInfo.MarketingParameters
+	if isEmptyParam(marketing.UtmSource) && isEmptyParam(marketing.UtmMedium) && !isEmptyParam(tenantCfg.DefaultUtmSource) {
+		marketing.UtmSource = tenantCfg.DefaultUtmSource
+		warnings = append(warnings, models.Warning{
+			WarningCode:    "DEFAULT_APPLIED",
+			WarningMessage: fmt.Sprintf("Using default utm_source: %s", *tenantCfg.DefaultUtmSource),
+			Field:          linkField("analyticsInfo", "marketingParameters", "utmSource"),
+		})
+	}
+	if !isEmptyParam(marketing.UtmSource) && isEmptyParam(marketing.UtmMedium) && !isEmptyParam(tenantCfg.DefaultUtmMedium) {
+		marketing.UtmMedium = tenantCfg.DefaultUtmMedium
 		warnings = append(warnings, models.Warning{
 			WarningCode:    "DEFAULT_APPLIED",
-			WarningMessage: fmt.Sprintf("Using default Android package name: %s", *tenantCfg.DefaultAndroidPackage),
+			WarningMessage: fmt.Sprintf("Param 'utmSource' is set without 'utmMedium', using default utm_medium: %s", *tenantCfg.DefaultUtmMedium),
+			Field:          linkField("analyticsInfo", "marketingParameters", "utmMedium"),
 		})
 	}
 
 	validationWarnings := s.validateLinkParameters(&params.DurableLinkInfo)
+	if tenantCfg.StrictValidation {
+		if errs := malformedParamErrors(validationWarnings); len(errs) > 0 {
+			return "", nil, models.ValidationErrors{Errors: errs}
+		}
+	}
 	warnings = append(warnings, validationWarnings...)
 
-	shortPath, suffixWarning := s.validateSuffixOption(params.Suffix)
-	if suffixWarning != nil {
+	if tenantCfg.WarnOnCrossDomainFallback {
+		warnings = append(warnings, crossDomainFallbackWarnings(params.DurableLinkInfo)...)
+	}
+
+	if tenantCfg.RequirePlatformConfig && !hasPlatformConfig(params.DurableLinkInfo) {
+		missing := models.Warning{
+			WarningCode:    "MISSING_PLATFORM_CONFIG",
+			WarningMessage: "No Android, iOS or fallback params are set, the link opens 'link' on every platform",
+			Field:          "durableLinkInfo",
+		}
+		if tenantCfg.StrictValidation {
+			return "", nil, models.ValidationErrors{Errors: []models.ValidationError{{
+				Field:   missing.Field,
+				Tag:     "missing_platform_config",
+				Message: missing.WarningMessage,
+			}}}
+		}
+		warnings = append(warnings, missing)
+	}
+
+	// Rule targets are redirected to like the link itself, so they must be allowed too
+	for _, rule := range params.DurableLinkInfo.Rules {
+		if err := checkDomainAllowed(tenantCfg, rule.Link); err != nil {
+			log.Error().
+				Str("link", rule.Link).
+				Msg("Rule link domain not allowed")
+			return "", nil, err
+		}
+	}
+
+	if params.CustomPath != "" {
+		if err := validateCustomPath(params.CustomPath, tenantCfg); err != nil {
+			return "", nil, err
+		}
+		// The custom path takes precedence, a suffix option alongside it has no effect
+		if params.Suffix.Option != "" {
+			ignored := models.Warning{
+				WarningCode:    "UNRECOGNIZED_PARAM",
+				WarningMessage: fmt.Sprintf("Param 'suffix.option' is ignored, since 'customPath' is specified. Received '%s'.", params.Suffix.Option),
+				Field:          "suffix.option",
+			}
+			if tenantCfg.StrictValidation {
+				return "", nil, models.ValidationErrors{Errors: []models.ValidationError{{
+					Field:   ignored.Field,
+					Tag:     "conflicting_param",
+					Message: ignored.WarningMessage,
+				}}}
+			}
+			warnings = append(warnings, ignored)
+		}
+		return host, warnings, nil
+	}
+
+	if _, suffixWarning := s.validateSuffixOption(params.Suffix); suffixWarning != nil {
 		warnings = append(warnings, *suffixWarning)
 	}
-	response, err := s.createOrGetShortLink(ctx, host, params.DurableLinkInfo, shortPath, projectID, tenantCfg)
-	if err != nil {
-		return nil, err
+	return host, warnings, nil
+}
+
+// prepareTargetLink checks that link, the target of a new link, is an absolute URL on a domain
+// the tenant allows and removes the tenant's StripQueryParams from it. It returns the link to
+// store along with a warning for stripped params.
+func prepareTargetLink(link string, tenantCfg TenantConfig) (string, []models.Warning, error) {
+	// Relative and protocol-relative links cannot be redirected to from the short link host
+	if !utils.IsURL(link) {
+		log.Error().
+			Str("link", link).
+			Msg("Link is not an absolute URL")
+		return "", nil, ErrRelativeLink
 	}
 
-	response.Warnings = warnings
-	return response, nil
+	if err := checkDomainAllowed(tenantCfg, link); err != nil {
+		log.Error().
+			Str("link", link).
+			Msg("Domain link not allowed")
+		return "", nil, err
+	}
+
+	warnings := []models.Warning{}
+	if stripped, params := utils.StripQueryParams(link, tenantCfg.StripQueryParams); len(params) > 0 {
+		link = stripped
+		warnings = append(warnings, models.Warning{
+			WarningCode:    "QUERY_PARAMS_STRIPPED",
+			WarningMessage: fmt.Sprintf("Removed query params %s from param 'link'", strings.Join(params, ", ")),
+			Field:          linkField("link"),
+		})
+	}
+	return link, warnings, nil
+}
+
+// nestedShortLink returns the stored link when target is itself an existing short link on host,
+// and nil otherwise.
+func (s *linkService) nestedShortLink(ctx context.Context, host, target string, projectID *uuid.UUID, tenantCfg TenantConfig) *models.DurableLinkDB {
+	targetHost, path, err := parseShortURL(target, tenantCfg)
+	if err != nil || utils.NormalizeHost(targetHost) != host {
+		return nil
+	}
+
+	nested, err := s.findLink(ctx, host, path, projectID, tenantCfg.PathCase)
+	if err != nil {
+		return nil
+	}
+	return nested
 }
 
 func (s *linkService) validateSuffixOption(suffix models.Suffix) (bool, *models.Warning) {
@@ -124,6 +701,7 @@ func (s *linkService) validateSuffixOption(suffix models.Suffix) (bool, *models.
 		return false, &models.Warning{
 			WarningCode:    "INVALID_SUFFIX_OPTION",
 			WarningMessage: fmt.Sprintf("Param 'suffix.option' must be 'SHORT' or 'UNGUESSABLE'. Received '%s', defaulting to 'UNGUESSABLE'.", suffix.Option),
+			Field:          "suffix.option",
 		}
 	}
 
@@ -133,6 +711,182 @@ func (s *linkService) validateSuffixOption(suffix models.Suffix) (bool, *models.
 	return true, nil
 }
 
+// validateCustomPath checks that a caller supplied vanity path is a single segment
+// of URL-safe characters and at least TenantConfig.MinCustomPathLength long.
+func validateCustomPath(customPath string, tenantCfg TenantConfig) error {
+	minLength := tenantCfg.MinCustomPathLength
+	if minLength <= 0 {
+		minLength = defaultMinCustomPathLength
+	}
+	if len(customPath) < minLength {
+		return ErrCustomPathTooShort
+	}
+	if !customPathPattern.MatchString(customPath) {
+		return ErrInvalidCustomPath
+	}
+	return nil
+}
+
+// createOptions carries stored columns that are not part of models.DurableLink.
+type createOptions struct {
+	rawRequest     *string
+	passwordHash   *string
+	maxClicks      *int64
+	pathScope      string
+	dedupIgnoreUTM bool
+}
+
+func (o createOptions) apply(dbLink *models.DurableLinkDB) {
+	dbLink.RawRequest = o.rawRequest
+	dbLink.PasswordHash = o.passwordHash
+	dbLink.MaxClicks = o.maxClicks
+	dbLink.PathScope = o.pathScope
+	dbLink.DedupIgnoreUTM = o.dedupIgnoreUTM
+}
+
+// dedupKey returns the link as compared for de-duplication, without UTM params when the tenant ignores them.
+func dedupKey(link models.DurableLink, tenantCfg TenantConfig) models.DurableLink {
+	if tenantCfg.DedupIgnoreUTM {
+		link.AnalyticsInfo.MarketingParameters = models.MarketingParameters{}
+	}
+	return link
+}
+
+// urlProblem describes why u is not usable as a fallback, image or rule link, or returns "" when it is
+// an absolute http or https URL, the same schemes the link itself is limited to.
+func urlProblem(u string) string {
+	if !utils.IsURL(u) {
+		return "is not a valid URL"
+	}
+	if utils.ValidateURLScheme(u) != nil {
+		return "must be an http or https URL"
+	}
+	return ""
+}
+
+// ruleProblem describes what is wrong with rule, or returns "" when it is valid.
+func ruleProblem(rule models.ResolutionRule) string {
+	if problem := urlProblem(rule.Link); problem != "" {
+		return "link " + problem
+	}
+	for _, country := range rule.Countries {
+		if !countryPattern.MatchString(country) {
+			return fmt.Sprintf("has an invalid country code %q", country)
+		}
+	}
+	if rule.TimeWindow != nil {
+		if err := rule.TimeWindow.Validate(); err != nil {
+			return "has an " + err.Error()
+		}
+	}
+	return ""
+}
+
+// checkDomainAllowed returns ErrDomainLinkNotAllowed unless the domain of link is in the tenant's
+// allow list. An empty list allows nothing, or everything with AllowAllDomains. Allowed domains
+// on the tenant's deny list fail with ErrDomainDenied.
+func checkDomainAllowed(tenantCfg TenantConfig, link string) error {
+	if len(tenantCfg.DomainAllowList) == 0 {
+		if !tenantCfg.AllowAllDomains {
+			return ErrEmptyDomainAllowList
+		}
+	} else if !utils.IsDomainAllowed(log.Logger, tenantCfg.DomainAllowList, link) {
+		return ErrDomainLinkNotAllowed
+	}
+	if len(tenantCfg.DomainDenyList) > 0 && utils.IsDomainDenied(log.Logger, tenantCfg.DomainDenyList, link) {
+		return ErrDomainDenied
+	}
+	return nil
+}
+
+// hasPlatformConfig reports whether dl sets any param that makes it behave differently per
+// platform, after defaults were applied and invalid params dropped.
+func hasPlatformConfig(dl models.DurableLink) bool {
+	android := dl.AndroidParameters
+	ios := dl.IosParameters
+	return !isEmptyParam(android.AndroidPackageName) ||
+		!isEmptyParam(android.AndroidFallbackLink) ||
+		len(android.AndroidFallbackLinks) > 0 ||
+		ios.IOSAppStoreId != nil ||
+		!isEmptyParam(ios.IOSFallbackLink) ||
+		len(ios.IOSFallbackLinks) > 0 ||
+		!isEmptyParam(ios.IOSIpadFallbackLink) ||
+		!isEmptyParam(dl.OtherPlatformParameters.FallbackURL)
+}
+
+// crossDomainFallbackWarnings warns about each fallback link of dl whose host differs from the
+// host of dl.Link. Such fallbacks are sometimes intended, e.g. an app store page, so they are kept.
+func crossDomainFallbackWarnings(dl models.DurableLink) []models.Warning {
+	linkHost := urlHost(dl.Link)
+	if linkHost == "" {
+		return nil
+	}
+
+	var warnings []models.Warning
+	check := func(group, jsonFieldName string, fallback *string) {
+		if isEmptyParam(fallback) {
+			return
+		}
+		if host := urlHost(*fallback); host != "" && host != linkHost {
+			warnings = append(warnings, models.Warning{
+				WarningCode:    "CROSS_DOMAIN_FALLBACK",
+				WarningMessage: fmt.Sprintf("Param '%s' points to host '%s', while 'link' points to '%s'", jsonFieldName, host, linkHost),
+				Field:          linkField(group, jsonFieldName),
+			})
+		}
+	}
+
+	android := dl.AndroidParameters
+	check("androidParameters", "androidFallbackLink", android.AndroidFallbackLink)
+	for i, fallback := range android.AndroidFallbackLinks {
+		check("androidParameters", fmt.Sprintf("androidFallbackLinks[%d]", i), &fallback)
+	}
+	ios := dl.IosParameters
+	check("iosParameters", "iosFallbackLink", ios.IOSFallbackLink)
+	for i, fallback := range ios.IOSFallbackLinks {
+		check("iosParameters", fmt.Sprintf("iosFallbackLinks[%d]", i), &fallback)
+	}
+	check("iosParameters", "iosIpadFallbackLink", ios.IOSIpadFallbackLink)
+	check("otherPlatformParameters", "fallbackUrl", dl.OtherPlatformParameters.FallbackURL)
+
+	return warnings
+}
+
+// urlHost returns the normalized hostname of rawURL, or "" when it has none.
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return utils.NormalizeHost(u.Hostname())
+}
+
+// isEmptyParam reports whether an optional string param is unset or empty.
+func isEmptyParam(s *string) bool {
+	return s == nil || *s == ""
+}
+
+// linkField returns the JSON path of a param nested in the request's durableLinkInfo.
+func linkField(path ...string) string {
+	return strings.Join(append([]string{"durableLinkInfo"}, path...), ".")
+}
+
+// malformedParamErrors converts MALFORMED_PARAM warnings into validation errors for strict mode.
+func malformedParamErrors(warnings []models.Warning) []models.ValidationError {
+	var errs []models.ValidationError
+	for _, w := range warnings {
+		if w.WarningCode != "MALFORMED_PARAM" {
+			continue
+		}
+		errs = append(errs, models.ValidationError{
+			Field:   w.Field,
+			Tag:     "malformed_param",
+			Message: w.WarningMessage,
+		})
+	}
+	return errs
+}
+
 func (s *linkService) validateLinkParameters(dl *models.DurableLink) []models.Warning {
 	warnings := []models.Warning{}
 
@@ -141,43 +895,167 @@ func (s *linkService) validateLinkParameters(dl *models.DurableLink) []models.Wa
 			warnings = append(warnings, models.Warning{
 				WarningCode:    "UNRECOGNIZED_PARAM",
 				WarningMessage: fmt.Sprintf("Param '%s' is not needed, since '%s' is not specified.", paramName, missingParam),
+				Field:          linkField("analyticsInfo", "itunesConnectAnalytics", paramName),
 			})
 		}
 	}
 
-	validateAndClearInvalidURL := func(url **string, jsonFieldName string) {
-		if *url != nil && **url != "" && !utils.IsURL(**url) {
+	validateAndClearInvalidURL := func(url **string, group, jsonFieldName string) {
+		if *url == nil || **url == "" {
+			return
+		}
+		if problem := urlProblem(**url); problem != "" {
 			warnings = append(warnings, models.Warning{
 				WarningCode:    "MALFORMED_PARAM",
-				WarningMessage: fmt.Sprintf("Param '%s' is not a valid URL", jsonFieldName),
+				WarningMessage: fmt.Sprintf("Param '%s' %s", jsonFieldName, problem),
+				Field:          linkField(group, jsonFieldName),
 			})
 			// Clear invalid URL - don't save garbage data
 			*url = nil
 		}
 	}
 
-	validateAndClearInvalidURL(&dl.AndroidParameters.AndroidFallbackLink, "androidFallbackLink")
-	validateAndClearInvalidURL(&dl.IosParameters.IOSFallbackLink, "iosFallbackLink")
-	validateAndClearInvalidURL(&dl.IosParameters.IOSIpadFallbackLink, "iosIpadFallbackLink")
-	validateAndClearInvalidURL(&dl.OtherPlatformParameters.FallbackURL, "fallbackUrl")
-	validateAndClearInvalidURL(&dl.SocialMetaTagInfo.SocialImageLink, "socialImageLink")
+	validateAndClearInvalidURL(&dl.AndroidParameters.AndroidFallbackLink, "androidParameters", "androidFallbackLink")
+	validateAndClearInvalidURL(&dl.IosParameters.IOSFallbackLink, "iosParameters", "iosFallbackLink")
+	validateAndClearInvalidURL(&dl.IosParameters.IOSIpadFallbackLink, "iosParameters", "iosIpadFallbackLink")
+	validateAndClearInvalidURL(&dl.OtherPlatformParameters.FallbackURL, "otherPlatformParameters", "fallbackUrl")
+	validateAndClearInvalidURL(&dl.SocialMetaTagInfo.SocialImageLink, "socialMetaTagInfo", "socialImageLink")
 
-	isi := dl.IosParameters.IOSAppStoreId
-	itunes := dl.AnalyticsInfo.ItunesConnectAnalytics
-	pt := itunes.Pt
-
-	if isi == nil {
-		addUnrecognizedWarning("at", itunes.At, "isi")
-		addUnrecognizedWarning("ct", itunes.Ct, "isi")
-		addUnrecognizedWarning("mt", itunes.Mt, "isi")
-		addUnrecognizedWarning("pt", pt, "isi")
+	validateAndDropInvalidURLs := func(urls *[]string, group, jsonFieldName string) {
+		valid := make([]string, 0, len(*urls))
+		for i, u := range *urls {
+			if problem := urlProblem(u); problem != "" {
+				element := fmt.Sprintf("%s[%d]", jsonFieldName, i)
+				warnings = append(warnings, models.Warning{
+					WarningCode:    "MALFORMED_PARAM",
+					WarningMessage: fmt.Sprintf("Param '%s' %s", element, problem),
+					Field:          linkField(group, element),
+				})
+				continue
+			}
+			valid = append(valid, u)
+		}
+		if len(valid) == 0 {
+			valid = nil
+		}
+		*urls = valid
 	}
 
-	if pt == nil || *pt == "" {
-		addUnrecognizedWarning("at", itunes.At, "pt")
-		addUnrecognizedWarning("ct", itunes.Ct, "pt")
-		addUnrecognizedWarning("mt", itunes.Mt, "pt")
-	}
+	validateAndDropInvalidURLs(&dl.AndroidParameters.AndroidFallbackLinks, "androidParameters", "androidFallbackLinks")
+	validateAndDropInvalidURLs(&dl.IosParameters.IOSFallbackLinks, "iosParameters", "iosFallbackLinks")
+
+	if len(dl.Rules) > 0 {
+		valid := make([]models.ResolutionRule, 0, len(dl.Rules))
+		for i, rule := range dl.Rules {
+			if problem := ruleProblem(rule); problem != "" {
+				element := fmt.Sprintf("rules[%d]", i)
+				warnings = append(warnings, models.Warning{
+					WarningCode:    "MALFORMED_PARAM",
+					WarningMessage: fmt.Sprintf("Param '%s' %s", element, problem),
+					Field:          linkField(element),
+				})
+				continue
+			}
+			valid = append(valid, rule)
+		}
+		if len(valid) == 0 {
+			valid = nil
+		}
+		dl.Rules = valid
+	}
+
+	if pkg := dl.AndroidParameters.AndroidPackageName; pkg != nil {
+		trimmed := strings.TrimSpace(*pkg)
+		if androidPackagePattern.MatchString(trimmed) {
+			dl.AndroidParameters.AndroidPackageName = &trimmed
+		} else {
+			warnings = append(warnings, models.Warning{
+				WarningCode:    "MALFORMED_PARAM",
+				WarningMessage: "Param 'androidPackageName' is not a valid Android package name",
+				Field:          linkField("androidParameters", "androidPackageName"),
+			})
+			dl.AndroidParameters.AndroidPackageName = nil
+		}
+	}
+
+	if dl.Locale != nil && !localePattern.MatchString(*dl.Locale) {
+		warnings = append(warnings, models.Warning{
+			WarningCode:    "MALFORMED_PARAM",
+			WarningMessage: "Param 'locale' is not a valid language tag",
+			Field:          linkField("locale"),
+		})
+		dl.Locale = nil
+	}
+
+	if rt := dl.RedirectType; rt != nil {
+		normalized := strings.ToLower(strings.TrimSpace(*rt))
+		if normalized == models.RedirectTypePermanent || normalized == models.RedirectTypeTemporary {
+			dl.RedirectType = &normalized
+		} else {
+			warnings = append(warnings, models.Warning{
+				WarningCode:    "MALFORMED_PARAM",
+				WarningMessage: fmt.Sprintf("Param 'redirectType' must be '%s' or '%s', defaulting to '%s'", models.RedirectTypePermanent, models.RedirectTypeTemporary, models.RedirectTypeTemporary),
+				Field:          linkField("redirectType"),
+			})
+			dl.RedirectType = nil
+		}
+	}
+
+	if delay := dl.InterstitialDelayMs; delay != nil && (*delay < 0 || *delay > maxInterstitialDelayMs) {
+		warnings = append(warnings, models.Warning{
+			WarningCode:    "MALFORMED_PARAM",
+			WarningMessage: fmt.Sprintf("Param 'interstitialDelayMs' must be between 0 and %d", maxInterstitialDelayMs),
+			Field:          linkField("interstitialDelayMs"),
+		})
+		dl.InterstitialDelayMs = nil
+	}
+
+	if title := dl.SocialMetaTagInfo.SocialTitle; title != nil && utf8.RuneCountInString(*title) > maxSocialTitleLength {
+		warnings = append(warnings, models.Warning{
+			WarningCode:    "MALFORMED_PARAM",
+			WarningMessage: fmt.Sprintf("Param 'socialTitle' is longer than %d characters and was truncated", maxSocialTitleLength),
+			Field:          linkField("socialMetaTagInfo", "socialTitle"),
+		})
+		truncated := string([]rune(*title)[:maxSocialTitleLength])
+		dl.SocialMetaTagInfo.SocialTitle = &truncated
+	}
+
+	if dl.IosParameters.IOSAppStoreId != nil && *dl.IosParameters.IOSAppStoreId <= 0 {
+		warnings = append(warnings, models.Warning{
+			WarningCode:    "MALFORMED_PARAM",
+			WarningMessage: "Param 'iosAppStoreId' is not a valid App Store ID",
+			Field:          linkField("iosParameters", "iosAppStoreId"),
+		})
+		dl.IosParameters.IOSAppStoreId = nil
+	}
+
+	isi := dl.IosParameters.IOSAppStoreId
+	itunes := dl.AnalyticsInfo.ItunesConnectAnalytics
+	pt := itunes.Pt
+
+	if isi == nil {
+		addUnrecognizedWarning("at", itunes.At, "isi")
+		addUnrecognizedWarning("ct", itunes.Ct, "isi")
+		addUnrecognizedWarning("mt", itunes.Mt, "isi")
+		addUnrecognizedWarning("pt", pt, "isi")
+	}
+
+	if pt == nil || *pt == "" {
+		addUnrecognizedWarning("at", itunes.At, "pt")
+		addUnrecognizedWarning("ct", itunes.Ct, "pt")
+		addUnrecognizedWarning("mt", itunes.Mt, "pt")
+	}
+
+	// Unknown media types are kept, Apple may add new ones
+	if mt := itunes.Mt; isi != nil && !isEmptyParam(pt) && !isEmptyParam(mt) && !itunesMediaTypes[strings.TrimSpace(*mt)] {
+		warnings = append(warnings, models.Warning{
+			WarningCode:    "MALFORMED_PARAM",
+			WarningMessage: fmt.Sprintf("Param 'mt' is not a known iTunes media type: %s", *mt),
+			Field:          linkField("analyticsInfo", "itunesConnectAnalytics", "mt"),
+		})
+	}
+
+	warnings = append(warnings, s.deprecatedParamWarnings(dl)...)
 
 	return warnings
 }
@@ -187,17 +1065,26 @@ func (s *linkService) createOrGetShortLink(
 	host string,
 	link models.DurableLink,
 	shortPath bool,
+	opts createOptions,
 	projectID *uuid.UUID,
 	tenantCfg TenantConfig,
 ) (*models.ShortLinkResponse, error) {
-	if shortPath {
-		if path, err := s.repo.FindExistingShortLink(ctx, host, &link, projectID); err == nil {
-			full := fmt.Sprintf("%s://%s/%s", tenantCfg.URLScheme, host, path)
+	// Password protected and click limited links are never shared with other requests
+	shareable := opts.passwordHash == nil && opts.maxClicks == nil
+	key := dedupKey(link, tenantCfg)
+	if shortPath && shareable && s.uniquenessFilter.MightContain(uniquenessKey(host, key, projectID)) {
+		var createdAfter time.Time
+		if tenantCfg.MaxReuseAge > 0 {
+			createdAfter = time.Now().Add(-tenantCfg.MaxReuseAge)
+		}
+		if path, err := s.repo.FindExistingShortLink(ctx, host, &key, projectID, createdAfter); err == nil {
+			resp := newShortLinkResponse(tenantCfg, host, path)
 			log.Debug().
 				Str("path", path).
 				Str("link", link.Link).
 				Msg("Re-using existing short link")
-			return &models.ShortLinkResponse{ShortLink: full, Warnings: []models.Warning{}}, nil
+			resp.Reused = true
+			return resp, nil
 
 		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Error().
@@ -211,7 +1098,6 @@ func (s *linkService) createOrGetShortLink(
 	if !shortPath {
 		length = tenantCfg.UnguessablePathLength
 	}
-	path := generateDurableLinkPath(length)
 
 	var projectIDStr *string
 	if projectID != nil {
@@ -219,55 +1105,674 @@ func (s *linkService) createOrGetShortLink(
 		projectIDStr = &idStr
 	}
 
+	// Links that are never shared get random paths: their HMAC path would lead to the shared link
+	// of the same target, and repeated creates would use up the deterministic candidates
+	if tenantCfg.PathStrategy == PathStrategyHMACDeterministic && shareable {
+		return s.createDeterministicLink(ctx, host, link, shortPath, length, opts, projectID, projectIDStr, tenantCfg)
+	}
+
+	// Checked before picking a path, so a recycled path is not popped for nothing
+	if err := s.checkLinkQuota(ctx, projectID, tenantCfg); err != nil {
+		return nil, err
+	}
+	path, err := s.findRandomPath(ctx, host, length, projectID, tenantCfg)
+	if err != nil {
+		return nil, err
+	}
+
 	dbLink := models.FromDurableLink(link, host, path, !shortPath, projectIDStr)
+	opts.apply(dbLink)
 	if err := s.repo.CreateShortLink(ctx, dbLink, projectID); err != nil {
 		return nil, fmt.Errorf("failed to store link: %w", err)
 	}
+	if shortPath && shareable {
+		s.uniquenessFilter.Add(uniquenessKey(host, key, projectID))
+	}
 
-	full := fmt.Sprintf("%s://%s/%s", tenantCfg.URLScheme, host, path)
+	resp := newShortLinkResponse(tenantCfg, host, path)
 	log.Debug().
 		Str("path", path).
 		Str("link", link.Link).
 		Msg("New link stored in database")
 
-	return &models.ShortLinkResponse{ShortLink: full, Warnings: []models.Warning{}}, nil
+	return resp, nil
 }
 
-func (s *linkService) ResolveShortPath(ctx context.Context, rawURL string, projectID *uuid.UUID, tenantCfg TenantConfig) (*models.LongLinkResponse, error) {
-	u, err := url.Parse(rawURL)
+// checkLinkQuota fails with ErrQuotaExceeded when projectID already holds tenantCfg.MaxLinks links.
+// Reused links don't count against the quota, so it is checked only right before an insert.
+// Concurrent creates may each pass the check, letting a project overshoot the quota slightly.
+func (s *linkService) checkLinkQuota(ctx context.Context, projectID *uuid.UUID, tenantCfg TenantConfig) error {
+	if tenantCfg.MaxLinks == nil {
+		return nil
+	}
+
+	count, err := s.repo.CountLinks(ctx, projectID)
 	if err != nil {
-		return nil, ErrInvalidRequestedLink
+		return fmt.Errorf("failed to count links: %w", err)
+	}
+	if count >= *tenantCfg.MaxLinks {
+		log.Warn().
+			Int64("count", count).
+			Int64("max_links", *tenantCfg.MaxLinks).
+			Msg("Link quota exceeded")
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// findRandomPath asks the path generator for a path that is not yet used on host,
+// retrying a bounded number of times on collision.
+func (s *linkService) findRandomPath(ctx context.Context, host string, length int, projectID *uuid.UUID, tenantCfg TenantConfig) (string, error) {
+	if tenantCfg.RecyclePaths {
+		path, err := s.popReleasedPath(ctx, host, length, projectID, tenantCfg)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, repository.ErrNoReleasedPath) {
+			return "", err
+		}
+	}
+
+	for attempt := range maxPathAttempts {
+		path, err := s.generatorFor(tenantCfg.PathCase).Generate(length)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate path: %w", err)
+		}
+		path = appendPathChecksum(tenantCfg.PathCase.apply(path), tenantCfg)
+
+		available, err := s.isPathAvailable(ctx, host, path, projectID, tenantCfg)
+		if err != nil {
+			return "", err
+		}
+		if available {
+			return path, nil
+		}
+
+		log.Warn().
+			Str("path", path).
+			Int("attempt", attempt).
+			Msg("Generated path collision, retrying")
 	}
 
-	normalizedHost := removePreviewFromHost(u.Host)
+	return "", ErrPathCollision
+}
+
+// generatorFor returns the generator of paths in pathCase. The default generator draws from the
+// letters of pathCase only, paths of a custom generator are folded to it after generation.
+func (s *linkService) generatorFor(pathCase PathCase) PathGenerator {
+	if _, ok := s.pathGenerator.(randomPathGenerator); ok {
+		return randomPathGenerator{alphabet: pathCase.alphabet()}
+	}
+	return s.pathGenerator
+}
 
-	pathParts := strings.Split(strings.Trim(u.Path, "/"), "/")
-	if len(pathParts) != 1 || pathParts[0] == "" {
-		return nil, ErrInvalidPathFormat
+// popReleasedPath takes a path of the given generated length from the pool of released paths,
+// skipping paths that have been taken again in the meantime.
+func (s *linkService) popReleasedPath(ctx context.Context, host string, length int, projectID *uuid.UUID, tenantCfg TenantConfig) (string, error) {
+	if tenantCfg.PathChecksum {
+		length++
 	}
 
-	return s.getLongLinkFromHostAndPath(ctx, normalizedHost, pathParts[0], projectID)
+	for range maxPathAttempts {
+		// Paths released under another path case stay in the pool for tenants of their case
+		path, err := s.repo.PopReleasedPath(ctx, host, length, tenantCfg.PathCase.releasedPathCase())
+		if err != nil {
+			return "", err
+		}
+
+		available, err := s.isPathAvailable(ctx, host, path, projectID, tenantCfg)
+		if err != nil {
+			return "", err
+		}
+		if available {
+			log.Debug().
+				Str("path", path).
+				Msg("Re-using released path")
+			return path, nil
+		}
+	}
+
+	return "", repository.ErrNoReleasedPath
 }
 
-func generateDurableLinkPath(length int) string {
-	const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	_, err := rand.Read(b)
+// DeleteLink deletes the link rawURL points to. Resolving the path afterwards fails with
+// repository.ErrLinkDeleted until a new link takes it. With TenantConfig.RecyclePaths its path
+// is released for new links to use.
+func (s *linkService) DeleteLink(ctx context.Context, rawURL string, projectID *uuid.UUID, tenantCfg TenantConfig) error {
+	tenantCfg = resolveTenantConfig(ctx, tenantCfg)
+	host, path, err := parseShortURL(rawURL, tenantCfg)
 	if err != nil {
-		log.Panic().Err(err).Msg("Failed to generate random bytes")
+		return err
+	}
+
+	err = s.repo.DeleteLink(ctx, host, path, projectID, tenantCfg.RecyclePaths)
+	if folded := tenantCfg.PathCase.apply(path); errors.Is(err, repository.ErrLinkNotFound) && folded != path {
+		return s.repo.DeleteLink(ctx, host, folded, projectID, tenantCfg.RecyclePaths)
+	}
+	return err
+}
+
+// isPathAvailable reports whether path is free on host within the tenant's PathUniquenessScope.
+func (s *linkService) isPathAvailable(ctx context.Context, host, path string, projectID *uuid.UUID, tenantCfg TenantConfig) (bool, error) {
+	if tenantCfg.PathUniquenessScope == PathUniquenessPerProject {
+		return s.repo.IsPathAvailableInProject(ctx, host, path, projectID)
+	}
+	return s.repo.IsPathAvailable(ctx, host, path)
+}
+
+// pathScope returns the models.DurableLinkDB.PathScope of links created for projectID.
+func pathScope(projectID *uuid.UUID, tenantCfg TenantConfig) string {
+	if tenantCfg.PathUniquenessScope != PathUniquenessPerProject || projectID == nil {
+		return ""
+	}
+	return projectID.String()
+}
+
+// createDeterministicLink stores link at its HMAC path, or re-uses the link already stored there.
+// The path is only inserted while free, so when a concurrent create of the same link takes it
+// first, that link is re-used instead of failing the create.
+func (s *linkService) createDeterministicLink(
+	ctx context.Context,
+	host string,
+	link models.DurableLink,
+	shortPath bool,
+	length int,
+	opts createOptions,
+	projectID *uuid.UUID,
+	projectIDStr *string,
+	tenantCfg TenantConfig,
+) (*models.ShortLinkResponse, error) {
+	paramsHash := deterministicParamsHash(link, tenantCfg)
+	for range maxPathAttempts {
+		path, reused, err := s.findDeterministicPath(ctx, host, link, length, projectID, tenantCfg)
+		if err != nil {
+			return nil, err
+		}
+		if !reused {
+			if err := s.checkLinkQuota(ctx, projectID, tenantCfg); err != nil {
+				return nil, err
+			}
+			dbLink := models.FromDurableLink(link, host, path, !shortPath, projectIDStr)
+			opts.apply(dbLink)
+			err = s.repo.CreateLinkIfPathFree(ctx, dbLink, projectID)
+			if err == nil {
+				if shortPath {
+					s.uniquenessFilter.Add(uniquenessKey(host, dedupKey(link, tenantCfg), projectID))
+				}
+				log.Debug().
+					Str("path", path).
+					Str("link", link.Link).
+					Msg("New link stored in database")
+				return newShortLinkResponse(tenantCfg, host, path), nil
+			}
+			if !errors.Is(err, repository.ErrPathTaken) {
+				return nil, fmt.Errorf("failed to store link: %w", err)
+			}
+
+			// Taken since it was checked, re-use the link if a concurrent create stored the same one
+			reused, err = s.storesDeterministicLink(ctx, host, path, link, paramsHash, projectID, tenantCfg)
+			if err != nil {
+				return nil, err
+			}
+			if !reused {
+				continue
+			}
+		}
+
+		resp := newShortLinkResponse(tenantCfg, host, path)
+		log.Debug().
+			Str("path", path).
+			Str("link", link.Link).
+			Msg("Re-using existing deterministic link")
+		resp.Reused = true
+		return resp, nil
 	}
 
-	for i := range b {
-		b[i] = alphanumeric[b[i]%byte(len(alphanumeric))]
+	return nil, ErrPathCollision
+}
+
+// findDeterministicPath derives the HMAC path for link, retrying with a new attempt counter
+// when the derived path is already taken by a different, protected or click limited link. It
+// reports reused=true when the path already stores this exact link. Paths are derived with the
+// newest secret, so links made before a rotation keep resolving but are no longer reused.
+func (s *linkService) findDeterministicPath(
+	ctx context.Context,
+	host string,
+	link models.DurableLink,
+	length int,
+	projectID *uuid.UUID,
+	tenantCfg TenantConfig,
+) (string, bool, error) {
+	secret, ok := tenantCfg.signingSecret()
+	if !ok {
+		return "", false, ErrMissingTenantSecret
+	}
+
+	paramsHash := deterministicParamsHash(link, tenantCfg)
+	scope := host
+	if projectID != nil {
+		scope = projectID.String() + "/" + host
 	}
 
-	id := string(b)
+	for attempt := range maxPathAttempts {
+		path := generateDeterministicPath(secret.Key, scope, link.Link, paramsHash, attempt, length)
+		path = appendPathChecksum(tenantCfg.PathCase.apply(path), tenantCfg)
+
+		// Checked like random paths, so paths of other projects count as taken too
+		available, err := s.isPathAvailable(ctx, host, path, projectID, tenantCfg)
+		if err != nil {
+			return "", false, err
+		}
+		if available {
+			return path, false, nil
+		}
+
+		reusable, err := s.storesDeterministicLink(ctx, host, path, link, paramsHash, projectID, tenantCfg)
+		if err != nil {
+			return "", false, err
+		}
+		if reusable {
+			return path, true, nil
+		}
+
+		log.Warn().
+			Str("path", path).
+			Int("attempt", attempt).
+			Msg("Deterministic path collision, retrying")
+	}
+
+	return "", false, ErrPathCollision
+}
+
+// storesDeterministicLink reports whether the link at path on host can be re-used for link: it
+// has the same target and params hash, and is neither password protected nor click limited.
+func (s *linkService) storesDeterministicLink(
+	ctx context.Context,
+	host, path string,
+	link models.DurableLink,
+	paramsHash string,
+	projectID *uuid.UUID,
+	tenantCfg TenantConfig,
+) (bool, error) {
+	existing, err := s.repo.GetLinkDBByHostAndPath(ctx, host, path, projectID)
+	if errors.Is(err, repository.ErrLinkNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if existing.PasswordHash != nil || existing.MaxClicks != nil {
+		return false, nil
+	}
+	return existing.Link == link.Link && deterministicParamsHash(existing.ToDurableLink(), tenantCfg) == paramsHash, nil
+}
 
+// deterministicParamsHash hashes the params of link the way its HMAC path is derived from.
+func deterministicParamsHash(link models.DurableLink, tenantCfg TenantConfig) string {
+	return models.FromDurableLink(dedupKey(link, tenantCfg), "", "", false, nil).ComputeParamsHash()
+}
+
+func (s *linkService) createCustomPathLink(
+	ctx context.Context,
+	host string,
+	link models.DurableLink,
+	customPath string,
+	opts createOptions,
+	projectID *uuid.UUID,
+	tenantCfg TenantConfig,
+) (*models.ShortLinkResponse, error) {
+	available, err := s.isPathAvailable(ctx, host, customPath, projectID, tenantCfg)
+	if err != nil {
+		return nil, err
+	}
+	if !available {
+		return nil, ErrCustomPathTaken
+	}
+	if err := s.checkLinkQuota(ctx, projectID, tenantCfg); err != nil {
+		return nil, err
+	}
+
+	var projectIDStr *string
+	if projectID != nil {
+		idStr := projectID.String()
+		projectIDStr = &idStr
+	}
+
+	dbLink := models.FromDurableLink(link, host, customPath, false, projectIDStr)
+	opts.apply(dbLink)
+	// The availability check above can race with another create of the same path
+	if err := s.repo.CreateLinkIfPathFree(ctx, dbLink, projectID); err != nil {
+		if errors.Is(err, repository.ErrPathTaken) {
+			return nil, ErrCustomPathTaken
+		}
+		return nil, fmt.Errorf("failed to store link: %w", err)
+	}
+	// Custom paths are re-used for matching short link requests too
+	if opts.passwordHash == nil && opts.maxClicks == nil {
+		s.uniquenessFilter.Add(uniquenessKey(host, dedupKey(link, tenantCfg), projectID))
+	}
+
+	resp := newShortLinkResponse(tenantCfg, host, customPath)
 	log.Debug().
-		Str("short_code", id).
-		Msg("Generated alphanumeric short ID")
+		Str("path", customPath).
+		Str("link", link.Link).
+		Msg("New custom path link stored in database")
+
+	return resp, nil
+}
+
+// ParseLongDurableLink turns a Firebase style long link, e.g.
+// "https://example.page.link/?link=https://example.com&apn=com.example", into a create request
+// for its host. Params are mapped with models.DurableLinkFromParams, whose warnings are
+// dropped; callers that need them should call it directly.
+func (s *linkService) ParseLongDurableLink(longLink string) (models.CreateDurableLinkRequest, error) {
+	u, err := url.Parse(longLink)
+	if err != nil || u.Host == "" {
+		return models.CreateDurableLinkRequest{}, ErrInvalidRequestedLink
+	}
+
+	params := make(map[string]string)
+	for key, values := range u.Query() {
+		params[key] = values[0]
+	}
+
+	dl, _ := models.DurableLinkFromParams(params)
+	if dl.Link == "" {
+		return models.CreateDurableLinkRequest{}, fmt.Errorf("%w: missing 'link' param", ErrInvalidRequestedLink)
+	}
+	dl.Host = u.Host
+
+	return models.CreateDurableLinkRequest{DurableLinkInfo: dl}, nil
+}
+
+func (s *linkService) ResolveShortPath(ctx context.Context, rawURL string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.LongLinkResponse, error) {
+	host, path, sig, err := splitShortURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resolveHostPath(ctx, host, path, sig, projectID, tenantCfg, newResolveOptions(opts))
+}
+
+// ResolveHostPath resolves a short link whose host and path were already split, e.g. by a
+// router, like ResolveShortPath would resolve the URL made of them. There is no query to carry
+// a signature, so links of tenants with RequireSignature must be resolved with ResolveShortPath.
+func (s *linkService) ResolveHostPath(ctx context.Context, host, path string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.LongLinkResponse, error) {
+	return s.resolveHostPath(ctx, host, path, "", projectID, tenantCfg, newResolveOptions(opts))
+}
+
+func (s *linkService) resolveHostPath(ctx context.Context, host, path, sig string, projectID *uuid.UUID, tenantCfg TenantConfig, opts resolveOptions) (*models.LongLinkResponse, error) {
+	tenantCfg = resolveTenantConfig(ctx, tenantCfg)
+	host, path, err := parseHostPath(host, path, sig, tenantCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getLongLinkFromHostAndPath(ctx, host, path, projectID, tenantCfg.PathCase, opts)
+}
+
+// ResolveOrFallback resolves rawURL like ResolveShortPath. When the link does not exist or is
+// gone, e.g. after reaching its click limit, and the tenant has a NotFoundFallbackURL, that URL
+// is returned with found=false instead of an error.
+func (s *linkService) ResolveOrFallback(ctx context.Context, rawURL string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (string, bool, error) {
+	tenantCfg = resolveTenantConfig(ctx, tenantCfg)
+	resp, err := s.ResolveShortPath(ctx, rawURL, projectID, tenantCfg, opts...)
+	if err == nil {
+		return resp.LongLink, true, nil
+	}
+
+	if (errors.Is(err, repository.ErrLinkNotFound) || errors.Is(err, repository.ErrLinkGone)) && tenantCfg.NotFoundFallbackURL != nil {
+		log.Debug().
+			Err(err).
+			Str("url", rawURL).
+			Str("fallback", *tenantCfg.NotFoundFallbackURL).
+			Msg("Link not resolvable, using tenant fallback")
+		return *tenantCfg.NotFoundFallbackURL, false, nil
+	}
+
+	return "", false, err
+}
+
+// ResolveProtected resolves rawURL like ResolveShortPath, supplying the password for
+// password protected links. Links without a password resolve regardless of password.
+func (s *linkService) ResolveProtected(ctx context.Context, rawURL, password string, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.LongLinkResponse, error) {
+	tenantCfg = resolveTenantConfig(ctx, tenantCfg)
+	host, path, err := parseShortURL(rawURL, tenantCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := s.resolveLink(ctx, host, path, password, projectID, tenantCfg.PathCase, newResolveOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return newLongLinkResponse(link, link.ToDurableLink().TargetFor(resolveRequestContext(ctx))), nil
+}
+
+// ResolveCandidates returns the ordered, de-duplicated URLs a client on platform should
+// try for the short link, ending with the target its rules pick for the request on ctx.
+func (s *linkService) ResolveCandidates(ctx context.Context, rawURL string, platform models.Platform, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) ([]string, error) {
+	tenantCfg = resolveTenantConfig(ctx, tenantCfg)
+	host, path, err := parseShortURL(rawURL, tenantCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := s.resolveLink(ctx, host, path, "", projectID, tenantCfg.PathCase, newResolveOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return resolvedDurableLink(ctx, link).CandidateURLs(platform), nil
+}
+
+// ResolveForPlatform returns the URL a client on platform is served for the short link, the
+// first of its candidates, and reports which field of the link it came from.
+func (s *linkService) ResolveForPlatform(ctx context.Context, rawURL string, platform models.Platform, projectID *uuid.UUID, tenantCfg TenantConfig, opts ...ResolveOption) (*models.PlatformLinkResponse, error) {
+	tenantCfg = resolveTenantConfig(ctx, tenantCfg)
+	host, path, err := parseShortURL(rawURL, tenantCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := s.resolveLink(ctx, host, path, "", projectID, tenantCfg.PathCase, newResolveOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	dl := resolvedDurableLink(ctx, link)
+	resp := &models.PlatformLinkResponse{LongLink: dl.Link, Source: models.LinkSourceCanonical}
+	if candidates := dl.Candidates(platform); len(candidates) > 0 {
+		resp.LongLink = candidates[0].URL
+		resp.Source = candidates[0].Source
+	}
+	return resp, nil
+}
+
+// resolvedDurableLink returns link with its canonical target replaced by the target its rules
+// pick for the request on ctx, so platform candidates end with what a plain resolve serves.
+func resolvedDurableLink(ctx context.Context, link *models.DurableLinkDB) models.DurableLink {
+	dl := link.ToDurableLink()
+	dl.Link = dl.TargetFor(resolveRequestContext(ctx))
+	return dl
+}
+
+// newShortLinkResponse builds the create response for path on host, including its components.
+func newShortLinkResponse(tenantCfg TenantConfig, host, path string) *models.ShortLinkResponse {
+	full := buildShortLink(tenantCfg, host, path)
+	return &models.ShortLinkResponse{
+		ShortLink:   full,
+		PreviewLink: buildPreviewLink(tenantCfg, host, full),
+		Details: &models.ShortLinkDetails{
+			Scheme: tenantCfg.URLScheme,
+			Host:   host,
+			Path:   path,
+			URL:    full,
+		},
+		Warnings: []models.Warning{},
+	}
+}
+
+// buildShortLink formats the public short link for path on host, including the tenant's path prefix
+// and suffix and, when required, the link signature.
+func buildShortLink(tenantCfg TenantConfig, host, path string) string {
+	signature := ""
+	if secret, ok := tenantCfg.signingSecret(); tenantCfg.RequireSignature && ok {
+		signature = "?" + signatureParam + "=" + url.QueryEscape(signPath(secret, host, path))
+	}
+	if prefix := strings.Trim(tenantCfg.PathPrefix, "/"); prefix != "" {
+		path = prefix + "/" + path
+	}
+	return fmt.Sprintf("%s://%s/%s%s%s", tenantCfg.URLScheme, host, path, tenantCfg.PathSuffix, signature)
+}
+
+// NormalizeShortURL returns the host and path a resolve of rawURL looks the link up by, without
+// touching the database, to help verify a domain setup. It applies the same preview, path prefix,
+// checksum, signature and host alias handling as ResolveShortPath. The path keeps its case, a
+// resolve that does not find it retries it folded to the tenant's PathCase.
+func NormalizeShortURL(rawURL string, tenantCfg TenantConfig) (host, path string, err error) {
+	return parseShortURL(rawURL, tenantCfg)
+}
+
+// buildPreviewLink formats the preview link of full, the short link on host: on the preview
+// subdomain, which resolves strip again, or with the tenant's preview query param.
+func buildPreviewLink(tenantCfg TenantConfig, host, full string) string {
+	if tenantCfg.PreviewSubdomain {
+		// Swapped into the built link, so a signature still covers the stored host
+		return strings.Replace(full, "://"+host, "://preview."+host, 1)
+	}
+
+	param := tenantCfg.PreviewQueryParam
+	if param == "" {
+		param = defaultPreviewQueryParam
+	}
+	if strings.Contains(full, "?") {
+		return full + "&" + param
+	}
+	return full + "?" + param
+}
+
+// parseShortURL extracts the normalized host and the single path segment from a short link
+// with parseHostPath.
+func parseShortURL(rawURL string, tenantCfg TenantConfig) (string, string, error) {
+	host, path, sig, err := splitShortURL(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	return parseHostPath(host, path, sig, tenantCfg)
+}
+
+// splitShortURL parses rawURL into its host, decoded path and signature. Percent-encoded
+// slashes and backslashes are rejected, once decoded they would split or join path segments.
+func splitShortURL(rawURL string) (host, path, sig string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", "", ErrInvalidRequestedLink
+	}
+
+	escaped := strings.ToLower(u.EscapedPath())
+	if strings.Contains(escaped, "%2f") || strings.Contains(escaped, "%5c") {
+		return "", "", "", ErrInvalidPathFormat
+	}
+
+	return u.Host, u.Path, u.Query().Get(signatureParam), nil
+}
+
+// parseHostPath normalizes host and extracts the single path segment from path, stripping
+// the tenant's path prefix and suffix. sig is the link signature, checked when the tenant requires one.
+func parseHostPath(host, path, sig string, tenantCfg TenantConfig) (string, string, error) {
+	// Hosts are stored lowercased by utils.CleanHost, so they are looked up lowercased too
+	normalizedHost := removePreviewFromHost(utils.NormalizeHost(host))
+
+	pathParts := pathSegments(path)
+	// Dot segments are never valid link paths, a traversal must not fall through to a lookup
+	if slices.Contains(pathParts, ".") || slices.Contains(pathParts, "..") {
+		return "", "", ErrInvalidPathFormat
+	}
+	for _, prefixPart := range pathSegments(tenantCfg.PathPrefix) {
+		if len(pathParts) == 0 || pathParts[0] != prefixPart {
+			return "", "", ErrInvalidPathFormat
+		}
+		pathParts = pathParts[1:]
+	}
+
+	if len(pathParts) != 1 {
+		return "", "", ErrInvalidPathFormat
+	}
+	if tenantCfg.PathSuffix != "" {
+		pathParts[0] = strings.TrimSuffix(pathParts[0], tenantCfg.PathSuffix)
+		if pathParts[0] == "" {
+			return "", "", ErrInvalidPathFormat
+		}
+	}
+	// The path is returned as given, lookups fall back to folding it to the tenant's path case.
+	// Checks accept either form, as links created before the path case was set keep theirs.
+	folded := tenantCfg.PathCase.apply(pathParts[0])
+	if tenantCfg.PathChecksum && !validPathChecksum(pathParts[0], PathCaseMixed) && !validPathChecksum(folded, tenantCfg.PathCase) {
+		return "", "", ErrPathChecksumMismatch
+	}
+	if tenantCfg.RequireSignature {
+		secrets := tenantCfg.verificationSecrets()
+		if len(secrets) == 0 {
+			return "", "", ErrMissingTenantSecret
+		}
+		if !validSignature(secrets, normalizedHost, pathParts[0], sig) && !validSignature(secrets, normalizedHost, folded, sig) {
+			return "", "", ErrInvalidSignature
+		}
+	}
+
+	if stored, ok := aliasedHost(tenantCfg, utils.NormalizeHost(normalizedHost)); ok {
+		normalizedHost = stored
+	}
+
+	return normalizedHost, pathParts[0], nil
+}
+
+// aliasedHost returns the host that links on the short domain alias are stored under, and
+// false when alias is not one of the tenant's HostAliases.
+func aliasedHost(tenantCfg TenantConfig, alias string) (string, bool) {
+	for a, host := range tenantCfg.HostAliases {
+		if utils.NormalizeHost(a) == alias {
+			return utils.NormalizeHost(host), true
+		}
+	}
+	return "", false
+}
+
+// pathSegments splits a URL path on "/", ignoring empty segments from repeated,
+// leading or trailing slashes.
+func pathSegments(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+}
+
+// generateDeterministicPath derives a base62 path of the given length from
+// HMAC-SHA256(secret, scope+link+paramsHash+attempt).
+func generateDeterministicPath(secret, scope, link, paramsHash string, attempt, length int) string {
+	const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	base := big.NewInt(int64(len(alphanumeric)))
+
+	b := make([]byte, 0, length)
+	for block := uint32(0); len(b) < length; block++ {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(scope))
+		mac.Write([]byte{0})
+		mac.Write([]byte(link))
+		mac.Write([]byte{0})
+		mac.Write([]byte(paramsHash))
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(attempt)))
+		mac.Write(binary.BigEndian.AppendUint32(nil, block))
+
+		n := new(big.Int).SetBytes(mac.Sum(nil))
+		mod := new(big.Int)
+		for n.Sign() > 0 && len(b) < length {
+			n.DivMod(n, base, mod)
+			b = append(b, alphanumeric[mod.Int64()])
+		}
+	}
 
-	return id
+	return string(b)
 }
 
 func removePreviewFromHost(host string) string {
@@ -1,14 +1,19 @@
 package service
 
-
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/apppanel/durablelinks-core/models"
 	"github.com/apppanel/durablelinks-core/repository"
+	"github.com/google/uuid"
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -21,6 +26,10 @@ func int64Ptr(i int64) *int64 {
 	return &i
 }
 
+func intPtr(i int) *int {
+	return &i
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -44,7 +53,7 @@ func setupTestService(t *testing.T) (*linkService, *gorm.DB) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&models.DurableLinkDB{})
+	err = db.AutoMigrate(&models.DurableLinkDB{}, &models.ReleasedPathDB{}, &models.DailyClicksDB{}, &models.LinkTagDB{}, &models.DeletedPathDB{})
 	require.NoError(t, err)
 
 	repo := repository.NewLinkRepository(db)
@@ -355,6 +364,138 @@ func TestCreateDurableLink_Warnings(t *testing.T) {
 			},
 			expectedWarnings: []models.Warning{},
 		},
+		{
+			name: "ftp fallback link should warn",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: "https://example.com/target",
+					OtherPlatformParameters: models.OtherPlatformParameters{
+						FallbackURL: stringPtr("ftp://files.example.com/app"),
+					},
+				},
+				Suffix: models.Suffix{
+					Option: "UNGUESSABLE",
+				},
+			},
+			expectedWarnings: []models.Warning{
+				{
+					WarningCode:    "MALFORMED_PARAM",
+					WarningMessage: "Param 'fallbackUrl' must be an http or https URL",
+				},
+			},
+		},
+		{
+			name: "javascript fallback links should warn",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: "https://example.com/target",
+					IosParameters: models.IOSParameters{
+						IOSFallbackLink:  stringPtr("javascript:alert(1)"),
+						IOSFallbackLinks: []string{"https://example.com/ok", "javascript://example.com/%0Aalert(1)"},
+					},
+				},
+				Suffix: models.Suffix{
+					Option: "UNGUESSABLE",
+				},
+			},
+			expectedWarnings: []models.Warning{
+				{
+					WarningCode:    "MALFORMED_PARAM",
+					WarningMessage: "Param 'iosFallbackLink' is not a valid URL",
+				},
+				{
+					WarningCode:    "MALFORMED_PARAM",
+					WarningMessage: "Param 'iosFallbackLinks[1]' must be an http or https URL",
+				},
+			},
+		},
+		{
+			name: "unknown itunes media type should warn",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: "https://example.com/target",
+					IosParameters: models.IOSParameters{
+						IOSAppStoreId: int64Ptr(123456789),
+					},
+					AnalyticsInfo: models.AnalyticsInfo{
+						ItunesConnectAnalytics: models.ITunesConnectAnalytics{
+							Pt: stringPtr("provider_token"),
+							Mt: stringPtr("99"),
+						},
+					},
+				},
+				Suffix: models.Suffix{
+					Option: "UNGUESSABLE",
+				},
+			},
+			expectedWarnings: []models.Warning{
+				{
+					WarningCode:    "MALFORMED_PARAM",
+					WarningMessage: "Param 'mt' is not a known iTunes media type: 99",
+				},
+			},
+		},
+		{
+			name: "zero ios app store id should warn",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: "https://example.com/target",
+					IosParameters: models.IOSParameters{
+						IOSAppStoreId: int64Ptr(0),
+					},
+				},
+				Suffix: models.Suffix{
+					Option: "UNGUESSABLE",
+				},
+			},
+			expectedWarnings: []models.Warning{
+				{
+					WarningCode:    "MALFORMED_PARAM",
+					WarningMessage: "Param 'iosAppStoreId' is not a valid App Store ID",
+				},
+			},
+		},
+		{
+			name: "negative ios app store id should warn",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: "https://example.com/target",
+					IosParameters: models.IOSParameters{
+						IOSAppStoreId: int64Ptr(-1),
+					},
+				},
+				Suffix: models.Suffix{
+					Option: "UNGUESSABLE",
+				},
+			},
+			expectedWarnings: []models.Warning{
+				{
+					WarningCode:    "MALFORMED_PARAM",
+					WarningMessage: "Param 'iosAppStoreId' is not a valid App Store ID",
+				},
+			},
+		},
+		{
+			name: "valid ios app store id should not warn",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: "https://example.com/target",
+					IosParameters: models.IOSParameters{
+						IOSAppStoreId: int64Ptr(123456789),
+					},
+				},
+				Suffix: models.Suffix{
+					Option: "UNGUESSABLE",
+				},
+			},
+			expectedWarnings: []models.Warning{},
+		},
 		{
 			name: "invalid suffix option should warn and default to UNGUESSABLE",
 			params: models.CreateDurableLinkRequest{
@@ -454,6 +595,37 @@ func TestCreateDurableLink_Warnings(t *testing.T) {
 	}
 }
 
+func TestCreateDurableLink_UnknownItunesMediaTypeIsStored(t *testing.T) {
+	service, db := setupTestService(t)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+			IosParameters: models.IOSParameters{
+				IOSAppStoreId: int64Ptr(123456789),
+			},
+			AnalyticsInfo: models.AnalyticsInfo{
+				ItunesConnectAnalytics: models.ITunesConnectAnalytics{
+					Pt: stringPtr("provider_token"),
+					Mt: stringPtr("99"),
+				},
+			},
+		},
+		Suffix: models.Suffix{Option: "UNGUESSABLE"},
+	}
+
+	result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, "durableLinkInfo.analyticsInfo.itunesConnectAnalytics.mt", result.Warnings[0].Field)
+
+	var link models.DurableLinkDB
+	require.NoError(t, db.Where("path = ?", result.Details.Path).First(&link).Error)
+	require.NotNil(t, link.ItunesMt)
+	assert.Equal(t, "99", *link.ItunesMt)
+}
+
 func TestCreateDurableLink_Defaults(t *testing.T) {
 	defaultAppStoreID := int64Ptr(123456789)
 	defaultAndroidPkg := stringPtr("com.example.app")
@@ -599,6 +771,93 @@ func TestCreateDurableLink_Defaults(t *testing.T) {
 	}
 }
 
+func TestCreateDurableLink_DefaultUtmParams(t *testing.T) {
+	cfg := defaultTenantCfg
+	cfg.DefaultUtmSource = stringPtr("app")
+	cfg.DefaultUtmMedium = stringPtr("share")
+
+	mediumOnly := defaultTenantCfg
+	mediumOnly.DefaultUtmMedium = stringPtr("share")
+
+	tests := []struct {
+		name         string
+		marketing    models.MarketingParameters
+		tenantCfg    TenantConfig
+		wantSource   *string
+		wantMedium   *string
+		wantWarnings []string
+	}{
+		{
+			name:         "neither provided gets both defaults",
+			tenantCfg:    cfg,
+			wantSource:   stringPtr("app"),
+			wantMedium:   stringPtr("share"),
+			wantWarnings: []string{"durableLinkInfo.analyticsInfo.marketingParameters.utmSource", "durableLinkInfo.analyticsInfo.marketingParameters.utmMedium"},
+		},
+		{
+			name:         "source without medium gets the default medium",
+			marketing:    models.MarketingParameters{UtmSource: stringPtr("newsletter")},
+			tenantCfg:    cfg,
+			wantSource:   stringPtr("newsletter"),
+			wantMedium:   stringPtr("share"),
+			wantWarnings: []string{"durableLinkInfo.analyticsInfo.marketingParameters.utmMedium"},
+		},
+		{
+			name:       "source and medium are kept",
+			marketing:  models.MarketingParameters{UtmSource: stringPtr("newsletter"), UtmMedium: stringPtr("email")},
+			tenantCfg:  cfg,
+			wantSource: stringPtr("newsletter"),
+			wantMedium: stringPtr("email"),
+		},
+		{
+			name:       "medium without source is kept",
+			marketing:  models.MarketingParameters{UtmMedium: stringPtr("email")},
+			tenantCfg:  cfg,
+			wantMedium: stringPtr("email"),
+		},
+		{
+			name:      "no source and no default source leaves both unset",
+			tenantCfg: mediumOnly,
+		},
+		{
+			name:       "no defaults configured",
+			marketing:  models.MarketingParameters{UtmSource: stringPtr("newsletter")},
+			tenantCfg:  defaultTenantCfg,
+			wantSource: stringPtr("newsletter"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := setupTestService(t)
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host:          "example.com",
+					Link:          "https://example.com/target",
+					AnalyticsInfo: models.AnalyticsInfo{MarketingParameters: tt.marketing},
+				},
+				Suffix: models.Suffix{Option: "SHORT"},
+			}
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, tt.tenantCfg)
+			require.NoError(t, err)
+
+			var fields []string
+			for _, w := range result.Warnings {
+				assert.Equal(t, "DEFAULT_APPLIED", w.WarningCode)
+				fields = append(fields, w.Field)
+			}
+			assert.Equal(t, tt.wantWarnings, fields)
+
+			var stored models.DurableLinkDB
+			require.NoError(t, db.Where("path = ?", result.Details.Path).First(&stored).Error)
+			assert.Equal(t, tt.wantSource, stored.UtmSource)
+			assert.Equal(t, tt.wantMedium, stored.UtmMedium)
+		})
+	}
+}
+
 func TestCreateDurableLink_ReuseExistingShortLink(t *testing.T) {
 	service, db := setupTestService(t)
 
@@ -629,6 +888,65 @@ func TestCreateDurableLink_ReuseExistingShortLink(t *testing.T) {
 	expectedShortLink := "https://example.com/abc123"
 	assert.Equal(t, expectedShortLink, result.ShortLink)
 	assert.Equal(t, 0, len(result.Warnings))
+	assert.True(t, result.Reused)
+}
+
+func TestCreateDurableLink_ReadReplicaLagging(t *testing.T) {
+	service, primary := setupTestService(t)
+	replica, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, replica.AutoMigrate(&models.DurableLinkDB{}, &models.ReleasedPathDB{}, &models.DailyClicksDB{}, &models.DeletedPathDB{}))
+	service.repo = repository.NewLinkRepositoryWithReplica(primary, replica)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "SHORT",
+		},
+	}
+
+	// The replica never sees the first link, so reuse proves the dedupe lookup read the primary
+	first, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	second, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	assert.Equal(t, first.ShortLink, second.ShortLink)
+	assert.True(t, second.Reused)
+
+	// Resolves are served by the replica
+	_, err = service.ResolveShortPath(context.Background(), first.ShortLink, nil, defaultTenantCfg)
+	assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+}
+
+func TestParseLongDurableLink(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	t.Run("maps params", func(t *testing.T) {
+		req, err := service.ParseLongDurableLink("https://example.page.link/?link=https%3A%2F%2Fexample.com%2Ftarget&apn=com.example.app&isi=123456789&efr=1")
+		require.NoError(t, err)
+		assert.Equal(t, "example.page.link", req.DurableLinkInfo.Host)
+		assert.Equal(t, "https://example.com/target", req.DurableLinkInfo.Link)
+		assert.Equal(t, stringPtr("com.example.app"), req.DurableLinkInfo.AndroidParameters.AndroidPackageName)
+		assert.Equal(t, int64Ptr(123456789), req.DurableLinkInfo.IosParameters.IOSAppStoreId)
+	})
+
+	tests := []struct {
+		name     string
+		longLink string
+	}{
+		{name: "missing link param", longLink: "https://example.page.link/?apn=com.example.app"},
+		{name: "missing host", longLink: "/?link=https://example.com/target"},
+		{name: "invalid URL", longLink: "not a valid url://"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ParseLongDurableLink(tt.longLink)
+			assert.ErrorIs(t, err, ErrInvalidRequestedLink)
+		})
+	}
 }
 
 func TestResolveShortPath(t *testing.T) {
@@ -677,6 +995,47 @@ func TestResolveShortPath(t *testing.T) {
 			rawURL:      "https://example.com/abc123/extra",
 			expectError: ErrInvalidPathFormat,
 		},
+		{
+			name:       "leading double slash resolves",
+			rawURL:     "https://example.com//abc123",
+			mockPath:   "abc123",
+			mockLink:   "https://example.com/target",
+			expectLink: "https://example.com/target",
+		},
+		{
+			name:       "trailing slash resolves",
+			rawURL:     "https://example.com/abc123/",
+			mockPath:   "abc123",
+			mockLink:   "https://example.com/target",
+			expectLink: "https://example.com/target",
+		},
+		{
+			name:        "multiple segments separated by double slash returns error",
+			rawURL:      "https://example.com/abc123//extra",
+			expectError: ErrInvalidPathFormat,
+		},
+		{
+			name:        "encoded traversal returns error",
+			rawURL:      "https://example.com/%2e%2e%2fadmin",
+			expectError: ErrInvalidPathFormat,
+		},
+		{
+			name:        "encoded slash returns error",
+			rawURL:      "https://example.com/abc123%2F",
+			expectError: ErrInvalidPathFormat,
+		},
+		{
+			name:        "encoded dot segment returns error",
+			rawURL:      "https://example.com/%2e%2e",
+			expectError: ErrInvalidPathFormat,
+		},
+		{
+			name:       "encoded safe path resolves",
+			rawURL:     "https://example.com/%61bc%31%323",
+			mockPath:   "abc123",
+			mockLink:   "https://example.com/target",
+			expectLink: "https://example.com/target",
+		},
 		{
 			name:        "link not found in database",
 			rawURL:      "https://example.com/notfound",
@@ -796,7 +1155,8 @@ func TestGenerateDurableLinkPath(t *testing.T) {
 			const iterations = 1000
 
 			for range iterations {
-				path := generateDurableLinkPath(tt.length)
+				path, err := randomPathGenerator{}.Generate(tt.length)
+				require.NoError(t, err)
 
 				// Test length
 				if len(path) != tt.length {
@@ -838,6 +1198,34 @@ func TestGenerateDurableLinkPath(t *testing.T) {
 	}
 }
 
+func TestCreateAndResolve_MixedCaseHost(t *testing.T) {
+	service, db := setupTestService(t)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "Example.COM",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{Option: "SHORT"},
+	}
+
+	created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", created.Details.Host)
+
+	var stored models.DurableLinkDB
+	require.NoError(t, db.Where("path = ?", created.Details.Path).First(&stored).Error)
+	assert.Equal(t, "example.com", stored.Host)
+
+	for _, host := range []string{"example.com", "Example.COM", "PREVIEW.Example.com"} {
+		t.Run(host, func(t *testing.T) {
+			resolved, err := service.ResolveShortPath(context.Background(), "https://"+host+"/"+created.Details.Path, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/target", resolved.LongLink)
+		})
+	}
+}
+
 func TestRemovePreviewFromHost(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -890,3 +1278,3373 @@ func TestRemovePreviewFromHost(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeShortURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawURL      string
+		tenantCfg   TenantConfig
+		wantHost    string
+		wantPath    string
+		expectError error
+	}{
+		{name: "plain", rawURL: "https://example.com/abc123", wantHost: "example.com", wantPath: "abc123"},
+		{name: "preview prefix", rawURL: "https://preview.example.com/abc123", wantHost: "example.com", wantPath: "abc123"},
+		{name: "hyphenated preview", rawURL: "https://acme-preview.short.link/abc123", wantHost: "acme.short.link", wantPath: "abc123"},
+		{name: "port is kept", rawURL: "http://localhost:8080/abc123", wantHost: "localhost:8080", wantPath: "abc123"},
+		{name: "trailing slash", rawURL: "https://example.com/abc123/", wantHost: "example.com", wantPath: "abc123"},
+		{name: "query is ignored", rawURL: "https://example.com/abc123?utm_source=mail", wantHost: "example.com", wantPath: "abc123"},
+		{
+			name:      "path prefix",
+			rawURL:    "https://example.com/l/abc123",
+			tenantCfg: TenantConfig{PathPrefix: "/l"},
+			wantHost:  "example.com",
+			wantPath:  "abc123",
+		},
+		{
+			name:      "host alias",
+			rawURL:    "https://ex.co/abc123",
+			tenantCfg: TenantConfig{HostAliases: map[string]string{"ex.co": "example.com"}},
+			wantHost:  "example.com",
+			wantPath:  "abc123",
+		},
+		{name: "empty path", rawURL: "https://example.com/", expectError: ErrInvalidPathFormat},
+		{name: "multiple segments", rawURL: "https://example.com/abc123/extra", expectError: ErrInvalidPathFormat},
+		{name: "invalid URL", rawURL: "not a valid url://", expectError: ErrInvalidRequestedLink},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, path, err := NormalizeShortURL(tt.rawURL, tt.tenantCfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHost, host)
+			assert.Equal(t, tt.wantPath, path)
+		})
+	}
+}
+
+func TestGenerateDeterministicPath(t *testing.T) {
+	first := generateDeterministicPath("secret", "example.com", "https://example.com/target", "hash", 0, 8)
+	second := generateDeterministicPath("secret", "example.com", "https://example.com/target", "hash", 0, 8)
+	assert.Equal(t, first, second, "identical inputs must produce identical codes")
+	assert.Len(t, first, 8)
+
+	assert.NotEqual(t, first, generateDeterministicPath("other-secret", "example.com", "https://example.com/target", "hash", 0, 8))
+	assert.NotEqual(t, first, generateDeterministicPath("secret", "example.com", "https://example.com/other", "hash", 0, 8))
+	assert.NotEqual(t, first, generateDeterministicPath("secret", "example.com", "https://example.com/target", "hash", 1, 8))
+
+	long := generateDeterministicPath("secret", "example.com", "https://example.com/target", "hash", 0, 64)
+	assert.Len(t, long, 64)
+	for _, r := range long {
+		assert.True(t, (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'), "invalid character %c", r)
+	}
+}
+
+func TestCreateDurableLink_HMACDeterministic(t *testing.T) {
+	cfg := defaultTenantCfg
+	cfg.PathStrategy = PathStrategyHMACDeterministic
+	cfg.Secret = "tenant-secret"
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "UNGUESSABLE",
+		},
+	}
+
+	t.Run("identical inputs produce identical codes", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		first, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		second, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		assert.Equal(t, first.ShortLink, second.ShortLink)
+		assert.False(t, first.Reused)
+		assert.True(t, second.Reused)
+
+		var count int64
+		db.Model(&models.DurableLinkDB{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("different params produce different codes", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		first, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		other := params
+		other.DurableLinkInfo.AndroidParameters.AndroidPackageName = stringPtr("com.example.app")
+		second, err := service.CreateDurableLink(context.Background(), other, nil, cfg)
+		require.NoError(t, err)
+
+		assert.NotEqual(t, first.ShortLink, second.ShortLink)
+	})
+
+	t.Run("collision with a different link is retried", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		paramsHash := models.FromDurableLink(params.DurableLinkInfo, "", "", false, nil).ComputeParamsHash()
+		taken := generateDeterministicPath(cfg.Secret, "example.com", params.DurableLinkInfo.Link, paramsHash, 0, cfg.UnguessablePathLength)
+		db.Create(&models.DurableLinkDB{
+			Host: "example.com",
+			Path: taken,
+			Link: "https://example.com/someone-else",
+		})
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		retried := generateDeterministicPath(cfg.Secret, "example.com", params.DurableLinkInfo.Link, paramsHash, 1, cfg.UnguessablePathLength)
+		assert.Equal(t, "https://example.com/"+retried, result.ShortLink)
+	})
+
+	t.Run("collision with another project's path is retried", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		projectID := uuid.New()
+		otherProject := uuid.NewString()
+		paramsHash := models.FromDurableLink(params.DurableLinkInfo, "", "", false, nil).ComputeParamsHash()
+		scope := projectID.String() + "/example.com"
+		taken := generateDeterministicPath(cfg.Secret, scope, params.DurableLinkInfo.Link, paramsHash, 0, cfg.UnguessablePathLength)
+		require.NoError(t, db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      taken,
+			Link:      "https://example.com/someone-else",
+			ProjectID: &otherProject,
+		}).Error)
+
+		result, err := service.CreateDurableLink(context.Background(), params, &projectID, cfg)
+		require.NoError(t, err)
+
+		retried := generateDeterministicPath(cfg.Secret, scope, params.DurableLinkInfo.Link, paramsHash, 1, cfg.UnguessablePathLength)
+		assert.Equal(t, "https://example.com/"+retried, result.ShortLink)
+		assert.False(t, result.Reused)
+	})
+
+	t.Run("password protected create does not reuse an unprotected link", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		open, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		protected := params
+		protected.Password = "open sesame"
+		result, err := service.CreateDurableLink(context.Background(), protected, nil, cfg)
+		require.NoError(t, err)
+
+		assert.NotEqual(t, open.ShortLink, result.ShortLink)
+		assert.False(t, result.Reused)
+
+		var stored models.DurableLinkDB
+		require.NoError(t, db.Where("path = ?", strings.TrimPrefix(result.ShortLink, "https://example.com/")).First(&stored).Error)
+		assert.NotNil(t, stored.PasswordHash)
+	})
+
+	t.Run("unprotected create does not reuse a password protected link", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		paramsHash := models.FromDurableLink(params.DurableLinkInfo, "", "", false, nil).ComputeParamsHash()
+		taken := generateDeterministicPath(cfg.Secret, "example.com", params.DurableLinkInfo.Link, paramsHash, 0, cfg.UnguessablePathLength)
+		require.NoError(t, db.Create(&models.DurableLinkDB{
+			Host:         "example.com",
+			Path:         taken,
+			Link:         params.DurableLinkInfo.Link,
+			ParamsHash:   paramsHash,
+			PasswordHash: stringPtr("$2a$10$hash"),
+		}).Error)
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		retried := generateDeterministicPath(cfg.Secret, "example.com", params.DurableLinkInfo.Link, paramsHash, 1, cfg.UnguessablePathLength)
+		assert.Equal(t, "https://example.com/"+retried, result.ShortLink)
+		assert.False(t, result.Reused)
+	})
+
+	t.Run("click limited create does not reuse an unlimited link", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		open, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		limited := params
+		limited.MaxClicks = int64Ptr(1)
+		result, err := service.CreateDurableLink(context.Background(), limited, nil, cfg)
+		require.NoError(t, err)
+
+		assert.NotEqual(t, open.ShortLink, result.ShortLink)
+		assert.False(t, result.Reused)
+
+		var stored models.DurableLinkDB
+		require.NoError(t, db.Where("path = ?", strings.TrimPrefix(result.ShortLink, "https://example.com/")).First(&stored).Error)
+		require.NotNil(t, stored.MaxClicks)
+		assert.Equal(t, int64(1), *stored.MaxClicks)
+	})
+
+	t.Run("unlimited create does not reuse a click limited link", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		paramsHash := models.FromDurableLink(params.DurableLinkInfo, "", "", false, nil).ComputeParamsHash()
+		taken := generateDeterministicPath(cfg.Secret, "example.com", params.DurableLinkInfo.Link, paramsHash, 0, cfg.UnguessablePathLength)
+		require.NoError(t, db.Create(&models.DurableLinkDB{
+			Host:       "example.com",
+			Path:       taken,
+			Link:       params.DurableLinkInfo.Link,
+			ParamsHash: paramsHash,
+			MaxClicks:  int64Ptr(1),
+		}).Error)
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		retried := generateDeterministicPath(cfg.Secret, "example.com", params.DurableLinkInfo.Link, paramsHash, 1, cfg.UnguessablePathLength)
+		assert.Equal(t, "https://example.com/"+retried, result.ShortLink)
+		assert.False(t, result.Reused)
+	})
+
+	t.Run("concurrent identical create re-uses the stored link", func(t *testing.T) {
+		service, db := setupTestService(t)
+		racing := NewLinkService(racingRepository{repository.NewLinkRepository(db)})
+
+		first, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		// The availability check passes, the insert then finds the path taken
+		second, err := racing.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, first.ShortLink, second.ShortLink)
+		assert.True(t, second.Reused)
+
+		var count int64
+		db.Model(&models.DurableLinkDB{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("missing secret returns error", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		noSecret := cfg
+		noSecret.Secret = ""
+		result, err := service.CreateDurableLink(context.Background(), params, nil, noSecret)
+		assert.ErrorIs(t, err, ErrMissingTenantSecret)
+		assert.Nil(t, result)
+	})
+}
+
+func TestCreateDurableLink_CustomPath(t *testing.T) {
+	newParams := func(customPath string) models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			CustomPath: customPath,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		customPath  string
+		minLength   int
+		expectError error
+	}{
+		{
+			name:        "below default minimum is rejected",
+			customPath:  "ab",
+			expectError: ErrCustomPathTooShort,
+		},
+		{
+			name:       "at default minimum is accepted",
+			customPath: "abc",
+		},
+		{
+			name:        "below configured minimum is rejected",
+			customPath:  "abcd",
+			minLength:   5,
+			expectError: ErrCustomPathTooShort,
+		},
+		{
+			name:       "at configured minimum is accepted",
+			customPath: "abcde",
+			minLength:  5,
+		},
+		{
+			name:       "configured minimum below default is honored",
+			customPath: "a",
+			minLength:  1,
+		},
+		{
+			name:        "invalid characters are rejected",
+			customPath:  "spring/sale",
+			expectError: ErrInvalidCustomPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			cfg := defaultTenantCfg
+			cfg.MinCustomPathLength = tt.minLength
+
+			result, err := service.CreateDurableLink(context.Background(), newParams(tt.customPath), nil, cfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/"+tt.customPath, result.ShortLink)
+		})
+	}
+
+	t.Run("taken custom path is rejected", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		_, err := service.CreateDurableLink(context.Background(), newParams("spring-sale"), nil, defaultTenantCfg)
+		require.NoError(t, err)
+
+		result, err := service.CreateDurableLink(context.Background(), newParams("spring-sale"), nil, defaultTenantCfg)
+		assert.ErrorIs(t, err, ErrCustomPathTaken)
+		assert.Nil(t, result)
+	})
+}
+
+// racingRepository reports every path as available, like a check that ran before a concurrent
+// create stored the same path.
+type racingRepository struct {
+	repository.LinkRepository
+}
+
+func (racingRepository) IsPathAvailable(ctx context.Context, host, path string) (bool, error) {
+	return true, nil
+}
+
+func (racingRepository) IsPathAvailableInProject(ctx context.Context, host, path string, projectID *uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func TestCreateDurableLink_CustomPathRace(t *testing.T) {
+	_, db := setupTestService(t)
+	service := NewLinkService(racingRepository{repository.NewLinkRepository(db)})
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		CustomPath: "spring-sale",
+	}
+
+	_, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+
+	// The availability check passes, the insert then finds the path taken
+	params.DurableLinkInfo.Link = "https://example.com/other"
+	result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	assert.ErrorIs(t, err, ErrCustomPathTaken)
+	assert.Equal(t, http.StatusConflict, HTTPStatusForError(err))
+	assert.Nil(t, result)
+
+	var stored []models.DurableLinkDB
+	require.NoError(t, db.Find(&stored).Error)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "https://example.com/target", stored[0].Link)
+}
+
+func TestCreateDurableLink_CustomPathWithSuffix(t *testing.T) {
+	tests := []struct {
+		name          string
+		suffixOption  string
+		expectWarning bool
+	}{
+		{name: "custom path only"},
+		{name: "custom path and SHORT", suffixOption: "SHORT", expectWarning: true},
+		{name: "custom path and UNGUESSABLE", suffixOption: "UNGUESSABLE", expectWarning: true},
+	}
+
+	for _, tt := range tests {
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			Suffix:     models.Suffix{Option: tt.suffixOption},
+			CustomPath: "spring-sale",
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/spring-sale", result.ShortLink, "the custom path wins")
+			if !tt.expectWarning {
+				assert.Empty(t, result.Warnings)
+				return
+			}
+			require.Len(t, result.Warnings, 1)
+			assert.Equal(t, "UNRECOGNIZED_PARAM", result.Warnings[0].WarningCode)
+			assert.Equal(t, "suffix.option", result.Warnings[0].Field)
+		})
+
+		t.Run(tt.name+" in strict mode", func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			cfg := defaultTenantCfg
+			cfg.StrictValidation = true
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+			if !tt.expectWarning {
+				require.NoError(t, err)
+				assert.Equal(t, "https://example.com/spring-sale", result.ShortLink)
+				return
+			}
+			var validationErrs models.ValidationErrors
+			require.ErrorAs(t, err, &validationErrs)
+			require.Len(t, validationErrs.Errors, 1)
+			assert.Equal(t, "suffix.option", validationErrs.Errors[0].Field)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestResolveCandidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		dbLink   models.DurableLinkDB
+		platform models.Platform
+		expected []string
+	}{
+		{
+			name: "ios uses ios fallback then other fallback then link",
+			dbLink: models.DurableLinkDB{
+				IOSFallbackLink:     stringPtr("https://example.com/ios"),
+				AndroidFallbackLink: stringPtr("https://example.com/android"),
+				OtherFallbackURL:    stringPtr("https://example.com/other"),
+			},
+			platform: models.PlatformIOS,
+			expected: []string{"https://example.com/ios", "https://example.com/other", "https://example.com/target"},
+		},
+		{
+			name: "ipad prefers ipad fallback over ios fallback",
+			dbLink: models.DurableLinkDB{
+				IOSFallbackLink:     stringPtr("https://example.com/ios"),
+				IOSIpadFallbackLink: stringPtr("https://example.com/ipad"),
+			},
+			platform: models.PlatformIPad,
+			expected: []string{"https://example.com/ipad", "https://example.com/ios", "https://example.com/target"},
+		},
+		{
+			name: "android uses android fallback then link",
+			dbLink: models.DurableLinkDB{
+				IOSFallbackLink:     stringPtr("https://example.com/ios"),
+				AndroidFallbackLink: stringPtr("https://example.com/android"),
+			},
+			platform: models.PlatformAndroid,
+			expected: []string{"https://example.com/android", "https://example.com/target"},
+		},
+		{
+			name: "other ignores platform fallbacks",
+			dbLink: models.DurableLinkDB{
+				IOSFallbackLink:     stringPtr("https://example.com/ios"),
+				AndroidFallbackLink: stringPtr("https://example.com/android"),
+				OtherFallbackURL:    stringPtr("https://example.com/other"),
+			},
+			platform: models.PlatformOther,
+			expected: []string{"https://example.com/other", "https://example.com/target"},
+		},
+		{
+			name:     "no fallbacks returns only the link",
+			dbLink:   models.DurableLinkDB{},
+			platform: models.PlatformIOS,
+			expected: []string{"https://example.com/target"},
+		},
+		{
+			name: "duplicates and empty values are removed",
+			dbLink: models.DurableLinkDB{
+				AndroidFallbackLink: stringPtr("https://example.com/target"),
+				OtherFallbackURL:    stringPtr(""),
+			},
+			platform: models.PlatformAndroid,
+			expected: []string{"https://example.com/target"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := setupTestService(t)
+
+			tt.dbLink.Host = "example.com"
+			tt.dbLink.Path = "abc123"
+			tt.dbLink.Link = "https://example.com/target"
+			db.Create(&tt.dbLink)
+
+			candidates, err := service.ResolveCandidates(context.Background(), "https://example.com/abc123", tt.platform, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, candidates)
+		})
+	}
+
+	t.Run("unknown path returns not found", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		candidates, err := service.ResolveCandidates(context.Background(), "https://example.com/missing", models.PlatformIOS, nil, defaultTenantCfg)
+		assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+		assert.Nil(t, candidates)
+	})
+}
+
+func TestResolveForPlatform(t *testing.T) {
+	allFallbacks := models.DurableLinkDB{
+		IOSFallbackLink:     stringPtr("https://example.com/ios"),
+		IOSIpadFallbackLink: stringPtr("https://example.com/ipad"),
+		AndroidFallbackLink: stringPtr("https://example.com/android"),
+		OtherFallbackURL:    stringPtr("https://example.com/other"),
+	}
+
+	tests := []struct {
+		name           string
+		dbLink         models.DurableLinkDB
+		platform       models.Platform
+		expectedURL    string
+		expectedSource models.LinkSource
+	}{
+		{name: "ios fallback", dbLink: allFallbacks, platform: models.PlatformIOS, expectedURL: "https://example.com/ios", expectedSource: models.LinkSourceIOSFallback},
+		{name: "ipad fallback", dbLink: allFallbacks, platform: models.PlatformIPad, expectedURL: "https://example.com/ipad", expectedSource: models.LinkSourceIPadFallback},
+		{name: "android fallback", dbLink: allFallbacks, platform: models.PlatformAndroid, expectedURL: "https://example.com/android", expectedSource: models.LinkSourceAndroidFallback},
+		{name: "other fallback", dbLink: allFallbacks, platform: models.PlatformOther, expectedURL: "https://example.com/other", expectedSource: models.LinkSourceOtherFallback},
+		{
+			name:           "ipad without ipad fallback uses ios fallback",
+			dbLink:         models.DurableLinkDB{IOSFallbackLink: stringPtr("https://example.com/ios")},
+			platform:       models.PlatformIPad,
+			expectedURL:    "https://example.com/ios",
+			expectedSource: models.LinkSourceIOSFallback,
+		},
+		{
+			name:           "ios fallback list counts as ios fallback",
+			dbLink:         models.DurableLinkDB{IOSFallbackLinks: models.StringList{"https://example.com/ios-list"}},
+			platform:       models.PlatformIOS,
+			expectedURL:    "https://example.com/ios-list",
+			expectedSource: models.LinkSourceIOSFallback,
+		},
+		{
+			name:           "android without android fallback uses other fallback",
+			dbLink:         models.DurableLinkDB{OtherFallbackURL: stringPtr("https://example.com/other")},
+			platform:       models.PlatformAndroid,
+			expectedURL:    "https://example.com/other",
+			expectedSource: models.LinkSourceOtherFallback,
+		},
+		{
+			name:           "no fallbacks uses the canonical link",
+			dbLink:         models.DurableLinkDB{IOSFallbackLink: stringPtr("https://example.com/ios")},
+			platform:       models.PlatformAndroid,
+			expectedURL:    "https://example.com/target",
+			expectedSource: models.LinkSourceCanonical,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := setupTestService(t)
+
+			tt.dbLink.Host = "example.com"
+			tt.dbLink.Path = "abc123"
+			tt.dbLink.Link = "https://example.com/target"
+			db.Create(&tt.dbLink)
+
+			result, err := service.ResolveForPlatform(context.Background(), "https://example.com/abc123", tt.platform, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedURL, result.LongLink)
+			assert.Equal(t, tt.expectedSource, result.Source)
+		})
+	}
+
+	t.Run("source is serialized by name", func(t *testing.T) {
+		b, err := json.Marshal(models.PlatformLinkResponse{LongLink: "https://example.com/ipad", Source: models.LinkSourceIPadFallback})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"longLink":"https://example.com/ipad","source":"ipadFallback"}`, string(b))
+	})
+
+	t.Run("matching rule replaces the canonical link", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host:  "example.com",
+				Link:  "https://example.com/target",
+				Rules: []models.ResolutionRule{{Countries: []string{"US"}, Link: "https://example.com/us"}},
+				IosParameters: models.IOSParameters{
+					IOSFallbackLink: stringPtr("https://example.com/ios"),
+				},
+			},
+			Suffix: models.Suffix{Option: "SHORT"},
+		}
+		created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+
+		ctx := ContextWithRequestContext(context.Background(), models.RequestContext{Country: "US"})
+		result, err := service.ResolveForPlatform(ctx, created.ShortLink, models.PlatformAndroid, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/us", result.LongLink)
+		assert.Equal(t, models.LinkSourceCanonical, result.Source)
+
+		candidates, err := service.ResolveCandidates(ctx, created.ShortLink, models.PlatformIOS, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://example.com/ios", "https://example.com/us"}, candidates)
+
+		// Requests the rule doesn't match keep the canonical link
+		result, err = service.ResolveForPlatform(context.Background(), created.ShortLink, models.PlatformAndroid, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", result.LongLink)
+	})
+
+	t.Run("unknown path returns not found", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		result, err := service.ResolveForPlatform(context.Background(), "https://example.com/missing", models.PlatformIOS, nil, defaultTenantCfg)
+		assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+		assert.Nil(t, result)
+	})
+}
+
+func TestValidateLinkParameters_ClearsNonPositiveAppStoreID(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	for _, id := range []int64{0, -1} {
+		dl := models.DurableLink{
+			Link: "https://example.com/target",
+			IosParameters: models.IOSParameters{
+				IOSAppStoreId: int64Ptr(id),
+			},
+		}
+		service.validateLinkParameters(&dl)
+		assert.Nil(t, dl.IosParameters.IOSAppStoreId, "id %d should be cleared", id)
+	}
+
+	dl := models.DurableLink{
+		Link: "https://example.com/target",
+		IosParameters: models.IOSParameters{
+			IOSAppStoreId: int64Ptr(123456789),
+		},
+	}
+	service.validateLinkParameters(&dl)
+	assert.Equal(t, int64Ptr(123456789), dl.IosParameters.IOSAppStoreId)
+}
+
+func TestResolveOrFallback(t *testing.T) {
+	fallbackCfg := defaultTenantCfg
+	fallbackCfg.NotFoundFallbackURL = stringPtr("https://example.com/home")
+
+	tests := []struct {
+		name        string
+		rawURL      string
+		tenantCfg   TenantConfig
+		expectURL   string
+		expectFound bool
+		expectError error
+	}{
+		{
+			name:        "existing link is found",
+			rawURL:      "https://example.com/abc123",
+			tenantCfg:   fallbackCfg,
+			expectURL:   "https://example.com/target",
+			expectFound: true,
+		},
+		{
+			name:        "missing link uses tenant fallback",
+			rawURL:      "https://example.com/missing",
+			tenantCfg:   fallbackCfg,
+			expectURL:   "https://example.com/home",
+			expectFound: false,
+		},
+		{
+			name:        "missing link without fallback returns not found",
+			rawURL:      "https://example.com/missing",
+			tenantCfg:   defaultTenantCfg,
+			expectError: repository.ErrLinkNotFound,
+		},
+		{
+			name:        "link past its click limit uses tenant fallback",
+			rawURL:      "https://example.com/exhausted",
+			tenantCfg:   fallbackCfg,
+			expectURL:   "https://example.com/home",
+			expectFound: false,
+		},
+		{
+			name:        "link past its click limit without fallback returns gone",
+			rawURL:      "https://example.com/exhausted",
+			tenantCfg:   defaultTenantCfg,
+			expectError: repository.ErrLinkGone,
+		},
+		{
+			name:        "invalid path is not masked by fallback",
+			rawURL:      "https://example.com/a/b",
+			tenantCfg:   fallbackCfg,
+			expectError: ErrInvalidPathFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := setupTestService(t)
+			db.Create(&models.DurableLinkDB{
+				Host: "example.com",
+				Path: "abc123",
+				Link: "https://example.com/target",
+			})
+			db.Create(&models.DurableLinkDB{
+				Host:       "example.com",
+				Path:       "exhausted",
+				Link:       "https://example.com/limited",
+				ClickCount: 1,
+				MaxClicks:  int64Ptr(1),
+			})
+
+			url, found, err := service.ResolveOrFallback(context.Background(), tt.rawURL, nil, tt.tenantCfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Empty(t, url)
+				assert.False(t, found)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectURL, url)
+			assert.Equal(t, tt.expectFound, found)
+		})
+	}
+}
+
+func TestCreateDurableLink_StoreRawRequest(t *testing.T) {
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+			AndroidParameters: models.AndroidParameters{
+				AndroidFallbackLink: stringPtr("not-a-valid-url"),
+			},
+		},
+		Suffix: models.Suffix{
+			Option: "UNGUESSABLE",
+		},
+	}
+
+	t.Run("raw request round-trips when enabled", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.StoreRawRequest = true
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		path := strings.TrimPrefix(result.ShortLink, "https://example.com/")
+		raw, err := service.repo.GetRawRequest(context.Background(), "example.com", path, nil)
+		require.NoError(t, err)
+
+		var stored models.CreateDurableLinkRequest
+		require.NoError(t, json.Unmarshal([]byte(raw), &stored))
+		// The original request is kept, before any cleanup or defaults
+		assert.Equal(t, params, stored)
+	})
+
+	t.Run("raw request is not stored by default", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+
+		path := strings.TrimPrefix(result.ShortLink, "https://example.com/")
+		raw, err := service.repo.GetRawRequest(context.Background(), "example.com", path, nil)
+		require.NoError(t, err)
+		assert.Empty(t, raw)
+	})
+}
+
+// fakePathGenerator returns predetermined paths in order, for deterministic tests.
+type fakePathGenerator struct {
+	paths []string
+	calls int
+}
+
+func (g *fakePathGenerator) Generate(length int) (string, error) {
+	if g.calls >= len(g.paths) {
+		return "", fmt.Errorf("fake generator exhausted")
+	}
+	path := g.paths[g.calls]
+	g.calls++
+	return path, nil
+}
+
+// fakeUniquenessFilter is an exact set standing in for a bloom filter.
+type fakeUniquenessFilter struct {
+	keys map[string]bool
+}
+
+func (f *fakeUniquenessFilter) MightContain(key string) bool {
+	return f.keys[key]
+}
+
+func (f *fakeUniquenessFilter) Add(key string) {
+	f.keys[key] = true
+}
+
+// countingRepository counts the lookups for existing short links.
+type countingRepository struct {
+	repository.LinkRepository
+	findExistingCalls int
+}
+
+func (r *countingRepository) FindExistingShortLink(ctx context.Context, host string, link *models.DurableLink, projectID *uuid.UUID, createdAfter time.Time) (string, error) {
+	r.findExistingCalls++
+	return r.LinkRepository.FindExistingShortLink(ctx, host, link, projectID, createdAfter)
+}
+
+func TestCreateDurableLink_UniquenessFilter(t *testing.T) {
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "SHORT",
+		},
+	}
+
+	newService := func(t *testing.T, filter UniquenessFilter) (*linkService, *countingRepository) {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		require.NoError(t, err)
+		require.NoError(t, db.AutoMigrate(&models.DurableLinkDB{}))
+		repo := &countingRepository{LinkRepository: repository.NewLinkRepository(db)}
+		return NewLinkService(repo, WithUniquenessFilter(filter)), repo
+	}
+
+	t.Run("definitely absent links skip the lookup", func(t *testing.T) {
+		service, repo := newService(t, &fakeUniquenessFilter{keys: map[string]bool{}})
+
+		_, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, 0, repo.findExistingCalls)
+	})
+
+	t.Run("possible matches fall back to the database", func(t *testing.T) {
+		service, repo := newService(t, &fakeUniquenessFilter{keys: map[string]bool{}})
+
+		first, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+
+		second, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, 1, repo.findExistingCalls)
+		assert.True(t, second.Reused)
+		assert.Equal(t, first.ShortLink, second.ShortLink)
+	})
+
+	t.Run("false positives create a new link", func(t *testing.T) {
+		filter := &fakeUniquenessFilter{keys: map[string]bool{}}
+		filter.Add(uniquenessKey("example.com", params.DurableLinkInfo, nil))
+		service, repo := newService(t, filter)
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, 1, repo.findExistingCalls)
+		assert.False(t, result.Reused)
+	})
+
+	t.Run("default filter always checks the database", func(t *testing.T) {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		require.NoError(t, err)
+		require.NoError(t, db.AutoMigrate(&models.DurableLinkDB{}))
+		repo := &countingRepository{LinkRepository: repository.NewLinkRepository(db)}
+		service := NewLinkService(repo)
+
+		_, err = service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, 1, repo.findExistingCalls)
+	})
+}
+
+func TestCreateDurableLink_PathGenerator(t *testing.T) {
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "UNGUESSABLE",
+		},
+	}
+
+	newService := func(t *testing.T, gen PathGenerator) (*linkService, *gorm.DB) {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		require.NoError(t, err)
+		require.NoError(t, db.AutoMigrate(&models.DurableLinkDB{}))
+		return NewLinkService(repository.NewLinkRepository(db), WithPathGenerator(gen)), db
+	}
+
+	t.Run("uses the injected generator", func(t *testing.T) {
+		service, _ := newService(t, &fakePathGenerator{paths: []string{"known1"}})
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/known1", result.ShortLink)
+	})
+
+	t.Run("collision retries with the next path", func(t *testing.T) {
+		gen := &fakePathGenerator{paths: []string{"taken", "free"}}
+		service, db := newService(t, gen)
+		db.Create(&models.DurableLinkDB{Host: "example.com", Path: "taken", Link: "https://example.com/other"})
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/free", result.ShortLink)
+		assert.Equal(t, 2, gen.calls)
+	})
+
+	t.Run("persistent collisions give up", func(t *testing.T) {
+		paths := make([]string, maxPathAttempts)
+		for i := range paths {
+			paths[i] = "taken"
+		}
+		service, db := newService(t, &fakePathGenerator{paths: paths})
+		db.Create(&models.DurableLinkDB{Host: "example.com", Path: "taken", Link: "https://example.com/other"})
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		assert.ErrorIs(t, err, ErrPathCollision)
+		assert.Nil(t, result)
+	})
+
+	t.Run("generator errors are returned", func(t *testing.T) {
+		service, _ := newService(t, &fakePathGenerator{})
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		assert.ErrorContains(t, err, "fake generator exhausted")
+		assert.Nil(t, result)
+	})
+}
+
+func TestResolveShortPath_PathPrefix(t *testing.T) {
+	prefixedCfg := defaultTenantCfg
+	prefixedCfg.PathPrefix = "/l"
+
+	tests := []struct {
+		name        string
+		rawURL      string
+		tenantCfg   TenantConfig
+		expectError error
+	}{
+		{
+			name:      "prefixed path resolves",
+			rawURL:    "https://example.com/l/abc123",
+			tenantCfg: prefixedCfg,
+		},
+		{
+			name:      "prefixed path with trailing slash resolves",
+			rawURL:    "https://example.com/l/abc123/",
+			tenantCfg: prefixedCfg,
+		},
+		{
+			name:        "missing prefix is rejected",
+			rawURL:      "https://example.com/abc123",
+			tenantCfg:   prefixedCfg,
+			expectError: ErrInvalidPathFormat,
+		},
+		{
+			name:        "prefix without code is rejected",
+			rawURL:      "https://example.com/l/",
+			tenantCfg:   prefixedCfg,
+			expectError: ErrInvalidPathFormat,
+		},
+		{
+			name:      "double slash after prefix resolves",
+			rawURL:    "https://example.com/l//abc123",
+			tenantCfg: prefixedCfg,
+		},
+		{
+			name:        "prefix as part of a longer segment is rejected",
+			rawURL:      "https://example.com/links/abc123",
+			tenantCfg:   prefixedCfg,
+			expectError: ErrInvalidPathFormat,
+		},
+		{
+			name:      "unprefixed path resolves without prefix configured",
+			rawURL:    "https://example.com/abc123",
+			tenantCfg: defaultTenantCfg,
+		},
+		{
+			name:        "prefixed path is multi-segment without prefix configured",
+			rawURL:      "https://example.com/l/abc123",
+			tenantCfg:   defaultTenantCfg,
+			expectError: ErrInvalidPathFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := setupTestService(t)
+			db.Create(&models.DurableLinkDB{
+				Host: "example.com",
+				Path: "abc123",
+				Link: "https://example.com/target",
+			})
+
+			result, err := service.ResolveShortPath(context.Background(), tt.rawURL, nil, tt.tenantCfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/target", result.LongLink)
+		})
+	}
+}
+
+func TestCreateDurableLink_PathPrefix(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	cfg := defaultTenantCfg
+	cfg.PathPrefix = "l/"
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		CustomPath: "spring",
+	}
+
+	result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/l/spring", result.ShortLink)
+
+	resolved, err := service.ResolveShortPath(context.Background(), result.ShortLink, nil, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/target", resolved.LongLink)
+}
+
+func TestCreateAndResolve_PathSuffix(t *testing.T) {
+	cfg := defaultTenantCfg
+	cfg.PathPrefix = "l"
+	cfg.PathSuffix = ".page"
+
+	t.Run("custom path", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			CustomPath: "spring",
+		}
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/l/spring.page", result.ShortLink)
+		assert.Equal(t, "spring", result.Details.Path)
+
+		for _, rawURL := range []string{result.ShortLink, "https://example.com/l/spring"} {
+			resolved, err := service.ResolveShortPath(context.Background(), rawURL, nil, cfg)
+			require.NoError(t, err, rawURL)
+			assert.Equal(t, "https://example.com/target", resolved.LongLink)
+		}
+
+		_, err = service.ResolveShortPath(context.Background(), "https://example.com/l/spring.page/extra", nil, cfg)
+		assert.ErrorIs(t, err, ErrInvalidPathFormat)
+		_, err = service.ResolveShortPath(context.Background(), "https://example.com/l/.page", nil, cfg)
+		assert.ErrorIs(t, err, ErrInvalidPathFormat)
+	})
+
+	t.Run("generated path with checksum and signature", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		signed := cfg
+		signed.PathChecksum = true
+		signed.RequireSignature = true
+		signed.Secret = "test-secret"
+
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			Suffix: models.Suffix{Option: "SHORT"},
+		}
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, signed)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result.ShortLink, "https://example.com/l/"+result.Details.Path+".page?sig="), result.ShortLink)
+
+		var stored models.DurableLinkDB
+		require.NoError(t, db.Where("path = ?", result.Details.Path).First(&stored).Error, "the suffix is not stored")
+
+		resolved, err := service.ResolveShortPath(context.Background(), result.ShortLink, nil, signed)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", resolved.LongLink)
+	})
+}
+
+func TestValidateLinkParameters_FallbackLinkLists(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	dl := models.DurableLink{
+		Link: "https://example.com/target",
+		AndroidParameters: models.AndroidParameters{
+			AndroidFallbackLinks: []string{"https://example.com/a", "not-a-url", "https://example.com/b"},
+		},
+		IosParameters: models.IOSParameters{
+			IOSFallbackLinks: []string{"bad"},
+		},
+	}
+
+	warnings := service.validateLinkParameters(&dl)
+	require.Len(t, warnings, 2)
+	assert.Equal(t, "Param 'androidFallbackLinks[1]' is not a valid URL", warnings[0].WarningMessage)
+	assert.Equal(t, "Param 'iosFallbackLinks[0]' is not a valid URL", warnings[1].WarningMessage)
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, dl.AndroidParameters.AndroidFallbackLinks)
+	assert.Nil(t, dl.IosParameters.IOSFallbackLinks)
+}
+
+func TestResolveCandidates_FallbackLinkLists(t *testing.T) {
+	service, db := setupTestService(t)
+
+	db.Create(&models.DurableLinkDB{
+		Host:                 "example.com",
+		Path:                 "abc123",
+		Link:                 "https://example.com/target",
+		AndroidFallbackLink:  stringPtr("https://example.com/market"),
+		AndroidFallbackLinks: models.StringList{"https://example.com/apk", "https://example.com/web"},
+		OtherFallbackURL:     stringPtr("https://example.com/other"),
+	})
+
+	candidates, err := service.ResolveCandidates(context.Background(), "https://example.com/abc123", models.PlatformAndroid, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://example.com/market",
+		"https://example.com/apk",
+		"https://example.com/web",
+		"https://example.com/other",
+		"https://example.com/target",
+	}, candidates)
+}
+
+func TestCreateAndResolve_PreservesFragment(t *testing.T) {
+	for _, option := range []string{"SHORT", "UNGUESSABLE"} {
+		t.Run(option, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			cfg := defaultTenantCfg
+			cfg.DomainAllowList = []string{"app.com"}
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: "https://app.com/p?x=1#foo",
+				},
+				Suffix: models.Suffix{
+					Option: option,
+				},
+			}
+
+			created, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+			require.NoError(t, err)
+
+			resolved, err := service.ResolveShortPath(context.Background(), created.ShortLink, nil, cfg)
+			require.NoError(t, err)
+			assert.Equal(t, "https://app.com/p?x=1#foo", resolved.LongLink)
+
+			// A fragment on the short link itself does not affect the lookup
+			resolved, err = service.ResolveShortPath(context.Background(), created.ShortLink+"#ignored", nil, cfg)
+			require.NoError(t, err)
+			assert.Equal(t, "https://app.com/p?x=1#foo", resolved.LongLink)
+		})
+	}
+}
+
+func TestCreateDurableLink_StrictValidation(t *testing.T) {
+	strictCfg := defaultTenantCfg
+	strictCfg.StrictValidation = true
+
+	invalid := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+			AndroidParameters: models.AndroidParameters{
+				AndroidFallbackLink: stringPtr("not-a-valid-url"),
+			},
+			SocialMetaTagInfo: models.SocialMetaTagInfo{
+				SocialImageLink: stringPtr("bad-image-url"),
+			},
+		},
+		Suffix: models.Suffix{
+			Option: "UNGUESSABLE",
+		},
+	}
+
+	t.Run("lenient mode warns and clears", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), invalid, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		require.Len(t, result.Warnings, 2)
+		assert.Equal(t, "MALFORMED_PARAM", result.Warnings[0].WarningCode)
+
+		var stored models.DurableLinkDB
+		require.NoError(t, db.First(&stored).Error)
+		assert.Nil(t, stored.AndroidFallbackLink)
+		assert.Nil(t, stored.SocialImageLink)
+	})
+
+	t.Run("strict mode returns validation errors", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), invalid, nil, strictCfg)
+		assert.Nil(t, result)
+
+		var validationErrs models.ValidationErrors
+		require.ErrorAs(t, err, &validationErrs)
+		require.Len(t, validationErrs.Errors, 2)
+		assert.Equal(t, "malformed_param", validationErrs.Errors[0].Tag)
+		assert.Equal(t, "Param 'androidFallbackLink' is not a valid URL", validationErrs.Errors[0].Message)
+		assert.Equal(t, "Param 'socialImageLink' is not a valid URL", validationErrs.Errors[1].Message)
+		assert.Equal(t, "durableLinkInfo.androidParameters.androidFallbackLink", validationErrs.Errors[0].Field)
+		assert.Equal(t, "durableLinkInfo.socialMetaTagInfo.socialImageLink", validationErrs.Errors[1].Field)
+
+		var count int64
+		db.Model(&models.DurableLinkDB{}).Count(&count)
+		assert.Zero(t, count, "nothing should be stored")
+	})
+
+	t.Run("strict mode keeps non-malformed warnings", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+				AnalyticsInfo: models.AnalyticsInfo{
+					ItunesConnectAnalytics: models.ITunesConnectAnalytics{
+						Pt: stringPtr("provider_token"),
+					},
+				},
+			},
+			Suffix: models.Suffix{
+				Option: "UNGUESSABLE",
+			},
+		}
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, strictCfg)
+		require.NoError(t, err)
+		require.Len(t, result.Warnings, 1)
+		assert.Equal(t, "UNRECOGNIZED_PARAM", result.Warnings[0].WarningCode)
+	})
+}
+
+func TestCreateDurableLink_URLSchemeOverride(t *testing.T) {
+	newParams := func(scheme *string) models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			Suffix: models.Suffix{
+				Option: "SHORT",
+			},
+			URLScheme: scheme,
+		}
+	}
+
+	t.Run("override to http", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), newParams(stringPtr("http")), nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result.ShortLink, "http://example.com/"), result.ShortLink)
+
+		// The override only applies to that call, also when the link is reused
+		reused, err := service.CreateDurableLink(context.Background(), newParams(nil), nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://"+strings.TrimPrefix(result.ShortLink, "http://"), reused.ShortLink)
+	})
+
+	t.Run("override is case-insensitive", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), newParams(stringPtr("HTTP")), nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result.ShortLink, "http://example.com/"), result.ShortLink)
+	})
+
+	t.Run("invalid override is rejected", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), newParams(stringPtr("ftp")), nil, defaultTenantCfg)
+		assert.ErrorIs(t, err, ErrInvalidURLScheme)
+		assert.Nil(t, result)
+	})
+}
+
+func TestResolveProtected(t *testing.T) {
+	service, db := setupTestService(t)
+
+	cfg := defaultTenantCfg
+	cfg.StoreRawRequest = true
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/secret",
+		},
+		CustomPath: "vault",
+		Password:   "open sesame",
+	}
+	created, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+	require.NoError(t, err)
+
+	var stored models.DurableLinkDB
+	require.NoError(t, db.Where("path = ?", "vault").First(&stored).Error)
+	require.NotNil(t, stored.PasswordHash)
+	assert.NotContains(t, *stored.PasswordHash, "open sesame")
+	require.NotNil(t, stored.RawRequest)
+	assert.NotContains(t, *stored.RawRequest, "open sesame", "plaintext password must not be stored")
+
+	tests := []struct {
+		name        string
+		password    string
+		expectError error
+	}{
+		{name: "correct password", password: "open sesame"},
+		{name: "incorrect password", password: "guess", expectError: ErrInvalidPassword},
+		{name: "missing password", password: "", expectError: ErrPasswordRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.ResolveProtected(context.Background(), created.ShortLink, tt.password, nil, cfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/secret", result.LongLink)
+		})
+	}
+
+	t.Run("plain resolve requires the password", func(t *testing.T) {
+		result, err := service.ResolveShortPath(context.Background(), created.ShortLink, nil, cfg)
+		assert.ErrorIs(t, err, ErrPasswordRequired)
+		assert.Nil(t, result)
+	})
+
+	t.Run("unprotected links resolve without a password", func(t *testing.T) {
+		db.Create(&models.DurableLinkDB{Host: "example.com", Path: "open", Link: "https://example.com/open"})
+
+		result, err := service.ResolveProtected(context.Background(), "https://example.com/open", "", nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/open", result.LongLink)
+	})
+}
+
+func TestCreateDurableLink_PasswordProtectedShortLinksAreNotReused(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "SHORT",
+		},
+	}
+
+	open, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+
+	params.Password = "secret"
+	protected, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, open.ShortLink, protected.ShortLink)
+
+	params.Password = ""
+	again, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	assert.Equal(t, open.ShortLink, again.ShortLink)
+}
+
+func TestCreateDurableLink_MaxClicks(t *testing.T) {
+	service, db := setupTestService(t)
+
+	maxClicks := int64(3)
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "SHORT",
+		},
+	}
+
+	open, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+
+	params.MaxClicks = &maxClicks
+	limited, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, open.ShortLink, limited.ShortLink, "click limited links must not be shared")
+
+	var stored models.DurableLinkDB
+	require.NoError(t, db.Where("path = ?", limited.Details.Path).First(&stored).Error)
+	require.NotNil(t, stored.MaxClicks)
+	assert.Equal(t, int64(3), *stored.MaxClicks)
+}
+
+func TestCreateDurableLink_ShortLinkDetails(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	cfg := defaultTenantCfg
+	cfg.PathPrefix = "l"
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "SHORT",
+		},
+	}
+
+	created, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+	require.NoError(t, err)
+	require.NotNil(t, created.Details)
+	assert.Equal(t, "https", created.Details.Scheme)
+	assert.Equal(t, "example.com", created.Details.Host)
+	assert.Len(t, created.Details.Path, cfg.ShortPathLength)
+	assert.Equal(t, "https://example.com/l/"+created.Details.Path, created.Details.URL)
+	assert.Equal(t, created.ShortLink, created.Details.URL)
+
+	assert.False(t, created.Reused)
+
+	reused, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+	require.NoError(t, err)
+	require.NotNil(t, reused.Details)
+	assert.Equal(t, *created.Details, *reused.Details)
+	assert.True(t, reused.Reused)
+}
+
+func TestCreateDurableLink_PreviewLink(t *testing.T) {
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{Option: "SHORT"},
+	}
+
+	t.Run("default query param", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/"+created.Details.Path+"?d=1", created.PreviewLink)
+	})
+
+	t.Run("configured query param", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.PreviewQueryParam = "debug=true"
+
+		created, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/"+created.Details.Path+"?debug=true", created.PreviewLink)
+	})
+
+	t.Run("query param after signature", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.RequireSignature = true
+		cfg.Secret = "test-secret"
+
+		created, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, created.ShortLink+"&d=1", created.PreviewLink)
+
+		resolved, err := service.ResolveShortPath(context.Background(), created.PreviewLink, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", resolved.LongLink)
+	})
+
+	t.Run("preview subdomain", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.PreviewSubdomain = true
+		cfg.RequireSignature = true
+		cfg.Secret = "test-secret"
+
+		created, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Replace(created.ShortLink, "https://example.com/", "https://preview.example.com/", 1), created.PreviewLink)
+
+		resolved, err := service.ResolveShortPath(context.Background(), created.PreviewLink, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", resolved.LongLink)
+	})
+}
+
+func TestCreateDurableLink_DedupIgnoreUTM(t *testing.T) {
+	withCampaign := func(campaign string) models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+				AnalyticsInfo: models.AnalyticsInfo{
+					MarketingParameters: models.MarketingParameters{
+						UtmSource:   stringPtr("newsletter"),
+						UtmCampaign: stringPtr(campaign),
+					},
+				},
+			},
+			Suffix: models.Suffix{
+				Option: "SHORT",
+			},
+		}
+	}
+
+	hmacCfg := defaultTenantCfg
+	hmacCfg.PathStrategy = PathStrategyHMACDeterministic
+	hmacCfg.Secret = "test-secret"
+
+	tests := []struct {
+		name      string
+		tenantCfg TenantConfig
+		ignoreUTM bool
+	}{
+		{name: "UTM included by default", tenantCfg: defaultTenantCfg},
+		{name: "UTM ignored when enabled", tenantCfg: defaultTenantCfg, ignoreUTM: true},
+		{name: "deterministic paths ignore UTM when enabled", tenantCfg: hmacCfg, ignoreUTM: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+			cfg := tt.tenantCfg
+			cfg.DedupIgnoreUTM = tt.ignoreUTM
+
+			spring, err := service.CreateDurableLink(context.Background(), withCampaign("spring"), nil, cfg)
+			require.NoError(t, err)
+			summer, err := service.CreateDurableLink(context.Background(), withCampaign("summer"), nil, cfg)
+			require.NoError(t, err)
+
+			if tt.ignoreUTM {
+				assert.Equal(t, spring.ShortLink, summer.ShortLink)
+			} else {
+				assert.NotEqual(t, spring.ShortLink, summer.ShortLink)
+			}
+		})
+	}
+}
+
+func TestCreateDurableLink_WarningFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    models.CreateDurableLinkRequest
+		tenantCfg TenantConfig
+		wantCode  string
+		wantField string
+	}{
+		{
+			name: "malformed URL param",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					OtherPlatformParameters: models.OtherPlatformParameters{FallbackURL: stringPtr("not-a-url")},
+				},
+			},
+			wantCode:  "MALFORMED_PARAM",
+			wantField: "durableLinkInfo.otherPlatformParameters.fallbackUrl",
+		},
+		{
+			name: "malformed fallback list entry",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					IosParameters: models.IOSParameters{IOSFallbackLinks: []string{"https://example.com/ios", "not-a-url"}},
+				},
+			},
+			wantCode:  "MALFORMED_PARAM",
+			wantField: "durableLinkInfo.iosParameters.iosFallbackLinks[1]",
+		},
+		{
+			name: "malformed App Store ID",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					IosParameters: models.IOSParameters{IOSAppStoreId: int64Ptr(-1)},
+				},
+			},
+			wantCode:  "MALFORMED_PARAM",
+			wantField: "durableLinkInfo.iosParameters.iosAppStoreId",
+		},
+		{
+			name: "unrecognized iTunes param",
+			params: models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					IosParameters: models.IOSParameters{IOSAppStoreId: int64Ptr(123456789)},
+					AnalyticsInfo: models.AnalyticsInfo{
+						ItunesConnectAnalytics: models.ITunesConnectAnalytics{Ct: stringPtr("campaign")},
+					},
+				},
+			},
+			wantCode:  "UNRECOGNIZED_PARAM",
+			wantField: "durableLinkInfo.analyticsInfo.itunesConnectAnalytics.ct",
+		},
+		{
+			name: "invalid suffix option",
+			params: models.CreateDurableLinkRequest{
+				Suffix: models.Suffix{Option: "TINY"},
+			},
+			wantCode:  "INVALID_SUFFIX_OPTION",
+			wantField: "suffix.option",
+		},
+		{
+			name:   "default applied",
+			params: models.CreateDurableLinkRequest{},
+			tenantCfg: func() TenantConfig {
+				c := defaultTenantCfg
+				c.DefaultAndroidPackage = stringPtr("com.example.app")
+				return c
+			}(),
+			wantCode:  "DEFAULT_APPLIED",
+			wantField: "durableLinkInfo.androidParameters.androidPackageName",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			params := tt.params
+			params.DurableLinkInfo.Host = "example.com"
+			params.DurableLinkInfo.Link = "https://example.com/target"
+			if params.Suffix.Option == "" {
+				params.Suffix.Option = "UNGUESSABLE"
+			}
+			cfg := tt.tenantCfg
+			if cfg.URLScheme == "" {
+				cfg = defaultTenantCfg
+			}
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+			require.NoError(t, err)
+			require.Len(t, result.Warnings, 1)
+			assert.Equal(t, tt.wantCode, result.Warnings[0].WarningCode)
+			assert.Equal(t, tt.wantField, result.Warnings[0].Field)
+		})
+	}
+}
+
+func TestValidateLinkParameters_Locale(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	tests := []struct {
+		locale string
+		valid  bool
+	}{
+		{locale: "en", valid: true},
+		{locale: "pt-BR", valid: true},
+		{locale: "zh-Hant-TW", valid: true},
+		{locale: "es-419", valid: true},
+		{locale: "", valid: false},
+		{locale: "e", valid: false},
+		{locale: "en_US", valid: false},
+		{locale: "en-", valid: false},
+		{locale: "not a locale", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			dl := models.DurableLink{
+				Link:   "https://example.com/target",
+				Locale: stringPtr(tt.locale),
+			}
+			warnings := service.validateLinkParameters(&dl)
+
+			if tt.valid {
+				assert.Empty(t, warnings)
+				assert.Equal(t, stringPtr(tt.locale), dl.Locale)
+				return
+			}
+
+			require.Len(t, warnings, 1)
+			assert.Equal(t, "MALFORMED_PARAM", warnings[0].WarningCode)
+			assert.Equal(t, "durableLinkInfo.locale", warnings[0].Field)
+			assert.Nil(t, dl.Locale)
+		})
+	}
+}
+
+func TestValidateLinkParameters_SocialTitleLength(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	t.Run("normal title is kept", func(t *testing.T) {
+		dl := models.DurableLink{
+			Link:              "https://example.com/target",
+			SocialMetaTagInfo: models.SocialMetaTagInfo{SocialTitle: stringPtr("Spring sale")},
+		}
+		warnings := service.validateLinkParameters(&dl)
+		assert.Empty(t, warnings)
+		assert.Equal(t, stringPtr("Spring sale"), dl.SocialMetaTagInfo.SocialTitle)
+	})
+
+	t.Run("long title is truncated", func(t *testing.T) {
+		// Multi-byte runes: the column limit counts characters, not bytes
+		dl := models.DurableLink{
+			Link:              "https://example.com/target",
+			SocialMetaTagInfo: models.SocialMetaTagInfo{SocialTitle: stringPtr(strings.Repeat("é", 600))},
+		}
+		warnings := service.validateLinkParameters(&dl)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "MALFORMED_PARAM", warnings[0].WarningCode)
+		assert.Equal(t, "durableLinkInfo.socialMetaTagInfo.socialTitle", warnings[0].Field)
+		require.NotNil(t, dl.SocialMetaTagInfo.SocialTitle)
+		assert.Equal(t, strings.Repeat("é", 500), *dl.SocialMetaTagInfo.SocialTitle)
+	})
+}
+
+func TestCreateAndResolve_RedirectType(t *testing.T) {
+	tests := []struct {
+		name         string
+		redirectType *string
+		want         string
+		warning      bool
+	}{
+		{name: "unset defaults to temporary", want: models.RedirectTypeTemporary},
+		{name: "permanent", redirectType: stringPtr("permanent"), want: models.RedirectTypePermanent},
+		{name: "case and whitespace are normalized", redirectType: stringPtr(" Permanent "), want: models.RedirectTypePermanent},
+		{name: "temporary", redirectType: stringPtr("temporary"), want: models.RedirectTypeTemporary},
+		{name: "unknown value falls back to temporary", redirectType: stringPtr("301"), want: models.RedirectTypeTemporary, warning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host:         "example.com",
+					Link:         "https://example.com/target",
+					RedirectType: tt.redirectType,
+				},
+				Suffix: models.Suffix{Option: "SHORT"},
+			}
+
+			created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			if tt.warning {
+				require.Len(t, created.Warnings, 1)
+				assert.Equal(t, "MALFORMED_PARAM", created.Warnings[0].WarningCode)
+				assert.Equal(t, "durableLinkInfo.redirectType", created.Warnings[0].Field)
+			} else {
+				assert.Empty(t, created.Warnings)
+			}
+
+			resolved, err := service.ResolveShortPath(context.Background(), created.ShortLink, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/target", resolved.LongLink)
+			assert.Equal(t, tt.want, resolved.RedirectType)
+		})
+	}
+}
+
+func TestCreateAndResolve_InterstitialDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		delay   *int
+		want    *int
+		warning bool
+	}{
+		{name: "unset", delay: nil, want: nil},
+		{name: "zero", delay: intPtr(0), want: intPtr(0)},
+		{name: "within range", delay: intPtr(2500), want: intPtr(2500)},
+		{name: "upper bound", delay: intPtr(10000), want: intPtr(10000)},
+		{name: "negative is dropped", delay: intPtr(-1), warning: true},
+		{name: "above upper bound is dropped", delay: intPtr(10001), warning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host:                "example.com",
+					Link:                "https://example.com/target",
+					InterstitialDelayMs: tt.delay,
+				},
+				Suffix: models.Suffix{Option: "SHORT"},
+			}
+
+			created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			if tt.warning {
+				require.Len(t, created.Warnings, 1)
+				assert.Equal(t, "MALFORMED_PARAM", created.Warnings[0].WarningCode)
+				assert.Equal(t, "durableLinkInfo.interstitialDelayMs", created.Warnings[0].Field)
+			} else {
+				assert.Empty(t, created.Warnings)
+			}
+
+			resolved, err := service.ResolveShortPath(context.Background(), created.ShortLink, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resolved.InterstitialDelayMs)
+		})
+	}
+}
+
+func TestValidateLinkParameters_AndroidPackageName(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	tests := []struct {
+		name    string
+		pkg     string
+		want    *string
+		warning bool
+	}{
+		{name: "valid", pkg: "com.example.app", want: stringPtr("com.example.app")},
+		{name: "underscores and digits", pkg: "com.example_2.app3", want: stringPtr("com.example_2.app3")},
+		{name: "trailing space is trimmed", pkg: "com.example.app ", want: stringPtr("com.example.app")},
+		{name: "no dot", pkg: "example", warning: true},
+		{name: "double dot", pkg: "Com..App", warning: true},
+		{name: "segment starting with digit", pkg: "com.1example", warning: true},
+		{name: "empty", pkg: "", warning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dl := models.DurableLink{
+				Link: "https://example.com/target",
+				AndroidParameters: models.AndroidParameters{
+					AndroidPackageName: stringPtr(tt.pkg),
+				},
+			}
+			warnings := service.validateLinkParameters(&dl)
+
+			assert.Equal(t, tt.want, dl.AndroidParameters.AndroidPackageName)
+			if !tt.warning {
+				assert.Empty(t, warnings)
+				return
+			}
+			require.Len(t, warnings, 1)
+			assert.Equal(t, "MALFORMED_PARAM", warnings[0].WarningCode)
+			assert.Equal(t, "durableLinkInfo.androidParameters.androidPackageName", warnings[0].Field)
+		})
+	}
+}
+
+func TestCreateDurableLink_MalformedDefaultAndroidPackage(t *testing.T) {
+	service, db := setupTestService(t)
+
+	cfg := defaultTenantCfg
+	cfg.DefaultAndroidPackage = stringPtr("Com..App ")
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "UNGUESSABLE",
+		},
+	}
+
+	result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+	require.NoError(t, err)
+	assert.Empty(t, result.Warnings)
+
+	var stored models.DurableLinkDB
+	require.NoError(t, db.First(&stored).Error)
+	assert.Nil(t, stored.AndroidPackageName)
+}
+
+func TestCreateDurableLink_NestedShortLink(t *testing.T) {
+	newParams := func(link string) models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: link,
+			},
+			Suffix: models.Suffix{
+				Option: "UNGUESSABLE",
+			},
+		}
+	}
+
+	t.Run("warns about double shortening", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		inner, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/target"), nil, defaultTenantCfg)
+		require.NoError(t, err)
+
+		outer, err := service.CreateDurableLink(context.Background(), newParams(inner.ShortLink), nil, defaultTenantCfg)
+		require.NoError(t, err)
+		require.Len(t, outer.Warnings, 1)
+		assert.Equal(t, "DOUBLE_SHORTENED", outer.Warnings[0].WarningCode)
+		assert.Equal(t, "durableLinkInfo.link", outer.Warnings[0].Field)
+
+		resolved, err := service.ResolveShortPath(context.Background(), outer.ShortLink, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Equal(t, inner.ShortLink, resolved.LongLink)
+	})
+
+	t.Run("unwraps when enabled", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.UnwrapNestedShortLinks = true
+
+		inner, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/target"), nil, cfg)
+		require.NoError(t, err)
+
+		outer, err := service.CreateDurableLink(context.Background(), newParams(inner.ShortLink), nil, cfg)
+		require.NoError(t, err)
+		assert.Empty(t, outer.Warnings)
+
+		resolved, err := service.ResolveShortPath(context.Background(), outer.ShortLink, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", resolved.LongLink)
+	})
+
+	t.Run("password protected links are not unwrapped", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.UnwrapNestedShortLinks = true
+
+		protected := newParams("https://example.com/target")
+		protected.Password = "secret"
+		inner, err := service.CreateDurableLink(context.Background(), protected, nil, cfg)
+		require.NoError(t, err)
+
+		outer, err := service.CreateDurableLink(context.Background(), newParams(inner.ShortLink), nil, cfg)
+		require.NoError(t, err)
+		require.Len(t, outer.Warnings, 1)
+		assert.Equal(t, "DOUBLE_SHORTENED", outer.Warnings[0].WarningCode)
+	})
+
+	t.Run("unwrapped targets are checked like requested ones", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		// Stored under settings that have since changed
+		require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "denied", Link: "https://evil.com/login"}).Error)
+		require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "tracked", Link: "https://example.com/target?fbclid=abc&id=1"}).Error)
+
+		cfg := defaultTenantCfg
+		cfg.UnwrapNestedShortLinks = true
+		cfg.AllowAllDomains = true
+		cfg.DomainAllowList = nil
+		cfg.DomainDenyList = []string{"evil.com"}
+		cfg.StripQueryParams = []string{"fbclid"}
+
+		_, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/denied"), nil, cfg)
+		assert.ErrorIs(t, err, ErrDomainDenied)
+
+		outer, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/tracked"), nil, cfg)
+		require.NoError(t, err)
+		require.Len(t, outer.Warnings, 1)
+		assert.Equal(t, "QUERY_PARAMS_STRIPPED", outer.Warnings[0].WarningCode)
+
+		resolved, err := service.ResolveShortPath(context.Background(), outer.ShortLink, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target?id=1", resolved.LongLink)
+
+		cfg.RejectPrivateTargets = true
+		require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "internal", Link: "http://127.0.0.1/admin"}).Error)
+		_, err = service.CreateDurableLink(context.Background(), newParams("https://example.com/internal"), nil, cfg)
+		assert.ErrorIs(t, err, ErrPrivateTarget)
+	})
+
+	t.Run("unknown paths on the short domain are not flagged", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/pricing"), nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Empty(t, result.Warnings)
+	})
+}
+
+func TestValidateCreateRequest(t *testing.T) {
+	// No repository: validation must never reach storage
+	service := NewLinkService(nil)
+
+	newParams := func() models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			Suffix: models.Suffix{
+				Option: "UNGUESSABLE",
+			},
+		}
+	}
+
+	t.Run("valid request has no warnings", func(t *testing.T) {
+		warnings, err := service.ValidateCreateRequest(newParams(), defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+
+	warningTests := []struct {
+		name      string
+		modify    func(*models.CreateDurableLinkRequest)
+		tenantCfg func(TenantConfig) TenantConfig
+		wantCode  string
+		wantField string
+	}{
+		{
+			name: "malformed fallback link",
+			modify: func(p *models.CreateDurableLinkRequest) {
+				p.DurableLinkInfo.AndroidParameters.AndroidFallbackLink = stringPtr("not-a-valid-url")
+			},
+			wantCode:  "MALFORMED_PARAM",
+			wantField: "durableLinkInfo.androidParameters.androidFallbackLink",
+		},
+		{
+			name: "unrecognized itunes param",
+			modify: func(p *models.CreateDurableLinkRequest) {
+				p.DurableLinkInfo.IosParameters.IOSAppStoreId = int64Ptr(123456789)
+				p.DurableLinkInfo.AnalyticsInfo.ItunesConnectAnalytics.At = stringPtr("affiliate")
+			},
+			wantCode:  "UNRECOGNIZED_PARAM",
+			wantField: "durableLinkInfo.analyticsInfo.itunesConnectAnalytics.at",
+		},
+		{
+			name: "invalid suffix option",
+			modify: func(p *models.CreateDurableLinkRequest) {
+				p.Suffix.Option = "TINY"
+			},
+			wantCode:  "INVALID_SUFFIX_OPTION",
+			wantField: "suffix.option",
+		},
+		{
+			name: "default applied",
+			tenantCfg: func(c TenantConfig) TenantConfig {
+				c.DefaultIOSAppStoreId = int64Ptr(123456789)
+				return c
+			},
+			wantCode:  "DEFAULT_APPLIED",
+			wantField: "durableLinkInfo.iosParameters.iosAppStoreId",
+		},
+	}
+
+	for _, tt := range warningTests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := newParams()
+			if tt.modify != nil {
+				tt.modify(&params)
+			}
+			cfg := defaultTenantCfg
+			if tt.tenantCfg != nil {
+				cfg = tt.tenantCfg(cfg)
+			}
+
+			warnings, err := service.ValidateCreateRequest(params, cfg)
+			require.NoError(t, err)
+			require.Len(t, warnings, 1)
+			assert.Equal(t, tt.wantCode, warnings[0].WarningCode)
+			assert.Equal(t, tt.wantField, warnings[0].Field)
+		})
+	}
+
+	errorTests := []struct {
+		name      string
+		modify    func(*models.CreateDurableLinkRequest)
+		tenantCfg func(TenantConfig) TenantConfig
+		wantError error
+	}{
+		{
+			name: "domain not allowed",
+			modify: func(p *models.CreateDurableLinkRequest) {
+				p.DurableLinkInfo.Link = "https://evil.com/target"
+			},
+			wantError: ErrDomainLinkNotAllowed,
+		},
+		{
+			name: "invalid url scheme",
+			modify: func(p *models.CreateDurableLinkRequest) {
+				p.URLScheme = stringPtr("ftp")
+			},
+			wantError: ErrInvalidURLScheme,
+		},
+		{
+			name: "custom path too short",
+			modify: func(p *models.CreateDurableLinkRequest) {
+				p.CustomPath = "ab"
+			},
+			wantError: ErrCustomPathTooShort,
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := newParams()
+			if tt.modify != nil {
+				tt.modify(&params)
+			}
+			cfg := defaultTenantCfg
+			if tt.tenantCfg != nil {
+				cfg = tt.tenantCfg(cfg)
+			}
+
+			warnings, err := service.ValidateCreateRequest(params, cfg)
+			assert.ErrorIs(t, err, tt.wantError)
+			assert.Nil(t, warnings)
+		})
+	}
+
+	t.Run("strict validation fails on malformed params", func(t *testing.T) {
+		cfg := defaultTenantCfg
+		cfg.StrictValidation = true
+
+		params := newParams()
+		params.DurableLinkInfo.Locale = stringPtr("not a locale")
+
+		_, err := service.ValidateCreateRequest(params, cfg)
+		var validationErrs models.ValidationErrors
+		require.ErrorAs(t, err, &validationErrs)
+		require.Len(t, validationErrs.Errors, 1)
+		assert.Equal(t, "durableLinkInfo.locale", validationErrs.Errors[0].Field)
+	})
+
+	t.Run("invalid host", func(t *testing.T) {
+		params := newParams()
+		params.DurableLinkInfo.Host = ""
+
+		_, err := service.ValidateCreateRequest(params, defaultTenantCfg)
+		assert.Error(t, err)
+	})
+}
+
+func TestCreateDurableLink_OutputHost(t *testing.T) {
+	cfg := defaultTenantCfg
+	cfg.HostAliases = map[string]string{"Ex.mp": "example.com"}
+
+	newParams := func(outputHost string) models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			Suffix: models.Suffix{
+				Option: "SHORT",
+			},
+			OutputHost: &outputHost,
+		}
+	}
+
+	t.Run("alias is used for the returned link", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), newParams("ex.mp"), nil, cfg)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result.ShortLink, "https://ex.mp/"))
+		assert.Equal(t, "ex.mp", result.Details.Host)
+
+		var stored models.DurableLinkDB
+		require.NoError(t, db.First(&stored).Error)
+		assert.Equal(t, "example.com", stored.Host)
+		assert.Equal(t, result.Details.Path, stored.Path)
+
+		resolved, err := service.ResolveShortPath(context.Background(), result.ShortLink, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", resolved.LongLink)
+
+		resolved, err = service.ResolveShortPath(context.Background(), "https://example.com/"+stored.Path, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", resolved.LongLink)
+	})
+
+	t.Run("re-used links use the alias", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		plain := newParams("")
+		plain.OutputHost = nil
+		first, err := service.CreateDurableLink(context.Background(), plain, nil, cfg)
+		require.NoError(t, err)
+
+		aliased, err := service.CreateDurableLink(context.Background(), newParams("ex.mp"), nil, cfg)
+		require.NoError(t, err)
+		assert.True(t, aliased.Reused)
+		assert.Equal(t, first.Details.Path, aliased.Details.Path)
+		assert.Equal(t, "ex.mp", aliased.Details.Host)
+	})
+
+	t.Run("storage host is allowed", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), newParams("example.com"), nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "example.com", result.Details.Host)
+	})
+
+	t.Run("other hosts are rejected", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		result, err := service.CreateDurableLink(context.Background(), newParams("evil.link"), nil, cfg)
+		assert.ErrorIs(t, err, ErrOutputHostNotAllowed)
+		assert.Nil(t, result)
+
+		var count int64
+		db.Model(&models.DurableLinkDB{}).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("aliases of other hosts are rejected", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		otherCfg := cfg
+		otherCfg.HostAliases = map[string]string{"ex.mp": "other.com"}
+
+		_, err := service.CreateDurableLink(context.Background(), newParams("ex.mp"), nil, otherCfg)
+		assert.ErrorIs(t, err, ErrOutputHostNotAllowed)
+	})
+}
+
+func TestCreateDurableLink_PathUniquenessScope(t *testing.T) {
+	projectA := uuid.New()
+	projectB := uuid.New()
+
+	newParams := func(link string) models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: link,
+			},
+			CustomPath: "promo",
+		}
+	}
+
+	t.Run("per project paths resolve per project", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.PathUniquenessScope = PathUniquenessPerProject
+
+		a, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/a"), &projectA, cfg)
+		require.NoError(t, err)
+		b, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/b"), &projectB, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, a.ShortLink, b.ShortLink)
+
+		resolved, err := service.ResolveShortPath(context.Background(), a.ShortLink, &projectA, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/a", resolved.LongLink)
+
+		resolved, err = service.ResolveShortPath(context.Background(), b.ShortLink, &projectB, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/b", resolved.LongLink)
+
+		// Still unique within a project
+		_, err = service.CreateDurableLink(context.Background(), newParams("https://example.com/c"), &projectA, cfg)
+		assert.ErrorIs(t, err, ErrCustomPathTaken)
+	})
+
+	t.Run("global paths collide across projects", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		_, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/a"), &projectA, defaultTenantCfg)
+		require.NoError(t, err)
+
+		result, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/b"), &projectB, defaultTenantCfg)
+		assert.ErrorIs(t, err, ErrCustomPathTaken)
+		assert.Nil(t, result)
+	})
+
+	t.Run("database rejects a global duplicate", func(t *testing.T) {
+		_, db := setupTestService(t)
+
+		a, b := projectA.String(), projectB.String()
+		require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "promo", Link: "https://example.com/a", ProjectID: &a}).Error)
+		assert.Error(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "promo", Link: "https://example.com/b", ProjectID: &b}).Error)
+		assert.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "promo", Link: "https://example.com/b", ProjectID: &b, PathScope: b}).Error)
+	})
+}
+
+func TestDeleteLink_RecyclePaths(t *testing.T) {
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "UNGUESSABLE",
+		},
+	}
+
+	newService := func(t *testing.T, gen PathGenerator) *linkService {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		require.NoError(t, err)
+		require.NoError(t, db.AutoMigrate(&models.DurableLinkDB{}, &models.ReleasedPathDB{}, &models.LinkTagDB{}, &models.DeletedPathDB{}))
+		return NewLinkService(repository.NewLinkRepository(db), WithPathGenerator(gen))
+	}
+
+	t.Run("deleted path is re-used", func(t *testing.T) {
+		cfg := defaultTenantCfg
+		cfg.UnguessablePathLength = 6
+		cfg.RecyclePaths = true
+		service := newService(t, &fakePathGenerator{paths: []string{"first1", "secnd2"}})
+
+		first, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		require.NoError(t, service.DeleteLink(context.Background(), first.ShortLink, nil, cfg))
+
+		_, err = service.ResolveShortPath(context.Background(), first.ShortLink, nil, cfg)
+		assert.ErrorIs(t, err, repository.ErrLinkGone)
+
+		other := params
+		other.DurableLinkInfo.Link = "https://example.com/other"
+		again, err := service.CreateDurableLink(context.Background(), other, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, first.ShortLink, again.ShortLink)
+
+		resolved, err := service.ResolveShortPath(context.Background(), again.ShortLink, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/other", resolved.LongLink)
+
+		// The pool is empty again, so the generator is used
+		third, err := service.CreateDurableLink(context.Background(), other, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/secnd2", third.ShortLink)
+	})
+
+	t.Run("paths are not re-used without the option", func(t *testing.T) {
+		cfg := defaultTenantCfg
+		cfg.UnguessablePathLength = 6
+		service := newService(t, &fakePathGenerator{paths: []string{"first1", "secnd2"}})
+
+		first, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		require.NoError(t, service.DeleteLink(context.Background(), first.ShortLink, nil, cfg))
+
+		cfg.RecyclePaths = true
+		again, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/secnd2", again.ShortLink)
+	})
+
+	t.Run("deleting a missing link fails", func(t *testing.T) {
+		service := newService(t, &fakePathGenerator{})
+
+		err := service.DeleteLink(context.Background(), "https://example.com/missing", nil, defaultTenantCfg)
+		assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+	})
+}
+
+func TestCreateDurableLink_RelativeLinks(t *testing.T) {
+	tests := []struct {
+		name        string
+		link        string
+		expectError error
+	}{
+		{name: "absolute", link: "https://example.com/target"},
+		{name: "relative", link: "/relative", expectError: ErrRelativeLink},
+		{name: "protocol relative", link: "//example.com/target", expectError: ErrRelativeLink},
+	}
+
+	for _, tt := range tests {
+		t.Run("link "+tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: tt.link,
+				},
+				Suffix: models.Suffix{Option: "SHORT"},
+			}
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+			assert.Empty(t, result.Warnings)
+		})
+
+		t.Run("fallback "+tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			dl := models.DurableLink{
+				Link:          "https://example.com/target",
+				IosParameters: models.IOSParameters{IOSFallbackLink: stringPtr(tt.link)},
+			}
+			warnings := service.validateLinkParameters(&dl)
+			if tt.expectError != nil {
+				require.Len(t, warnings, 1)
+				assert.Equal(t, "MALFORMED_PARAM", warnings[0].WarningCode)
+				assert.Equal(t, "durableLinkInfo.iosParameters.iosFallbackLink", warnings[0].Field)
+				assert.Nil(t, dl.IosParameters.IOSFallbackLink)
+				return
+			}
+			assert.Empty(t, warnings)
+			assert.Equal(t, stringPtr(tt.link), dl.IosParameters.IOSFallbackLink)
+		})
+	}
+}
+
+func TestCreateDurableLink_StripQueryParams(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	cfg := defaultTenantCfg
+	cfg.StripQueryParams = []string{"fbclid", "gclid"}
+
+	t.Run("listed params are stripped", func(t *testing.T) {
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target?fbclid=abc&id=7&gclid=xyz",
+			},
+			Suffix: models.Suffix{Option: "SHORT"},
+		}
+
+		created, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		require.Len(t, created.Warnings, 1)
+		assert.Equal(t, "QUERY_PARAMS_STRIPPED", created.Warnings[0].WarningCode)
+		assert.Equal(t, "durableLinkInfo.link", created.Warnings[0].Field)
+		assert.Contains(t, created.Warnings[0].WarningMessage, "fbclid, gclid")
+
+		resolved, err := service.ResolveShortPath(context.Background(), created.ShortLink, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target?id=7", resolved.LongLink)
+	})
+
+	t.Run("other params are kept", func(t *testing.T) {
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target?id=7&ref=mail",
+			},
+			Suffix: models.Suffix{Option: "SHORT"},
+		}
+
+		created, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.Empty(t, created.Warnings)
+
+		resolved, err := service.ResolveShortPath(context.Background(), created.ShortLink, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target?id=7&ref=mail", resolved.LongLink)
+	})
+}
+
+func TestCreateDurableLink_EmptyDomainAllowList(t *testing.T) {
+	tests := []struct {
+		name            string
+		allowList       []string
+		allowAll        bool
+		link            string
+		expectError     error
+		unexpectedError error
+	}{
+		{name: "empty list denies all", link: "https://example.com/target", expectError: ErrEmptyDomainAllowList},
+		{name: "empty list with allow all", allowAll: true, link: "https://anything.example.org/target"},
+		{name: "allow all does not widen a non-empty list", allowList: []string{"example.com"}, allowAll: true, link: "https://other.com/target", expectError: ErrDomainLinkNotAllowed, unexpectedError: ErrEmptyDomainAllowList},
+		{name: "non-empty list", allowList: []string{"example.com"}, link: "https://example.com/target"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			cfg := defaultTenantCfg
+			cfg.DomainAllowList = tt.allowList
+			cfg.AllowAllDomains = tt.allowAll
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: tt.link,
+				},
+				Suffix: models.Suffix{Option: "SHORT"},
+			}
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				// Callers matching the generic error keep working
+				assert.ErrorIs(t, err, ErrDomainLinkNotAllowed)
+				if tt.unexpectedError != nil {
+					assert.NotErrorIs(t, err, tt.unexpectedError)
+				}
+				assert.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, result.ShortLink)
+		})
+	}
+}
+
+func TestCreateDurableLink_DomainDenyList(t *testing.T) {
+	tests := []struct {
+		name            string
+		allowList       []string
+		allowAll        bool
+		denyList        []string
+		link            string
+		ruleLink        string
+		expectError     error
+		unexpectedError error
+	}{
+		{name: "denied domain", allowAll: true, denyList: []string{"evil.com"}, link: "https://evil.com/login", expectError: ErrDomainDenied},
+		{name: "denied subdomain", allowAll: true, denyList: []string{"evil.com"}, link: "https://login.evil.com/", expectError: ErrDomainDenied},
+		{name: "deny wins over allow", allowList: []string{"example.com", "evil.com"}, denyList: []string{"evil.com"}, link: "https://evil.com/login", expectError: ErrDomainDenied},
+		{name: "not allowed is reported before denied", allowList: []string{"example.com"}, denyList: []string{"evil.com"}, link: "https://evil.com/login", expectError: ErrDomainLinkNotAllowed, unexpectedError: ErrDomainDenied},
+		{name: "denied rule link", allowAll: true, denyList: []string{"evil.com"}, link: "https://example.com/target", ruleLink: "https://evil.com/login", expectError: ErrDomainDenied},
+		{name: "other domains are allowed", allowAll: true, denyList: []string{"evil.com"}, link: "https://notevil.com/target"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := setupTestService(t)
+
+			cfg := defaultTenantCfg
+			cfg.DomainAllowList = tt.allowList
+			cfg.AllowAllDomains = tt.allowAll
+			cfg.DomainDenyList = tt.denyList
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: tt.link,
+				},
+				Suffix: models.Suffix{Option: "SHORT"},
+			}
+			if tt.ruleLink != "" {
+				params.DurableLinkInfo.Rules = []models.ResolutionRule{{Countries: []string{"US"}, Link: tt.ruleLink}}
+			}
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				if tt.unexpectedError != nil {
+					assert.NotErrorIs(t, err, tt.unexpectedError)
+				}
+				assert.Equal(t, http.StatusBadRequest, HTTPStatusForError(err))
+				assert.Nil(t, result)
+
+				var count int64
+				db.Model(&models.DurableLinkDB{}).Count(&count)
+				assert.Zero(t, count)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, result.ShortLink)
+		})
+	}
+}
+
+func TestResolveHostPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		path        string
+		expectError error
+	}{
+		{name: "host and path", host: "example.com", path: "/abc123"},
+		{name: "path without leading slash", host: "example.com", path: "abc123"},
+		{name: "preview host is normalized", host: "preview.example.com", path: "/abc123"},
+		{name: "hyphenated preview host is normalized", host: "example-preview.com", path: "/abc123"},
+		{name: "leading double slash", host: "example.com", path: "//abc123"},
+		{name: "trailing slash", host: "example.com", path: "/abc123/"},
+		{name: "empty path", host: "example.com", path: "/", expectError: ErrInvalidPathFormat},
+		{name: "multiple segments", host: "example.com", path: "/abc123/extra", expectError: ErrInvalidPathFormat},
+		{name: "multiple segments separated by double slash", host: "example.com", path: "/abc123//extra", expectError: ErrInvalidPathFormat},
+		{name: "link not found", host: "example.com", path: "/notfound", expectError: repository.ErrLinkNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := setupTestService(t)
+			require.NoError(t, db.Create(&models.DurableLinkDB{
+				Host: "example.com",
+				Path: "abc123",
+				Link: "https://example.com/target",
+			}).Error)
+
+			result, err := service.ResolveHostPath(context.Background(), tt.host, tt.path, nil, defaultTenantCfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/target", result.LongLink)
+		})
+	}
+
+	t.Run("path prefix", func(t *testing.T) {
+		service, db := setupTestService(t)
+		require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "abc123", Link: "https://example.com/target"}).Error)
+
+		cfg := defaultTenantCfg
+		cfg.PathPrefix = "/l"
+
+		result, err := service.ResolveHostPath(context.Background(), "example.com", "/l/abc123", nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", result.LongLink)
+
+		_, err = service.ResolveHostPath(context.Background(), "example.com", "/abc123", nil, cfg)
+		assert.ErrorIs(t, err, ErrInvalidPathFormat)
+	})
+
+	t.Run("signature required", func(t *testing.T) {
+		service, db := setupTestService(t)
+		require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "abc123", Link: "https://example.com/target"}).Error)
+
+		cfg := defaultTenantCfg
+		cfg.RequireSignature = true
+		cfg.Secret = "test-secret"
+
+		_, err := service.ResolveHostPath(context.Background(), "example.com", "/abc123", nil, cfg)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+}
+
+func TestResolveShortPath_GoneVersusNotFound(t *testing.T) {
+	service, db := setupTestService(t)
+
+	links := []models.DurableLinkDB{
+		{Host: "example.com", Path: "active", Link: "https://example.com/target", ClickCount: 2, MaxClicks: int64Ptr(3)},
+		{Host: "example.com", Path: "exhausted", Link: "https://example.com/target", ClickCount: 3, MaxClicks: int64Ptr(3)},
+		{Host: "example.com", Path: "deleted", Link: "https://example.com/target"},
+	}
+	for i := range links {
+		require.NoError(t, db.Create(&links[i]).Error)
+	}
+	require.NoError(t, service.DeleteLink(context.Background(), "https://example.com/deleted", nil, defaultTenantCfg))
+
+	tests := []struct {
+		name        string
+		rawURL      string
+		expectError error
+		status      int
+	}{
+		{name: "below click limit", rawURL: "https://example.com/active", status: http.StatusOK},
+		{name: "click limit reached", rawURL: "https://example.com/exhausted", expectError: repository.ErrLinkGone, status: http.StatusGone},
+		{name: "never existed", rawURL: "https://example.com/missing", expectError: repository.ErrLinkNotFound, status: http.StatusNotFound},
+		{name: "deleted", rawURL: "https://example.com/deleted", expectError: repository.ErrLinkDeleted, status: http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.ResolveShortPath(context.Background(), tt.rawURL, nil, defaultTenantCfg)
+			assert.Equal(t, tt.status, HTTPStatusForError(err))
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/target", result.LongLink)
+		})
+	}
+}
+
+func TestResolveShortPath_MaxClicks(t *testing.T) {
+	service, db := setupTestService(t)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix:    models.Suffix{Option: "SHORT"},
+		MaxClicks: int64Ptr(1),
+	}
+	created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+
+	resolved, err := service.ResolveShortPath(context.Background(), created.ShortLink, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/target", resolved.LongLink)
+
+	_, err = service.ResolveShortPath(context.Background(), created.ShortLink, nil, defaultTenantCfg)
+	assert.ErrorIs(t, err, repository.ErrClickLimitReached)
+	assert.ErrorIs(t, err, repository.ErrLinkGone)
+
+	var link models.DurableLinkDB
+	require.NoError(t, db.Where("path = ?", created.Details.Path).First(&link).Error)
+	assert.Equal(t, int64(1), link.ClickCount)
+}
+
+func TestResolveShortPath_SkipAnalytics(t *testing.T) {
+	newLink := func(t *testing.T) (*linkService, *gorm.DB, *int) {
+		service, db := setupTestService(t)
+		require.NoError(t, db.Create(&models.DurableLinkDB{
+			Host:      "example.com",
+			Path:      "abc123",
+			Link:      "https://example.com/target",
+			MaxClicks: int64Ptr(1),
+		}).Error)
+
+		updates := 0
+		require.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:count_updates", func(*gorm.DB) { updates++ }))
+		return service, db, &updates
+	}
+	clicks := func(t *testing.T, db *gorm.DB) int64 {
+		var link models.DurableLinkDB
+		require.NoError(t, db.Where("path = ?", "abc123").First(&link).Error)
+		return link.ClickCount
+	}
+
+	t.Run("skipped", func(t *testing.T) {
+		service, db, updates := newLink(t)
+
+		for range 2 {
+			_, err := service.ResolveShortPath(context.Background(), "https://example.com/abc123", nil, defaultTenantCfg, WithSkipAnalytics())
+			require.NoError(t, err)
+			_, err = service.ResolveForPlatform(context.Background(), "https://example.com/abc123", models.PlatformIOS, nil, defaultTenantCfg, WithSkipAnalytics())
+			require.NoError(t, err)
+		}
+
+		assert.Zero(t, *updates)
+		assert.Zero(t, clicks(t, db))
+	})
+
+	t.Run("counted", func(t *testing.T) {
+		service, db, updates := newLink(t)
+
+		_, err := service.ResolveShortPath(context.Background(), "https://example.com/abc123", nil, defaultTenantCfg)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, *updates)
+		assert.Equal(t, int64(1), clicks(t, db))
+
+		// Skipping analytics doesn't bypass the click limit
+		_, err = service.ResolveShortPath(context.Background(), "https://example.com/abc123", nil, defaultTenantCfg, WithSkipAnalytics())
+		assert.ErrorIs(t, err, repository.ErrClickLimitReached)
+	})
+}
+
+func TestResolveProtected_WrongPasswordIsNotCounted(t *testing.T) {
+	service, db := setupTestService(t)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix:    models.Suffix{Option: "SHORT"},
+		Password:  "secret",
+		MaxClicks: int64Ptr(1),
+	}
+	created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+
+	_, err = service.ResolveProtected(context.Background(), created.ShortLink, "wrong", nil, defaultTenantCfg)
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	_, err = service.ResolveShortPath(context.Background(), created.ShortLink, nil, defaultTenantCfg)
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+
+	var link models.DurableLinkDB
+	require.NoError(t, db.Where("path = ?", created.Details.Path).First(&link).Error)
+	assert.Zero(t, link.ClickCount)
+
+	_, err = service.ResolveProtected(context.Background(), created.ShortLink, "secret", nil, defaultTenantCfg)
+	require.NoError(t, err)
+	_, err = service.ResolveProtected(context.Background(), created.ShortLink, "secret", nil, defaultTenantCfg)
+	assert.ErrorIs(t, err, repository.ErrClickLimitReached)
+}
+
+func TestResolveShortPath_HostAliases(t *testing.T) {
+	service, db := setupTestService(t)
+
+	require.NoError(t, db.Create(&models.DurableLinkDB{
+		Host: "olddomain.com",
+		Path: "abc",
+		Link: "https://example.com/target",
+	}).Error)
+
+	cfg := defaultTenantCfg
+	cfg.HostAliases = map[string]string{"newdomain.com": "olddomain.com"}
+
+	tests := []struct {
+		name        string
+		rawURL      string
+		expectError error
+	}{
+		{name: "stored host still resolves", rawURL: "https://olddomain.com/abc"},
+		{name: "aliased host resolves to the stored host", rawURL: "https://newdomain.com/abc"},
+		{name: "aliased host is case-insensitive", rawURL: "https://NewDomain.com/abc"},
+		{name: "preview of aliased host", rawURL: "https://preview.newdomain.com/abc"},
+		{name: "hyphenated preview of aliased host", rawURL: "https://newdomain-preview.com/abc"},
+		{name: "unaliased host is not found", rawURL: "https://otherdomain.com/abc", expectError: repository.ErrLinkNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.ResolveShortPath(context.Background(), tt.rawURL, nil, cfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/target", result.LongLink)
+		})
+	}
+}
+
+func TestCreateDurableLink_MaxLinks(t *testing.T) {
+	projectID := uuid.New()
+	cfg := defaultTenantCfg
+	cfg.MaxLinks = int64Ptr(2)
+
+	newParams := func(link string) models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: link,
+			},
+			Suffix: models.Suffix{
+				Option: "SHORT",
+			},
+		}
+	}
+
+	service, db := setupTestService(t)
+	countLinks := func() int64 {
+		var count int64
+		db.Model(&models.DurableLinkDB{}).Count(&count)
+		return count
+	}
+
+	for _, link := range []string{"https://example.com/one", "https://example.com/two"} {
+		_, err := service.CreateDurableLink(context.Background(), newParams(link), &projectID, cfg)
+		require.NoError(t, err)
+	}
+
+	t.Run("create at the quota fails", func(t *testing.T) {
+		result, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/three"), &projectID, cfg)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.Equal(t, http.StatusForbidden, HTTPStatusForError(err))
+		assert.Nil(t, result)
+		assert.Equal(t, int64(2), countLinks())
+	})
+
+	t.Run("custom path at the quota fails", func(t *testing.T) {
+		params := newParams("https://example.com/three")
+		params.CustomPath = "custom"
+		_, err := service.CreateDurableLink(context.Background(), params, &projectID, cfg)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.Equal(t, int64(2), countLinks())
+	})
+
+	t.Run("create over a lowered quota fails", func(t *testing.T) {
+		lowered := cfg
+		lowered.MaxLinks = int64Ptr(1)
+		_, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/three"), &projectID, lowered)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+	})
+
+	t.Run("reusing an existing link at the quota succeeds", func(t *testing.T) {
+		result, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/one"), &projectID, cfg)
+		require.NoError(t, err)
+		assert.True(t, result.Reused)
+	})
+
+	t.Run("other projects have their own quota", func(t *testing.T) {
+		otherProject := uuid.New()
+		_, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/three"), &otherProject, cfg)
+		require.NoError(t, err)
+	})
+
+	t.Run("no quota is unlimited", func(t *testing.T) {
+		_, err := service.CreateDurableLink(context.Background(), newParams("https://example.com/four"), &projectID, defaultTenantCfg)
+		require.NoError(t, err)
+	})
+}
+
+func TestCreateDurableLink_MaxReuseAge(t *testing.T) {
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "SHORT",
+		},
+	}
+	cfg := defaultTenantCfg
+	cfg.MaxReuseAge = 24 * time.Hour
+
+	t.Run("recent link is reused", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		first, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+
+		second, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.True(t, second.Reused)
+		assert.Equal(t, first.ShortLink, second.ShortLink)
+	})
+
+	t.Run("old link is skipped", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		first, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		db.Model(&models.DurableLinkDB{}).Where("path = ?", first.Details.Path).
+			UpdateColumn("created_at", time.Now().Add(-48*time.Hour))
+
+		second, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.False(t, second.Reused)
+		assert.NotEqual(t, first.ShortLink, second.ShortLink)
+
+		// Without a reuse age the old link is still shared
+		third, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.True(t, third.Reused)
+	})
+}
+
+func TestCreateDurableLink_RequirePlatformConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		dl            models.DurableLink
+		strict        bool
+		defaultPkg    *string
+		expectWarning bool
+	}{
+		{name: "bare link warns", dl: models.DurableLink{Host: "example.com", Link: "https://example.com/target"}, expectWarning: true},
+		{
+			name: "android package",
+			dl: models.DurableLink{Host: "example.com", Link: "https://example.com/target",
+				AndroidParameters: models.AndroidParameters{AndroidPackageName: stringPtr("com.example.app")}},
+		},
+		{
+			name: "ios app store id",
+			dl: models.DurableLink{Host: "example.com", Link: "https://example.com/target",
+				IosParameters: models.IOSParameters{IOSAppStoreId: int64Ptr(123456789)}},
+		},
+		{
+			name: "other platform fallback",
+			dl: models.DurableLink{Host: "example.com", Link: "https://example.com/target",
+				OtherPlatformParameters: models.OtherPlatformParameters{FallbackURL: stringPtr("https://example.com/web")}},
+		},
+		{name: "default package counts", dl: models.DurableLink{Host: "example.com", Link: "https://example.com/target"}, defaultPkg: stringPtr("com.example.app")},
+		{
+			name: "dropped malformed param does not count",
+			dl: models.DurableLink{Host: "example.com", Link: "https://example.com/target",
+				AndroidParameters: models.AndroidParameters{AndroidFallbackLink: stringPtr("not-a-valid-url")}},
+			expectWarning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			cfg := defaultTenantCfg
+			cfg.RequirePlatformConfig = true
+			cfg.DefaultAndroidPackage = tt.defaultPkg
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: tt.dl,
+				Suffix:          models.Suffix{Option: "SHORT"},
+			}
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+			require.NoError(t, err)
+
+			var found bool
+			for _, w := range result.Warnings {
+				if w.WarningCode == "MISSING_PLATFORM_CONFIG" {
+					found = true
+					assert.Equal(t, "durableLinkInfo", w.Field)
+				}
+			}
+			assert.Equal(t, tt.expectWarning, found)
+		})
+	}
+
+	t.Run("strict mode rejects a bare link", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.RequirePlatformConfig = true
+		cfg.StrictValidation = true
+
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{Host: "example.com", Link: "https://example.com/target"},
+			Suffix:          models.Suffix{Option: "SHORT"},
+		}
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		assert.Nil(t, result)
+		var validationErrs models.ValidationErrors
+		require.ErrorAs(t, err, &validationErrs)
+		require.Len(t, validationErrs.Errors, 1)
+		assert.Equal(t, "missing_platform_config", validationErrs.Errors[0].Tag)
+
+		var count int64
+		db.Model(&models.DurableLinkDB{}).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{Host: "example.com", Link: "https://example.com/target"},
+			Suffix:          models.Suffix{Option: "SHORT"},
+		}
+
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.Empty(t, result.Warnings)
+	})
+}
+
+func TestCreateDurableLink_WarnOnCrossDomainFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		ios        models.IOSParameters
+		android    models.AndroidParameters
+		wantFields []string
+	}{
+		{
+			name: "same host",
+			ios:  models.IOSParameters{IOSFallbackLink: stringPtr("https://Example.com/ios")},
+		},
+		{
+			name:       "cross host",
+			ios:        models.IOSParameters{IOSFallbackLink: stringPtr("https://other.com/ios")},
+			wantFields: []string{"durableLinkInfo.iosParameters.iosFallbackLink"},
+		},
+		{
+			name:       "subdomain is another host",
+			ios:        models.IOSParameters{IOSIpadFallbackLink: stringPtr("https://www.example.com/ipad")},
+			wantFields: []string{"durableLinkInfo.iosParameters.iosIpadFallbackLink"},
+		},
+		{
+			name:       "fallback lists",
+			android:    models.AndroidParameters{AndroidFallbackLinks: []string{"https://example.com/apk", "https://mirror.com/apk"}},
+			wantFields: []string{"durableLinkInfo.androidParameters.androidFallbackLinks[1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			cfg := defaultTenantCfg
+			cfg.WarnOnCrossDomainFallback = true
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host:              "example.com",
+					Link:              "https://example.com/target",
+					IosParameters:     tt.ios,
+					AndroidParameters: tt.android,
+				},
+				Suffix: models.Suffix{Option: "SHORT"},
+			}
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+			require.NoError(t, err)
+
+			var fields []string
+			for _, w := range result.Warnings {
+				if w.WarningCode == "CROSS_DOMAIN_FALLBACK" {
+					fields = append(fields, w.Field)
+				}
+			}
+			assert.Equal(t, tt.wantFields, fields)
+
+			// The warning is opt-in
+			result, err = service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			assert.Empty(t, result.Warnings)
+		})
+	}
+}
+
+func TestCreateAndResolve_PathCase(t *testing.T) {
+	tests := []struct {
+		name        string
+		pathCase    PathCase
+		charset     *regexp.Regexp
+		fakePath    string
+		storedPath  string
+		resolveWith string
+		resolves    bool
+	}{
+		{name: "mixed", pathCase: PathCaseMixed, charset: regexp.MustCompile(`^[A-Za-z0-9]+$`), fakePath: "AbCd1", storedPath: "AbCd1", resolveWith: "abcd1"},
+		{name: "lower", pathCase: PathCaseLower, charset: regexp.MustCompile(`^[a-z0-9]+$`), fakePath: "AbCd1", storedPath: "abcd1", resolveWith: "ABCD1", resolves: true},
+		{name: "upper", pathCase: PathCaseUpper, charset: regexp.MustCompile(`^[A-Z0-9]+$`), fakePath: "AbCd1", storedPath: "ABCD1", resolveWith: "abcd1", resolves: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultTenantCfg
+			cfg.PathCase = tt.pathCase
+
+			t.Run("generated character set", func(t *testing.T) {
+				service, _ := setupTestService(t)
+
+				for i := range 20 {
+					params := models.CreateDurableLinkRequest{
+						DurableLinkInfo: models.DurableLink{
+							Host: "example.com",
+							Link: fmt.Sprintf("https://example.com/target/%d", i),
+						},
+						Suffix: models.Suffix{Option: "UNGUESSABLE"},
+					}
+					result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+					require.NoError(t, err)
+					assert.Regexp(t, tt.charset, result.Details.Path)
+				}
+			})
+
+			t.Run("resolution", func(t *testing.T) {
+				db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+				require.NoError(t, err)
+				require.NoError(t, db.AutoMigrate(&models.DurableLinkDB{}, &models.DeletedPathDB{}))
+				service := NewLinkService(repository.NewLinkRepository(db), WithPathGenerator(&fakePathGenerator{paths: []string{tt.fakePath}}))
+
+				params := models.CreateDurableLinkRequest{
+					DurableLinkInfo: models.DurableLink{
+						Host: "example.com",
+						Link: "https://example.com/target",
+					},
+					Suffix: models.Suffix{Option: "SHORT"},
+				}
+				result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+				require.NoError(t, err)
+				assert.Equal(t, tt.storedPath, result.Details.Path)
+
+				resolved, err := service.ResolveShortPath(context.Background(), "https://example.com/"+tt.storedPath, nil, cfg)
+				require.NoError(t, err)
+				assert.Equal(t, "https://example.com/target", resolved.LongLink)
+
+				resolved, err = service.ResolveShortPath(context.Background(), "https://example.com/"+tt.resolveWith, nil, cfg)
+				if tt.resolves {
+					require.NoError(t, err)
+					assert.Equal(t, "https://example.com/target", resolved.LongLink)
+				} else {
+					assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+				}
+			})
+		})
+	}
+
+	t.Run("custom path and checksum are folded", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.PathCase = PathCaseLower
+		cfg.PathChecksum = true
+
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			CustomPath: "Spring-Sale",
+		}
+		result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, appendPathChecksum("spring-sale", cfg), result.Details.Path)
+		assert.Equal(t, strings.ToLower(result.Details.Path), result.Details.Path)
+		assert.True(t, validPathChecksum(result.Details.Path, PathCaseLower))
+
+		resolved, err := service.ResolveShortPath(context.Background(), "https://example.com/"+strings.ToUpper(result.Details.Path), nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", resolved.LongLink)
+	})
+
+	t.Run("links created before the path case keep resolving", func(t *testing.T) {
+		service, db := setupTestService(t)
+		require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "AbCd1", Link: "https://example.com/legacy"}).Error)
+		require.NoError(t, db.Create(&models.DurableLinkDB{Host: "example.com", Path: "efgh2", Link: "https://example.com/lower"}).Error)
+
+		cfg := defaultTenantCfg
+		cfg.PathCase = PathCaseLower
+
+		resolved, err := service.ResolveShortPath(context.Background(), "https://example.com/AbCd1", nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/legacy", resolved.LongLink)
+
+		resolved, err = service.ResolveShortPath(context.Background(), "https://example.com/EFGH2", nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/lower", resolved.LongLink)
+
+		require.NoError(t, service.DeleteLink(context.Background(), "https://example.com/AbCd1", nil, cfg))
+		require.NoError(t, service.DeleteLink(context.Background(), "https://example.com/EFGH2", nil, cfg))
+
+		var remaining int64
+		db.Model(&models.DurableLinkDB{}).Count(&remaining)
+		assert.Zero(t, remaining)
+	})
+
+	t.Run("released paths of another case stay in the pool", func(t *testing.T) {
+		service, db := setupTestService(t)
+		require.NoError(t, db.Create(&models.ReleasedPathDB{Host: "example.com", Path: "AbCdEfGh", Length: 8}).Error)
+		require.NoError(t, db.Create(&models.ReleasedPathDB{Host: "example.com", Path: "abcd1234", Length: 8}).Error)
+
+		cfg := defaultTenantCfg
+		cfg.PathCase = PathCaseLower
+		cfg.RecyclePaths = true
+
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "https://example.com/target",
+			},
+			Suffix: models.Suffix{Option: "SHORT"},
+		}
+		result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "abcd1234", result.Details.Path)
+
+		var pool []models.ReleasedPathDB
+		require.NoError(t, db.Find(&pool).Error)
+		require.Len(t, pool, 1)
+		assert.Equal(t, "AbCdEfGh", pool[0].Path)
+	})
+}
+
+func TestRandomPathGenerator_PathCaseAlphabet(t *testing.T) {
+	for _, pathCase := range []PathCase{PathCaseMixed, PathCaseLower, PathCaseUpper} {
+		alphabet := pathCase.alphabet()
+		path, err := randomPathGenerator{alphabet: alphabet}.Generate(5000)
+		require.NoError(t, err)
+
+		// Every symbol is drawn directly, none is folded onto another
+		seen := make(map[rune]bool)
+		for _, r := range path {
+			seen[r] = true
+		}
+		assert.Len(t, seen, len(alphabet))
+		for _, r := range alphabet {
+			assert.True(t, seen[r], "symbol %c of %q never generated", r, alphabet)
+		}
+	}
+}
+
+func TestCreateAndResolve_IsUnguessable(t *testing.T) {
+	tests := []struct {
+		name       string
+		option     string
+		customPath string
+		want       bool
+	}{
+		{name: "short", option: "SHORT", want: false},
+		{name: "unguessable", option: "UNGUESSABLE", want: true},
+		{name: "custom path", customPath: "spring", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: "https://example.com/target",
+				},
+				Suffix:     models.Suffix{Option: tt.option},
+				CustomPath: tt.customPath,
+			}
+
+			created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+			require.NoError(t, err)
+
+			resolved, err := service.ResolveShortPath(context.Background(), created.ShortLink, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resolved.IsUnguessable)
+		})
+	}
+}
+
+func TestResolveShortPath_RecordsClickTimeSeries(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{Option: "SHORT"},
+	}
+	created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+
+	for range 3 {
+		_, err := service.ResolveShortPath(context.Background(), created.ShortLink, nil, defaultTenantCfg)
+		require.NoError(t, err)
+	}
+	// Previews are not clicks
+	_, err = service.ResolveShortPath(context.Background(), created.ShortLink, nil, defaultTenantCfg, WithSkipAnalytics())
+	require.NoError(t, err)
+
+	today := time.Now().UTC()
+	buckets, err := service.repo.GetClickTimeSeries(context.Background(), "example.com", created.Details.Path, nil, today, today, repository.GranularityDay)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	assert.Equal(t, int64(3), buckets[0].Clicks)
+}
+
+func TestResolveShortPath_CountsCampaignClicks(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	projectID := uuid.New()
+	var shortLinks []string
+	for _, target := range []string{"https://example.com/a", "https://example.com/b"} {
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host:       "example.com",
+				Link:       target,
+				CampaignID: stringPtr("spring"),
+			},
+			Suffix: models.Suffix{Option: "SHORT"},
+		}
+		created, err := service.CreateDurableLink(context.Background(), params, &projectID, defaultTenantCfg)
+		require.NoError(t, err)
+		shortLinks = append(shortLinks, created.ShortLink)
+	}
+
+	for _, shortLink := range []string{shortLinks[0], shortLinks[0], shortLinks[1]} {
+		_, err := service.ResolveShortPath(context.Background(), shortLink, &projectID, defaultTenantCfg)
+		require.NoError(t, err)
+	}
+
+	stats, err := service.repo.GetCampaignStats(context.Background(), &projectID, "spring")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Links)
+	assert.Equal(t, int64(3), stats.Clicks)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"crypto/rand"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PathGenerator produces candidate paths for new links. Implementations must be safe
+// for concurrent use, as a single service handles many requests at once.
+type PathGenerator interface {
+	Generate(length int) (string, error)
+}
+
+// Alphabets of generated paths, one per PathCase. Single case alphabets keep all 36 symbols
+// distinct instead of folding the mixed alphabet, which would favor letters over digits.
+const (
+	mixedCaseAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	lowerCaseAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	upperCaseAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// alphabet returns the symbols generated paths of case c are drawn from.
+func (c PathCase) alphabet() string {
+	switch c {
+	case PathCaseLower:
+		return lowerCaseAlphabet
+	case PathCaseUpper:
+		return upperCaseAlphabet
+	}
+	return mixedCaseAlphabet
+}
+
+// randomPathGenerator is the default PathGenerator, drawing paths from alphabet with crypto/rand.
+// An empty alphabet draws mixed case alphanumeric paths.
+type randomPathGenerator struct {
+	alphabet string
+}
+
+func (g randomPathGenerator) Generate(length int) (string, error) {
+	alphabet := g.alphabet
+	if alphabet == "" {
+		alphabet = mixedCaseAlphabet
+	}
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	for i := range b {
+		b[i] = alphabet[b[i]%byte(len(alphabet))]
+	}
+
+	id := string(b)
+
+	log.Debug().
+		Str("short_code", id).
+		Msg("Generated alphanumeric short ID")
+
+	return id, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// privateTargetLookupTimeout bounds the DNS lookup of a target host for RejectPrivateTargets.
+const privateTargetLookupTimeout = 2 * time.Second
+
+// HostResolver looks up the addresses of a host name. *net.Resolver implements it.
+type HostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// WithHostResolver replaces net.DefaultResolver for the lookups of RejectPrivateTargets.
+func WithHostResolver(r HostResolver) Option {
+	return func(s *linkService) {
+		s.hostResolver = r
+	}
+}
+
+// checkPublicTarget returns ErrPrivateTarget when the host of link is, or resolves to, a
+// loopback, private or link-local address. Hosts that cannot be resolved are rejected too,
+// as they cannot be shown to be public.
+func (s *linkService) checkPublicTarget(ctx context.Context, link string) error {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ErrInvalidRequestedLink
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	if isLocalhostName(host) {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, host)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("%w: %s", ErrPrivateTarget, host)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, privateTargetLookupTimeout)
+	defer cancel()
+
+	addrs, err := s.hostResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: could not resolve %s: %v", ErrPrivateTarget, host, err)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrPrivateTarget, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// isLocalhostName reports whether host is localhost or one of its subdomains, which resolve to
+// loopback without DNS.
+func isLocalhostName(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+// isPublicIP reports whether ip is not loopback, RFC 1918 / RFC 4193 private, link-local
+// or unspecified.
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsUnspecified()
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver answers lookups from a fixed table instead of DNS.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	addrs := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func TestCreateDurableLink_RejectPrivateTargets(t *testing.T) {
+	resolver := fakeResolver{
+		"public.example.com":   {"93.184.216.34"},
+		"internal.example.com": {"10.1.2.3"},
+		"mixed.example.com":    {"93.184.216.34", "192.168.0.10"},
+	}
+
+	tests := []struct {
+		name        string
+		link        string
+		rules       []models.ResolutionRule
+		expectError bool
+	}{
+		{name: "public host", link: "https://public.example.com/target"},
+		{name: "public IP", link: "https://93.184.216.34/target"},
+		{name: "localhost", link: "http://localhost:8080/target", expectError: true},
+		{name: "loopback IP", link: "http://127.0.0.1/target", expectError: true},
+		{name: "IPv6 loopback", link: "http://[::1]/target", expectError: true},
+		{name: "RFC 1918 10/8", link: "http://10.0.0.5/target", expectError: true},
+		{name: "RFC 1918 172.16/12", link: "http://172.16.4.1/target", expectError: true},
+		{name: "RFC 1918 192.168/16", link: "http://192.168.1.1/target", expectError: true},
+		{name: "link-local", link: "http://169.254.169.254/latest/meta-data", expectError: true},
+		{name: "host resolving to a private address", link: "https://internal.example.com/target", expectError: true},
+		{name: "host resolving to any private address", link: "https://mixed.example.com/target", expectError: true},
+		{name: "unresolvable host", link: "https://unknown.example.com/target", expectError: true},
+		{
+			name:        "private rule target",
+			link:        "https://public.example.com/target",
+			rules:       []models.ResolutionRule{{Countries: []string{"US"}, Link: "http://10.0.0.5/us"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+			service.hostResolver = resolver
+
+			cfg := defaultTenantCfg
+			cfg.DomainAllowList = nil
+			cfg.AllowAllDomains = true
+			cfg.RejectPrivateTargets = true
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host:  "example.com",
+					Link:  tt.link,
+					Rules: tt.rules,
+				},
+				Suffix: models.Suffix{Option: "SHORT"},
+			}
+
+			result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+			if tt.expectError {
+				assert.ErrorIs(t, err, ErrPrivateTarget)
+				assert.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, result.ShortLink)
+		})
+	}
+
+	t.Run("private targets are accepted when not rejected", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		cfg := defaultTenantCfg
+		cfg.DomainAllowList = nil
+		cfg.AllowAllDomains = true
+
+		params := models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host: "example.com",
+				Link: "http://127.0.0.1/target",
+			},
+			Suffix: models.Suffix{Option: "SHORT"},
+		}
+
+		_, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+		assert.NoError(t, err)
+	})
+}
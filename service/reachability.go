@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultVerifyTargetTimeout applies when TenantConfig.VerifyTargetTimeout is unset.
+const defaultVerifyTargetTimeout = 3 * time.Second
+
+// maxReachabilityRedirects matches the redirect limit of http.DefaultClient.
+const maxReachabilityRedirects = 10
+
+// publicOnlyClient makes the reachability requests of tenants with RejectPrivateTargets. Its
+// dialer refuses non-public addresses after DNS resolution, so neither a redirect nor a host
+// name that resolves differently than at validation time can reach internal services.
+var publicOnlyClient = &http.Client{
+	Transport: &http.Transport{
+		// No proxy, it would make the connection to the target on our behalf
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Control: rejectNonPublicDial,
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: defaultVerifyTargetTimeout,
+	},
+	CheckRedirect: checkPublicRedirect,
+}
+
+// rejectNonPublicDial is a net.Dialer Control func that fails connections to addresses for which
+// isPublicIP is false.
+func rejectNonPublicDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, host)
+	}
+	return nil
+}
+
+// checkPublicRedirect checks every redirect hop of publicOnlyClient. Literal private addresses
+// and localhost names are refused here, host names are checked again when dialing.
+func checkPublicRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxReachabilityRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxReachabilityRedirects)
+	}
+	host := req.URL.Hostname()
+	if isLocalhostName(host) {
+		return fmt.Errorf("%w: redirect to %s", ErrPrivateTarget, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return fmt.Errorf("%w: redirect to %s", ErrPrivateTarget, host)
+	}
+	return nil
+}
+
+// verifyTargetReachable checks that link answers with a non-error status. A HEAD request is
+// tried first, falling back to GET for servers that don't handle HEAD properly. With
+// rejectPrivate, requests and their redirects may only connect to public addresses.
+func verifyTargetReachable(ctx context.Context, link string, timeout time.Duration, rejectPrivate bool) error {
+	if timeout <= 0 {
+		timeout = defaultVerifyTargetTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := http.DefaultClient
+	if rejectPrivate {
+		client = publicOnlyClient
+	}
+
+	status, err := requestStatus(ctx, client, http.MethodHead, link)
+	if err == nil && status < http.StatusBadRequest {
+		return nil
+	}
+
+	status, err = requestStatus(ctx, client, http.MethodGet, link)
+	if errors.Is(err, ErrPrivateTarget) {
+		log.Error().
+			Err(err).
+			Str("link", link).
+			Msg("Target link leads to a private address")
+		return fmt.Errorf("%w: %v", ErrPrivateTarget, err)
+	}
+	if err != nil {
+		log.Debug().
+			Err(err).
+			Str("link", link).
+			Msg("Target link is unreachable")
+		return fmt.Errorf("%w: %v", ErrTargetUnreachable, err)
+	}
+	if status >= http.StatusBadRequest {
+		log.Debug().
+			Int("status", status).
+			Str("link", link).
+			Msg("Target link returned an error status")
+		return fmt.Errorf("%w: status %d", ErrTargetUnreachable, status)
+	}
+
+	return nil
+}
+
+func requestStatus(ctx context.Context, client *http.Client, method, link string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	return resp.StatusCode, nil
+}
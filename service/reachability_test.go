@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDurableLink_VerifyTargetReachable(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/hang", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cfg := defaultTenantCfg
+	cfg.DomainAllowList = []string{serverURL.Hostname()}
+	cfg.VerifyTargetReachable = true
+	cfg.VerifyTargetTimeout = 100 * time.Millisecond
+
+	tests := []struct {
+		name        string
+		path        string
+		skip        bool
+		tenantCfg   TenantConfig
+		expectError error
+	}{
+		{name: "200 is accepted", path: "/ok", tenantCfg: cfg},
+		{name: "404 is rejected", path: "/missing", tenantCfg: cfg, expectError: ErrTargetUnreachable},
+		{name: "500 is rejected", path: "/broken", tenantCfg: cfg, expectError: ErrTargetUnreachable},
+		{name: "HEAD not allowed falls back to GET", path: "/get-only", tenantCfg: cfg},
+		{name: "hanging target times out", path: "/hang", tenantCfg: cfg, expectError: ErrTargetUnreachable},
+		{name: "check can be skipped per request", path: "/missing", skip: true, tenantCfg: cfg},
+		{
+			name: "check is off by default",
+			path: "/missing",
+			tenantCfg: func() TenantConfig {
+				c := cfg
+				c.VerifyTargetReachable = false
+				return c
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+
+			params := models.CreateDurableLinkRequest{
+				DurableLinkInfo: models.DurableLink{
+					Host: "example.com",
+					Link: server.URL + tt.path,
+				},
+				Suffix: models.Suffix{
+					Option: "UNGUESSABLE",
+				},
+				SkipTargetVerification: tt.skip,
+			}
+
+			start := time.Now()
+			result, err := service.CreateDurableLink(context.Background(), params, nil, tt.tenantCfg)
+			assert.Less(t, time.Since(start), 2*time.Second)
+
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, result.ShortLink)
+		})
+	}
+}
+
+func TestVerifyTargetReachable_ConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	link := server.URL + "/gone"
+	server.Close()
+
+	err := verifyTargetReachable(context.Background(), link, 100*time.Millisecond, false)
+	assert.ErrorIs(t, err, ErrTargetUnreachable)
+}
+
+func TestVerifyTargetReachable_RejectPrivate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	require.NoError(t, verifyTargetReachable(context.Background(), server.URL, 100*time.Millisecond, false))
+
+	// The test server listens on loopback, so the dialer must refuse it
+	err := verifyTargetReachable(context.Background(), server.URL, 100*time.Millisecond, true)
+	assert.ErrorIs(t, err, ErrPrivateTarget)
+}
+
+func TestCheckPublicRedirect(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		expectError error
+	}{
+		{name: "public address", target: "http://93.184.216.34/next"},
+		{name: "host name is checked when dialing", target: "https://example.com/next"},
+		{name: "loopback address", target: "http://127.0.0.1/admin", expectError: ErrPrivateTarget},
+		{name: "ipv6 loopback address", target: "http://[::1]/admin", expectError: ErrPrivateTarget},
+		{name: "private address", target: "http://10.0.0.1/", expectError: ErrPrivateTarget},
+		{name: "link-local metadata address", target: "http://169.254.169.254/latest/meta-data", expectError: ErrPrivateTarget},
+		{name: "localhost name", target: "http://localhost:8080/", expectError: ErrPrivateTarget},
+		{name: "localhost subdomain", target: "http://api.localhost/", expectError: ErrPrivateTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.target, nil)
+			require.NoError(t, err)
+
+			err = checkPublicRedirect(req, nil)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("too many redirects", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://93.184.216.34/next", nil)
+		require.NoError(t, err)
+		assert.Error(t, checkPublicRedirect(req, make([]*http.Request, maxReachabilityRedirects)))
+	})
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+)
+
+type requestContextKey struct{}
+
+// ContextWithRequestContext returns a copy of ctx carrying req, the request a link is being
+// resolved for. Resolution rules are evaluated against it.
+func ContextWithRequestContext(ctx context.Context, req models.RequestContext) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, req)
+}
+
+// RequestContextFromContext returns the request context stored by ContextWithRequestContext.
+func RequestContextFromContext(ctx context.Context) (models.RequestContext, bool) {
+	req, ok := ctx.Value(requestContextKey{}).(models.RequestContext)
+	return req, ok
+}
+
+// resolveRequestContext returns the request context on ctx, with the current time filled in
+// when it carries none. Without one on ctx the country is unknown.
+func resolveRequestContext(ctx context.Context) models.RequestContext {
+	req, _ := RequestContextFromContext(ctx)
+	if req.Time.IsZero() {
+		req.Time = time.Now()
+	}
+	return req
+}
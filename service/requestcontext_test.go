@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestContextFromContext(t *testing.T) {
+	_, ok := RequestContextFromContext(context.Background())
+	assert.False(t, ok)
+
+	req := models.RequestContext{Country: "DE", Time: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	got, ok := RequestContextFromContext(ContextWithRequestContext(context.Background(), req))
+	require.True(t, ok)
+	assert.Equal(t, req, got)
+
+	// Without a time the current one is used
+	before := time.Now()
+	resolved := resolveRequestContext(ContextWithRequestContext(context.Background(), models.RequestContext{Country: "DE"}))
+	assert.Equal(t, "DE", resolved.Country)
+	assert.False(t, resolved.Time.Before(before))
+}
+
+func TestResolveShortPath_Rules(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/default",
+			Rules: []models.ResolutionRule{
+				{Countries: []string{"de", "AT"}, Link: "https://example.com/dach"},
+				{TimeWindow: &models.TimeWindow{From: "22:00", To: "06:00", TimeZone: "America/New_York"}, Link: "https://example.com/night"},
+			},
+		},
+		CustomPath: "promo",
+	}
+	created, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+	require.NoError(t, err)
+	assert.Empty(t, created.Warnings)
+
+	noon := time.Date(2026, 5, 1, 16, 0, 0, 0, time.UTC)    // 12:00 in New York
+	midnight := time.Date(2026, 5, 2, 4, 0, 0, 0, time.UTC) // 00:00 in New York
+
+	tests := []struct {
+		name     string
+		req      models.RequestContext
+		expected string
+	}{
+		{name: "country matches", req: models.RequestContext{Country: "DE", Time: noon}, expected: "https://example.com/dach"},
+		{name: "first matching rule wins", req: models.RequestContext{Country: "AT", Time: midnight}, expected: "https://example.com/dach"},
+		{name: "time window wraps past midnight", req: models.RequestContext{Country: "US", Time: midnight}, expected: "https://example.com/night"},
+		{name: "no rule matches", req: models.RequestContext{Country: "US", Time: noon}, expected: "https://example.com/default"},
+		{name: "unknown country", req: models.RequestContext{Time: noon}, expected: "https://example.com/default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ContextWithRequestContext(context.Background(), tt.req)
+			result, err := service.ResolveShortPath(ctx, created.ShortLink, nil, defaultTenantCfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.LongLink)
+		})
+	}
+}
+
+func TestCreateDurableLink_RuleValidation(t *testing.T) {
+	newParams := func(rules ...models.ResolutionRule) models.CreateDurableLinkRequest {
+		return models.CreateDurableLinkRequest{
+			DurableLinkInfo: models.DurableLink{
+				Host:  "example.com",
+				Link:  "https://example.com/default",
+				Rules: rules,
+			},
+			Suffix: models.Suffix{
+				Option: "UNGUESSABLE",
+			},
+		}
+	}
+
+	t.Run("malformed rules are dropped with a warning", func(t *testing.T) {
+		service, db := setupTestService(t)
+
+		params := newParams(
+			models.ResolutionRule{Countries: []string{"Germany"}, Link: "https://example.com/de"},
+			models.ResolutionRule{TimeWindow: &models.TimeWindow{From: "25:00", To: "06:00"}, Link: "https://example.com/night"},
+			models.ResolutionRule{Countries: []string{"GB"}, Link: "ftp://example.com/gb"},
+			models.ResolutionRule{Countries: []string{"FR"}, Link: "https://example.com/fr"},
+		)
+		result, err := service.CreateDurableLink(context.Background(), params, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		require.Len(t, result.Warnings, 3)
+		assert.Equal(t, "durableLinkInfo.rules[0]", result.Warnings[0].Field)
+		assert.Equal(t, "durableLinkInfo.rules[1]", result.Warnings[1].Field)
+		assert.Equal(t, "durableLinkInfo.rules[2]", result.Warnings[2].Field)
+		assert.Equal(t, "Param 'rules[2]' link must be an http or https URL", result.Warnings[2].WarningMessage)
+
+		var stored models.DurableLinkDB
+		require.NoError(t, db.First(&stored).Error)
+		assert.Equal(t, models.ResolutionRules{{Countries: []string{"FR"}, Link: "https://example.com/fr"}}, stored.Rules)
+	})
+
+	t.Run("rule links must be in the allow list", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		_, err := service.CreateDurableLink(context.Background(), newParams(models.ResolutionRule{Link: "https://evil.com/"}), nil, defaultTenantCfg)
+		assert.ErrorIs(t, err, ErrDomainLinkNotAllowed)
+	})
+
+	t.Run("links with different rules are not shared", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		plain := newParams()
+		plain.Suffix.Option = "SHORT"
+		first, err := service.CreateDurableLink(context.Background(), plain, nil, defaultTenantCfg)
+		require.NoError(t, err)
+
+		ruled := newParams(models.ResolutionRule{Countries: []string{"FR"}, Link: "https://example.com/fr"})
+		ruled.Suffix.Option = "SHORT"
+		second, err := service.CreateDurableLink(context.Background(), ruled, nil, defaultTenantCfg)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ShortLink, second.ShortLink)
+	})
+}
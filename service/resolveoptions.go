@@ -0,0 +1,24 @@
+package service
+
+// ResolveOption customizes a single resolve.
+type ResolveOption func(*resolveOptions)
+
+type resolveOptions struct {
+	skipAnalytics bool
+}
+
+// WithSkipAnalytics resolves without counting a click, e.g. for link previews, health checks
+// or crawlers. Links that have used up their clicks still don't resolve.
+func WithSkipAnalytics() ResolveOption {
+	return func(o *resolveOptions) {
+		o.skipAnalytics = true
+	}
+}
+
+func newResolveOptions(opts []ResolveOption) resolveOptions {
+	var o resolveOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
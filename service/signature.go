@@ -0,0 +1,84 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// signatureParam is the query parameter carrying a short link's signature.
+const signatureParam = "sig"
+
+// signatureLength is how many bytes of the HMAC are kept, enough to make guessing hopeless
+// while keeping the link short.
+const signatureLength = 16
+
+// keyIDSeparator separates the key ID from the HMAC in a signature. It is not part of the
+// URL safe base64 alphabet, so the last one always ends the key ID.
+const keyIDSeparator = "."
+
+// Secret is one of a tenant's HMAC keys. ID is embedded in signatures so the key that made
+// them can still be found after newer keys are added.
+type Secret struct {
+	ID  string
+	Key string
+}
+
+// signingSecret returns the key new signatures and deterministic paths are made with: the
+// last of Secrets, or the legacy Secret without an ID. It returns false when there is none.
+func (c TenantConfig) signingSecret() (Secret, bool) {
+	if n := len(c.Secrets); n > 0 {
+		return c.Secrets[n-1], true
+	}
+	if c.Secret != "" {
+		return Secret{Key: c.Secret}, true
+	}
+	return Secret{}, false
+}
+
+// verificationSecrets returns every key a signature may have been made with.
+func (c TenantConfig) verificationSecrets() []Secret {
+	secrets := c.Secrets
+	if c.Secret != "" {
+		secrets = append(secrets[:len(secrets):len(secrets)], Secret{Key: c.Secret})
+	}
+	return secrets
+}
+
+// signPath returns the URL safe signature of path on host under secret, prefixed with the
+// secret's ID when it has one.
+func signPath(secret Secret, host, path string) string {
+	mac := macPath(secret.Key, host, path)
+	if secret.ID == "" {
+		return mac
+	}
+	return secret.ID + keyIDSeparator + mac
+}
+
+func macPath(key, host, path string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(host))
+	mac.Write([]byte{0})
+	mac.Write([]byte(path))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureLength])
+}
+
+// validSignature reports whether sig was produced by signPath for host and path with one of
+// secrets. Signatures with a key ID are only checked against that key, ones without against all.
+func validSignature(secrets []Secret, host, path, sig string) bool {
+	keyID, mac, hasKeyID := "", sig, false
+	if i := strings.LastIndex(sig, keyIDSeparator); i >= 0 {
+		keyID, mac, hasKeyID = sig[:i], sig[i+len(keyIDSeparator):], true
+	}
+
+	for _, secret := range secrets {
+		if hasKeyID && secret.ID != keyID {
+			continue
+		}
+		if hmac.Equal([]byte(mac), []byte(macPath(secret.Key, host, path))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveShortPath_RequireSignature(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	cfg := defaultTenantCfg
+	cfg.RequireSignature = true
+	cfg.Secret = "test-secret"
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "UNGUESSABLE",
+		},
+	}
+
+	result, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+	require.NoError(t, err)
+
+	signed, err := url.Parse(result.ShortLink)
+	require.NoError(t, err)
+	sig := signed.Query().Get("sig")
+	require.NotEmpty(t, sig)
+	unsigned := result.ShortLink[:len(result.ShortLink)-len(signed.RawQuery)-1]
+
+	tampered := *signed
+	tampered.Path = "/" + replaceLast(result.Details.Path)
+
+	otherSecret := cfg
+	otherSecret.Secret = "other-secret"
+
+	tests := []struct {
+		name        string
+		rawURL      string
+		tenantCfg   TenantConfig
+		expectError error
+	}{
+		{name: "valid signature", rawURL: result.ShortLink, tenantCfg: cfg},
+		{name: "missing signature", rawURL: unsigned, tenantCfg: cfg, expectError: ErrInvalidSignature},
+		{name: "tampered signature", rawURL: unsigned + "?sig=" + replaceLast(sig), tenantCfg: cfg, expectError: ErrInvalidSignature},
+		{name: "tampered path", rawURL: tampered.String(), tenantCfg: cfg, expectError: ErrInvalidSignature},
+		{name: "different secret", rawURL: result.ShortLink, tenantCfg: otherSecret, expectError: ErrInvalidSignature},
+		{name: "signature ignored when not required", rawURL: unsigned, tenantCfg: defaultTenantCfg},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := service.ResolveShortPath(context.Background(), tt.rawURL, nil, tt.tenantCfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, resolved)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/target", resolved.LongLink)
+		})
+	}
+}
+
+func TestCreateDurableLink_RequireSignatureNeedsSecret(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	cfg := defaultTenantCfg
+	cfg.RequireSignature = true
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+	}
+
+	_, err := service.CreateDurableLink(context.Background(), params, nil, cfg)
+	assert.ErrorIs(t, err, ErrMissingTenantSecret)
+}
+
+// replaceLast returns s with its last character swapped for a different one.
+func replaceLast(s string) string {
+	if s[len(s)-1] == 'A' {
+		return s[:len(s)-1] + "B"
+	}
+	return s[:len(s)-1] + "A"
+}
+
+func TestResolveShortPath_SecretRotation(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "UNGUESSABLE",
+		},
+	}
+
+	legacy := defaultTenantCfg
+	legacy.RequireSignature = true
+	legacy.Secret = "legacy-secret"
+
+	v1 := defaultTenantCfg
+	v1.RequireSignature = true
+	v1.Secrets = []Secret{{ID: "v1", Key: "first-secret"}}
+
+	legacyLink, err := service.CreateDurableLink(context.Background(), params, nil, legacy)
+	require.NoError(t, err)
+	v1Link, err := service.CreateDurableLink(context.Background(), params, nil, v1)
+	require.NoError(t, err)
+
+	signed, err := url.Parse(v1Link.ShortLink)
+	require.NoError(t, err)
+	assert.Contains(t, signed.Query().Get("sig"), "v1.", "signatures carry the key ID")
+
+	rotated := v1
+	rotated.Secret = legacy.Secret
+	rotated.Secrets = []Secret{{ID: "v1", Key: "first-secret"}, {ID: "v2", Key: "second-secret"}}
+
+	v2Link, err := service.CreateDurableLink(context.Background(), params, nil, rotated)
+	require.NoError(t, err)
+	signed, err = url.Parse(v2Link.ShortLink)
+	require.NoError(t, err)
+	assert.Contains(t, signed.Query().Get("sig"), "v2.", "new links are signed with the newest key")
+
+	// A known key ID must match its own key, not any configured key
+	wrongKeyID := strings.Replace(v1Link.ShortLink, "sig=v1.", "sig=v2.", 1)
+
+	retired := rotated
+	retired.Secret = ""
+	retired.Secrets = []Secret{{ID: "v2", Key: "second-secret"}}
+
+	tests := []struct {
+		name        string
+		rawURL      string
+		tenantCfg   TenantConfig
+		expectError error
+	}{
+		{name: "legacy signature after rotation", rawURL: legacyLink.ShortLink, tenantCfg: rotated},
+		{name: "old key after rotation", rawURL: v1Link.ShortLink, tenantCfg: rotated},
+		{name: "new key", rawURL: v2Link.ShortLink, tenantCfg: rotated},
+		{name: "key ID of another key", rawURL: wrongKeyID, tenantCfg: rotated, expectError: ErrInvalidSignature},
+		{name: "retired key", rawURL: v1Link.ShortLink, tenantCfg: retired, expectError: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := service.ResolveShortPath(context.Background(), tt.rawURL, nil, tt.tenantCfg)
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/target", resolved.LongLink)
+		})
+	}
+}
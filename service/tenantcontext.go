@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"reflect"
+)
+
+type tenantConfigKey struct{}
+
+// ContextWithTenantConfig returns a copy of ctx carrying tenantCfg, typically set once by middleware.
+func ContextWithTenantConfig(ctx context.Context, tenantCfg TenantConfig) context.Context {
+	return context.WithValue(ctx, tenantConfigKey{}, tenantCfg)
+}
+
+// TenantConfigFromContext returns the tenant config stored by ContextWithTenantConfig.
+func TenantConfigFromContext(ctx context.Context) (TenantConfig, bool) {
+	tenantCfg, ok := ctx.Value(tenantConfigKey{}).(TenantConfig)
+	return tenantCfg, ok
+}
+
+// resolveTenantConfig returns the explicitly passed config, or the one on ctx when the
+// caller passed the zero TenantConfig.
+func resolveTenantConfig(ctx context.Context, explicit TenantConfig) TenantConfig {
+	if !reflect.ValueOf(explicit).IsZero() {
+		return explicit
+	}
+	if tenantCfg, ok := TenantConfigFromContext(ctx); ok {
+		return tenantCfg
+	}
+	return explicit
+}
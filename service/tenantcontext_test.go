@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantConfigFromContext(t *testing.T) {
+	_, ok := TenantConfigFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := ContextWithTenantConfig(context.Background(), defaultTenantCfg)
+	cfg, ok := TenantConfigFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, defaultTenantCfg, cfg)
+}
+
+func TestCreateAndResolve_TenantConfigFromContext(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	params := models.CreateDurableLinkRequest{
+		DurableLinkInfo: models.DurableLink{
+			Host: "example.com",
+			Link: "https://example.com/target",
+		},
+		Suffix: models.Suffix{
+			Option: "SHORT",
+		},
+	}
+
+	ctx := ContextWithTenantConfig(context.Background(), defaultTenantCfg)
+
+	t.Run("config is taken from the context", func(t *testing.T) {
+		result, err := service.CreateDurableLink(ctx, params, nil, TenantConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, "https", result.Details.Scheme)
+		assert.Len(t, result.Details.Path, defaultTenantCfg.ShortPathLength)
+
+		resolved, err := service.ResolveShortPath(ctx, result.ShortLink, nil, TenantConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/target", resolved.LongLink)
+	})
+
+	t.Run("explicit config takes precedence", func(t *testing.T) {
+		explicit := defaultTenantCfg
+		explicit.URLScheme = "http"
+		explicit.ShortPathLength = 5
+
+		other := params
+		other.DurableLinkInfo.Link = "https://example.com/other"
+		result, err := service.CreateDurableLink(ctx, other, nil, explicit)
+		require.NoError(t, err)
+		assert.Equal(t, "http", result.Details.Scheme)
+		assert.Len(t, result.Details.Path, 5)
+	})
+
+	t.Run("no config anywhere", func(t *testing.T) {
+		_, err := service.CreateDurableLink(context.Background(), params, nil, TenantConfig{})
+		assert.ErrorIs(t, err, ErrDomainLinkNotAllowed)
+	})
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultTenantConfig returns the config GetTenantConfig starts from before applying a
+// tenant's stored settings.
+func DefaultTenantConfig() TenantConfig {
+	return TenantConfig{
+		URLScheme:             "https",
+		ShortPathLength:       4,
+		UnguessablePathLength: 17,
+		MinCustomPathLength:   defaultMinCustomPathLength,
+		VerifyTargetTimeout:   defaultVerifyTargetTimeout,
+	}
+}
+
+// tenantSettingFields maps the keys of stored tenant settings to the fields of cfg they set.
+// Secrets are deliberately not loadable from settings.
+func tenantSettingFields(cfg *TenantConfig) map[string]any {
+	return map[string]any{
+		"url_scheme":                    &cfg.URLScheme,
+		"domain_allow_list":             &cfg.DomainAllowList,
+		"allow_all_domains":             &cfg.AllowAllDomains,
+		"domain_deny_list":              &cfg.DomainDenyList,
+		"short_path_length":             &cfg.ShortPathLength,
+		"unguessable_path_length":       &cfg.UnguessablePathLength,
+		"min_custom_path_length":        &cfg.MinCustomPathLength,
+		"default_ios_app_store_id":      &cfg.DefaultIOSAppStoreId,
+		"default_android_package":       &cfg.DefaultAndroidPackage,
+		"default_utm_source":            &cfg.DefaultUtmSource,
+		"default_utm_medium":            &cfg.DefaultUtmMedium,
+		"not_found_fallback_url":        &cfg.NotFoundFallbackURL,
+		"store_raw_request":             &cfg.StoreRawRequest,
+		"path_prefix":                   &cfg.PathPrefix,
+		"path_suffix":                   &cfg.PathSuffix,
+		"strict_validation":             &cfg.StrictValidation,
+		"verify_target_reachable":       &cfg.VerifyTargetReachable,
+		"verify_target_timeout":         &cfg.VerifyTargetTimeout,
+		"dedup_ignore_utm":              &cfg.DedupIgnoreUTM,
+		"path_checksum":                 &cfg.PathChecksum,
+		"require_signature":             &cfg.RequireSignature,
+		"unwrap_nested_short_links":     &cfg.UnwrapNestedShortLinks,
+		"recycle_paths":                 &cfg.RecyclePaths,
+		"strip_query_params":            &cfg.StripQueryParams,
+		"reject_private_targets":        &cfg.RejectPrivateTargets,
+		"preview_query_param":           &cfg.PreviewQueryParam,
+		"preview_subdomain":             &cfg.PreviewSubdomain,
+		"max_links":                     &cfg.MaxLinks,
+		"max_reuse_age":                 &cfg.MaxReuseAge,
+		"require_platform_config":       &cfg.RequirePlatformConfig,
+		"warn_on_cross_domain_fallback": &cfg.WarnOnCrossDomainFallback,
+		"path_case":                     &cfg.PathCase,
+		"path_strategy":                 &cfg.PathStrategy,
+		"path_uniqueness_scope":         &cfg.PathUniquenessScope,
+		"host_aliases":                  &cfg.HostAliases,
+	}
+}
+
+// GetTenantConfig builds the config of tenantID from DefaultTenantConfig and the tenant's stored
+// settings, so operators can change a tenant's behavior without a deploy. Unknown keys are
+// ignored, malformed or invalid values fail with ErrInvalidTenantSetting.
+func (s *linkService) GetTenantConfig(ctx context.Context, tenantID string) (*TenantConfig, error) {
+	settings, err := s.repo.GetTenantSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := DefaultTenantConfig()
+	fields := tenantSettingFields(&cfg)
+	for key, value := range settings {
+		field, ok := fields[key]
+		if !ok {
+			log.Warn().
+				Str("tenant_id", tenantID).
+				Str("key", key).
+				Msg("Ignoring unknown tenant setting")
+			continue
+		}
+		if err := setTenantSetting(field, value); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTenantSetting, key, err)
+		}
+	}
+	// The suffix is trimmed from the last path segment on resolve, so it can't span segments
+	if strings.Contains(cfg.PathSuffix, "/") {
+		return nil, fmt.Errorf("%w: path_suffix: must not contain '/'", ErrInvalidTenantSetting)
+	}
+
+	return &cfg, nil
+}
+
+// setTenantSetting parses value into field, one of the pointers of tenantSettingFields. Lists
+// are comma separated, maps are comma separated key=value pairs and enums are parsed by their
+// encoding.TextUnmarshaler.
+func setTenantSetting(field any, value string) error {
+	switch f := field.(type) {
+	case encoding.TextUnmarshaler:
+		return f.UnmarshalText([]byte(value))
+	case *string:
+		*f = value
+	case **string:
+		*f = &value
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*f = b
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*f = n
+	case **int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*f = &n
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*f = d
+	case *[]string:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*f = list
+	case *map[string]string:
+		m := make(map[string]string)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(item, "=")
+			key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+			if !ok || key == "" || val == "" {
+				return fmt.Errorf("malformed pair %q, want key=value", item)
+			}
+			m[key] = val
+		}
+		*f = m
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
+	}
+	return nil
+}
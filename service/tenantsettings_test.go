@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTenantConfig(t *testing.T) {
+	service, db := setupTestService(t)
+	require.NoError(t, db.AutoMigrate(&models.TenantSettingDB{}))
+	ctx := context.Background()
+
+	t.Run("missing keys fall back to defaults", func(t *testing.T) {
+		cfg, err := service.GetTenantConfig(ctx, "unknown-tenant")
+		require.NoError(t, err)
+		assert.Equal(t, DefaultTenantConfig(), *cfg)
+	})
+
+	t.Run("present keys override defaults", func(t *testing.T) {
+		settings := map[string]string{
+			"short_path_length":        "6",
+			"domain_allow_list":        "example.com, example.org",
+			"strict_validation":        "true",
+			"default_ios_app_store_id": "123456789",
+			"not_found_fallback_url":   "https://example.com/404",
+			"verify_target_timeout":    "1500ms",
+			"path_case":                "lower",
+			"path_strategy":            "hmac_deterministic",
+			"path_uniqueness_scope":    "per_project",
+			"host_aliases":             "go.example.com=example.com, l.example.org = example.org",
+			"some_future_flag":         "on",
+		}
+		for key, value := range settings {
+			require.NoError(t, service.repo.SetTenantSetting(ctx, "acme", key, value))
+		}
+
+		cfg, err := service.GetTenantConfig(ctx, "acme")
+		require.NoError(t, err)
+
+		want := DefaultTenantConfig()
+		want.ShortPathLength = 6
+		want.DomainAllowList = []string{"example.com", "example.org"}
+		want.StrictValidation = true
+		want.DefaultIOSAppStoreId = int64Ptr(123456789)
+		want.NotFoundFallbackURL = stringPtr("https://example.com/404")
+		want.VerifyTargetTimeout = 1500 * time.Millisecond
+		want.PathCase = PathCaseLower
+		want.PathStrategy = PathStrategyHMACDeterministic
+		want.PathUniquenessScope = PathUniquenessPerProject
+		want.HostAliases = map[string]string{"go.example.com": "example.com", "l.example.org": "example.org"}
+		assert.Equal(t, want, *cfg)
+	})
+
+	t.Run("updated keys take effect", func(t *testing.T) {
+		require.NoError(t, service.repo.SetTenantSetting(ctx, "acme", "short_path_length", "8"))
+
+		cfg, err := service.GetTenantConfig(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, 8, cfg.ShortPathLength)
+	})
+
+	t.Run("malformed values are rejected", func(t *testing.T) {
+		malformed := map[string]string{
+			"strict_validation":     "maybe",
+			"path_case":             "title",
+			"path_strategy":         "sequential",
+			"path_uniqueness_scope": "per_tenant",
+			"host_aliases":          "go.example.com",
+			"path_suffix":           "/page",
+		}
+		for key, value := range malformed {
+			tenantID := "broken-" + key
+			require.NoError(t, service.repo.SetTenantSetting(ctx, tenantID, key, value))
+
+			cfg, err := service.GetTenantConfig(ctx, tenantID)
+			assert.ErrorIs(t, err, ErrInvalidTenantSetting)
+			assert.ErrorContains(t, err, key)
+			assert.Nil(t, cfg)
+		}
+	})
+}
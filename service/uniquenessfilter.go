@@ -0,0 +1,38 @@
+package service
+
+import (
+	"github.com/apppanel/durablelinks-core/models"
+	"github.com/google/uuid"
+)
+
+// UniquenessFilter is a probabilistic membership check over stored short links, such as a
+// bloom filter. Before looking for an existing short link to re-use, the service asks the
+// filter and skips the database lookup when the link is definitely not stored. Implementations
+// must be safe for concurrent use and should be seeded with the links stored before the
+// service started, otherwise those are no longer re-used.
+type UniquenessFilter interface {
+	// MightContain reports whether a link with key may be stored. false must mean it is
+	// definitely not stored, true may be a false positive.
+	MightContain(key string) bool
+	// Add records key once a link with it is stored.
+	Add(key string)
+}
+
+// passThroughFilter is the default UniquenessFilter, it never rules a link out so every
+// create checks the database.
+type passThroughFilter struct{}
+
+func (passThroughFilter) MightContain(string) bool { return true }
+
+func (passThroughFilter) Add(string) {}
+
+// uniquenessKey identifies link the way FindExistingShortLink matches it: by project, host,
+// target and params.
+func uniquenessKey(host string, link models.DurableLink, projectID *uuid.UUID) string {
+	scope := host
+	if projectID != nil {
+		scope = projectID.String() + "/" + host
+	}
+	paramsHash := models.FromDurableLink(link, "", "", false, nil).ComputeParamsHash()
+	return scope + "\n" + link.Link + "\n" + paramsHash
+}
@@ -28,6 +28,38 @@ func IsURL(str string) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
+// StripQueryParams removes every query parameter named in names from rawURL and returns the
+// result with the names that were removed. The order of the remaining parameters is kept, and
+// rawURL is returned unchanged when nothing was removed or it cannot be parsed.
+func StripQueryParams(rawURL string, names []string) (string, []string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" || len(names) == 0 {
+		return rawURL, nil
+	}
+
+	var kept, stripped []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if !slices.Contains(names, key) {
+			kept = append(kept, pair)
+			continue
+		}
+		if !slices.Contains(stripped, key) {
+			stripped = append(stripped, key)
+		}
+	}
+	if len(stripped) == 0 {
+		return rawURL, nil
+	}
+
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String(), stripped
+}
+
 func CleanHost(logger zerolog.Logger, raw string) (string, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
@@ -42,7 +74,7 @@ func CleanHost(logger zerolog.Logger, raw string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	host := u.Hostname()
+	host := NormalizeHost(u.Hostname())
 	logger.Debug().
 		Str("host", host).
 		Msg("Cleaned host")
@@ -58,13 +90,40 @@ func IsDomainAllowed(logger zerolog.Logger, allowList []string, rawLink string)
 			Msg("Invalid link")
 		return false
 	}
-	host := strings.ToLower(u.Hostname())
+	host := NormalizeHost(u.Hostname())
 
 	for _, allowed := range allowList {
-		allowed = strings.ToLower(strings.TrimSpace(allowed))
-		if host == allowed {
+		if host == NormalizeHost(allowed) {
 			return true
 		}
 	}
 	return false
 }
+
+// IsDomainDenied reports whether the host of rawLink is one of the domains in denyList or a
+// subdomain of one, so denying "evil.com" also denies "login.evil.com". Links that cannot be
+// parsed are reported as denied.
+func IsDomainDenied(logger zerolog.Logger, denyList []string, rawLink string) bool {
+	u, err := url.Parse(rawLink)
+	if err != nil {
+		logger.Error().
+			Str("raw_link", rawLink).
+			Msg("Invalid link")
+		return true
+	}
+	host := NormalizeHost(u.Hostname())
+
+	for _, denied := range denyList {
+		denied = NormalizeHost(denied)
+		if denied != "" && (host == denied || strings.HasSuffix(host, "."+denied)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeHost lowercases a hostname and drops the trailing dot of its fully
+// qualified form, so "Example.com." and "example.com" compare equal.
+func NormalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
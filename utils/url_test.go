@@ -176,6 +176,24 @@ func TestIsDomainAllowed(t *testing.T) {
 			allowList: allowList,
 			want:      true,
 		},
+		{
+			name:      "trailing dot and mixed case",
+			rawLink:   "https://Example.com./path",
+			allowList: allowList,
+			want:      true,
+		},
+		{
+			name:      "allow list entry with trailing dot and mixed case",
+			rawLink:   "https://example.com",
+			allowList: []string{" Example.COM. "},
+			want:      true,
+		},
+		{
+			name:      "trailing dot does not widen match",
+			rawLink:   "https://notexample.com.",
+			allowList: allowList,
+			want:      false,
+		},
 		{
 			name:      "empty allow list",
 			rawLink:   "https://example.com",
@@ -204,6 +222,35 @@ func TestIsDomainAllowed(t *testing.T) {
 	}
 }
 
+func TestIsDomainDenied(t *testing.T) {
+	denyList := []string{"evil.com", " Phish.Example. "}
+
+	tests := []struct {
+		name    string
+		rawLink string
+		want    bool
+	}{
+		{name: "exact match", rawLink: "https://evil.com/login", want: true},
+		{name: "subdomain match", rawLink: "https://login.evil.com", want: true},
+		{name: "case and trailing dot", rawLink: "https://EVIL.com./", want: true},
+		{name: "normalized deny list entry", rawLink: "https://phish.example", want: true},
+		{name: "suffix without dot does not match", rawLink: "https://notevil.com", want: false},
+		{name: "parent domain does not match", rawLink: "https://example", want: false},
+		{name: "not in deny list", rawLink: "https://example.com", want: false},
+		{name: "with port", rawLink: "https://evil.com:8443", want: true},
+		{name: "invalid URL is denied", rawLink: "http://[::1", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsDomainDenied(testLogger, denyList, tt.rawLink))
+		})
+	}
+
+	assert.False(t, IsDomainDenied(testLogger, nil, "https://evil.com"))
+	assert.False(t, IsDomainDenied(testLogger, []string{""}, "https://evil.com"))
+}
+
 func TestCleanHost(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -259,6 +306,24 @@ func TestCleanHost(t *testing.T) {
 			want:    "",
 			wantErr: true,
 		},
+		{
+			name:    "host with trailing dot",
+			raw:     "example.com.",
+			want:    "example.com",
+			wantErr: false,
+		},
+		{
+			name:    "mixed case host",
+			raw:     "https://EXAMPLE.Com/path",
+			want:    "example.com",
+			wantErr: false,
+		},
+		{
+			name:    "mixed case host with trailing dot",
+			raw:     "https://Example.com./path",
+			want:    "example.com",
+			wantErr: false,
+		},
 		{
 			name:    "host with spaces",
 			raw:     "  example.com  ",
@@ -281,3 +346,75 @@ func TestCleanHost(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"example.com", "example.com"},
+		{"Example.com.", "example.com"},
+		{"EXAMPLE.COM", "example.com"},
+		{" example.com. ", "example.com"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeHost(tt.input))
+		})
+	}
+}
+
+func TestStripQueryParams(t *testing.T) {
+	names := []string{"fbclid", "gclid"}
+
+	tests := []struct {
+		name         string
+		url          string
+		want         string
+		wantStripped []string
+	}{
+		{
+			name: "no query",
+			url:  "https://example.com/page",
+			want: "https://example.com/page",
+		},
+		{
+			name: "other params are left alone",
+			url:  "https://example.com/page?id=1&ref=home",
+			want: "https://example.com/page?id=1&ref=home",
+		},
+		{
+			name:         "only stripped params",
+			url:          "https://example.com/page?fbclid=abc",
+			want:         "https://example.com/page",
+			wantStripped: []string{"fbclid"},
+		},
+		{
+			name:         "order of remaining params is kept",
+			url:          "https://example.com/page?z=1&gclid=x&a=2&fbclid=y&gclid=z",
+			want:         "https://example.com/page?z=1&a=2",
+			wantStripped: []string{"gclid", "fbclid"},
+		},
+		{
+			name:         "fragment is kept",
+			url:          "https://example.com/page?fbclid=abc&id=1#section",
+			want:         "https://example.com/page?id=1#section",
+			wantStripped: []string{"fbclid"},
+		},
+		{
+			name: "names are matched exactly",
+			url:  "https://example.com/page?FBCLID=abc&fbclid_x=1",
+			want: "https://example.com/page?FBCLID=abc&fbclid_x=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stripped := StripQueryParams(tt.url, names)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantStripped, stripped)
+		})
+	}
+}